@@ -0,0 +1,67 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net/http"
+)
+
+type abortKey struct{}
+
+var contextAbortKey abortKey
+
+// Abort marks the request as handled, the downstream middleware and
+// handler of the route will be skipped, it is usually used by the
+// middleware which has already written the response, such as auth
+// and caching middleware, the upstream middleware can tell it via
+// IsAborted:
+//
+//	func auth(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+//			if !authorized(req) {
+//				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+//				fastrouter.Abort(req)
+//				return
+//			}
+//			next.ServeHTTP(w, req)
+//		})
+//	}
+//
+// It takes no effect unless the root router's Abortable is enabled,
+// and on the route without middleware, and the request which is not
+// dispatched by router.
+func Abort(req *http.Request) {
+	if aborted, ok := req.Context().Value(contextAbortKey).(*bool); ok {
+		*aborted = true
+	}
+}
+
+// IsAborted reports whether the request was aborted via Abort.
+func IsAborted(req *http.Request) bool {
+	aborted, ok := req.Context().Value(contextAbortKey).(*bool)
+	return ok && *aborted
+}
+
+// abortGuard returns a handler that skips the next handler if the
+// request was aborted.
+func abortGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !IsAborted(req) {
+			next.ServeHTTP(w, req)
+		}
+	})
+}
+
+// abortable returns a handler that enables Abort for the downstream
+// middleware and handler, it is used by the handlers which are not
+// dispatched via Router.handle, such as the mounted handler, the
+// routes keep the flag in their paramsContext instead.
+func abortable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		aborted := false
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextAbortKey, &aborted)))
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAbort(t *testing.T) {
+	calls := []string{}
+	newMiddleware := func(name string, abort bool) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				if abort {
+					w.WriteHeader(http.StatusUnauthorized)
+					Abort(req)
+				}
+				next.ServeHTTP(w, req)
+				if IsAborted(req) {
+					calls = append(calls, name+" aborted")
+				}
+			})
+		}
+	}
+
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.Abortable = true
+		r.PooledParams = pooled
+		r.Middleware = append(r.Middleware, newMiddleware("global", false))
+		r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "handler")
+		}, newMiddleware("auth", true), newMiddleware("cache", false))
+		r.Get("/ok", func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "handler")
+		}, newMiddleware("cache", false))
+		r.Prepare()
+
+		calls = calls[:0]
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("pooled %t: expect status code to be %d, but got %d", pooled, http.StatusUnauthorized, w.Code)
+		}
+		expected := []string{"global", "auth", "auth aborted", "global aborted"}
+		if !compareSlice(calls, expected) {
+			t.Errorf("pooled %t: expect calls to be %v, but got %v", pooled, expected, calls)
+		}
+
+		calls = calls[:0]
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/ok", nil)
+		r.ServeHTTP(w, req)
+		expected = []string{"global", "cache", "handler"}
+		if !compareSlice(calls, expected) {
+			t.Errorf("pooled %t: expect calls to be %v, but got %v", pooled, expected, calls)
+		}
+	}
+
+	// Abort takes no effect unless the router is Abortable.
+	r := New()
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	}, newMiddleware("auth", true))
+	r.Prepare()
+	calls = calls[:0]
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	expected := []string{"auth", "handler"}
+	if !compareSlice(calls, expected) {
+		t.Errorf("expect calls to be %v, but got %v", expected, calls)
+	}
+}
+
+func TestAbort_Mounted(t *testing.T) {
+	r := New()
+	r.Abortable = true
+	r.Mount("/legacy", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Error("expect mounted handler to be skipped")
+	}), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			Abort(req)
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy/users", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestAbort_Allocs(t *testing.T) {
+	for _, abortable := range []bool{false, true} {
+		r := NewWithEngine(TreeEngine)
+		r.PooledParams = true
+		r.Abortable = abortable
+		r.Get("/users/<id>", func(w http.ResponseWriter, req *http.Request) {}, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				IsAborted(req)
+				next.ServeHTTP(w, req)
+			})
+		})
+		r.Prepare()
+
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		w := httptest.NewRecorder()
+		allocs := testing.AllocsPerRun(100, func() {
+			r.ServeHTTP(w, req)
+		})
+		// the only allocation is the shallow copy of request.
+		if allocs > 1 {
+			t.Errorf("abortable %t: expect at most 1 allocation per request, but got %v", abortable, allocs)
+		}
+	}
+}
+
+func TestAbort2(t *testing.T) {
+	// the request which is not dispatched by router.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	Abort(req)
+	if IsAborted(req) {
+		t.Error("expect request not to be aborted")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// AdminRoute is the runtime information of route that exposed by the
+// admin endpoint.
+type AdminRoute struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Hits    uint64 `json:"hits"`
+
+	// Indicates whether the route is the implicit HEAD route of a GET
+	// route via automatic HEAD, its hits is the number of HEAD
+	// requests which fall back to the GET route.
+	Implicit bool `json:"implicit,omitempty"`
+}
+
+// MountAdmin mounts an admin endpoint under the given prefix, such as
+// "/_router", it exposes the following runtime operations as JSON:
+//
+//	GET  /_router/routes                  lists routes
+//	POST /_router/routes/enable?name=foo  enables the route named foo
+//	POST /_router/routes/disable?name=foo disables the route named foo
+//	POST /_router/maintenance/enable      enables the maintenance mode
+//	POST /_router/maintenance/disable     disables the maintenance mode
+//	GET  /_router/stats                   views statistics
+//
+// The disabled routes are handled as Not Found, and all the requests
+// except the admin endpoint are responded with 503 Service Unavailable
+// in maintenance mode.
+//
+// The endpoint MUST be protected by the given auth middleware.
+//
+// Returns the admin group. Causes a panic if the auth is nil.
+func (r *Router) MountAdmin(prefix string, auth Middleware) *Router {
+	if auth == nil {
+		panic(`the auth middleware of admin endpoint MUST NOT be nil`)
+	}
+
+	root := r
+	if r.inline != nil {
+		root = r.inline
+	}
+	root = root.root()
+	admin := r.Group(prefix)
+	admin.admin = true
+	admin.Middleware = append(admin.Middleware, auth)
+	admin.Get("/routes", root.adminRoutes)
+	admin.Post("/routes/enable", root.adminSetRouteEnabled(true))
+	admin.Post("/routes/disable", root.adminSetRouteEnabled(false))
+	admin.Post("/maintenance/enable", root.adminSetMaintenance(true))
+	admin.Post("/maintenance/disable", root.adminSetMaintenance(false))
+	admin.Get("/stats", root.adminStats)
+
+	return admin
+}
+
+// isAdmin reports whether the router belongs to the admin group.
+func (r *Router) isAdmin() bool {
+	for router := r; router != nil; router = router.parent {
+		if router.admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// adminRouteList returns the runtime information of all routes.
+func (r *Router) adminRouteList() []AdminRoute {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := []AdminRoute{}
+	r.walk(func(route *Route) error {
+		info := AdminRoute{
+			Method:  route.method,
+			Pattern: route.router.fullPrefix() + route.pattern,
+			Name:    route.name,
+			Enabled: route.Enabled(),
+			Hits:    atomic.LoadUint64(&route.hits),
+		}
+		routes = append(routes, info)
+		if route.autoHead() {
+			info.Method = http.MethodHead
+			info.Hits = atomic.LoadUint64(&route.headHits)
+			info.Implicit = true
+			routes = append(routes, info)
+		}
+		return nil
+	})
+
+	return routes
+}
+
+func (r *Router) adminRoutes(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.adminRouteList())
+}
+
+func (r *Router) adminSetRouteEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := req.URL.Query().Get("name")
+		if err := r.SetEnabled(name, enabled); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "enabled": enabled})
+	}
+}
+
+func (r *Router) adminSetMaintenance(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var maintenance int32
+		if enabled {
+			maintenance = 1
+		}
+		atomic.StoreInt32(&r.maintenance, maintenance)
+		writeJSON(w, http.StatusOK, map[string]bool{"maintenance": enabled})
+	}
+}
+
+func (r *Router) adminStats(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"requests":    atomic.LoadUint64(&r.requests),
+		"maintenance": atomic.LoadInt32(&r.maintenance) != 0,
+		"routes":      r.adminRouteList(),
+	})
+}
+
+// writeJSON writes v as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(body)
+}
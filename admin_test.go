@@ -0,0 +1,154 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "secret" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func TestRouter_MountAdmin(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler).Name("users")
+	r.Group("v1").Get("/posts", emptyHandler)
+	r.MountAdmin("/_router", adminAuth)
+	r.Prepare()
+
+	serve := func(method, path string, auth bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if auth {
+			req.Header.Set("Authorization", "secret")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodGet, "/_router/routes", false); w.Code != http.StatusUnauthorized {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	serve(http.MethodGet, "/users", false)
+	w := serve(http.MethodGet, "/_router/routes", true)
+	var routes []AdminRoute
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 12 || routes[0] != (AdminRoute{http.MethodGet, "/users", "users", true, 1, false}) {
+		t.Errorf("expect 12 routes and the first to be users, but got %v", routes)
+	}
+
+	// disable route.
+	if w := serve(http.MethodPost, "/_router/routes/disable?name=users", true); w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if w := serve(http.MethodGet, "/users", false); w.Code != http.StatusNotFound {
+		t.Errorf("expect disabled route to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+	serve(http.MethodPost, "/_router/routes/enable?name=users", true)
+	if w := serve(http.MethodGet, "/users", false); w.Code != http.StatusOK {
+		t.Errorf("expect enabled route to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if w := serve(http.MethodPost, "/_router/routes/enable?name=unknown", true); w.Code != http.StatusNotFound {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+
+	// maintenance mode.
+	serve(http.MethodPost, "/_router/maintenance/enable", true)
+	if w := serve(http.MethodGet, "/v1/posts", false); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code in maintenance mode to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	w = serve(http.MethodGet, "/_router/stats", true)
+	var stats struct {
+		Requests    uint64
+		Maintenance bool
+		Routes      []AdminRoute
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Maintenance || stats.Requests != 11 || stats.Routes[0].Hits != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	serve(http.MethodPost, "/_router/maintenance/disable", true)
+	if w := serve(http.MethodGet, "/v1/posts", false); w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRouter_MountAdmin2(t *testing.T) {
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for nil auth middleware, but got nil")
+		}
+	}()
+	New().MountAdmin("/_router", nil)
+}
+
+func TestRouter_MountAdmin3(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler)
+	r.Get("/posts", emptyHandler)
+	r.Head("/posts", emptyHandler)
+	r.Prepare()
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	for i := 0; i < 2; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/users", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/posts", nil))
+	}
+
+	expect := []AdminRoute{
+		{Method: http.MethodGet, Pattern: "/users", Enabled: true, Hits: 1},
+		{Method: http.MethodHead, Pattern: "/users", Enabled: true, Hits: 2, Implicit: true},
+		{Method: http.MethodGet, Pattern: "/posts", Enabled: true},
+		{Method: http.MethodHead, Pattern: "/posts", Enabled: true, Hits: 2},
+	}
+	if routes := r.adminRouteList(); !reflect.DeepEqual(routes, expect) {
+		t.Errorf("expect routes to be %v, but got %v", expect, routes)
+	}
+
+	r.DisableAutoHead = true
+	if routes := r.adminRouteList(); len(routes) != 3 {
+		t.Errorf("expect 3 routes without automatic HEAD, but got %v", routes)
+	}
+}
+
+func TestRouter_MountAdminInline(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler).Name("users")
+	r.With(newHeaderMiddleware("Inline", "on")).MountAdmin("/_router", adminAuth)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/_router/routes/disable?name=users", nil)
+	req.Header.Set("Authorization", "secret")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if v := w.Header().Get("Inline"); v != "on" {
+		t.Errorf("expect header Inline to be %q, but got %q", "on", v)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect disabled route to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Alias registers a localized pattern of route for the given locale in
+// the same router, so that one handler is reachable under several
+// localized patterns, for example:
+//
+//	r.Get("/products/<id>", showProduct).Name("product").
+//		Alias("de", "/produkte/<id>").
+//		Alias("fr", "/produits/<id>")
+//
+// The alias shares the name of route, it is used by RequestURL for
+// reversing the route according to the locale of request, see
+// LocaleResolver.
+//
+// The alias copies the handler, middleware and metadata of route at the
+// time of calling, so that it SHOULD be called after the route was
+// configured.
+//
+// Returns the route itself for chaining. Causes a panic if the alias of
+// locale already exists, or the pattern is invalid.
+func (route *Route) Alias(locale, pattern string) *Route {
+	if _, ok := route.aliases[locale]; ok {
+		panic(fmt.Errorf("the alias of route %q for locale %q already exists", route.pattern, locale))
+	}
+
+	alias := route.router.Handle(route.method, pattern, nil, route.middleware...)
+	alias.handler = route.handler
+	alias.meta = route.meta
+	alias.description = route.description
+	alias.contextValues = route.contextValues
+	alias.rewriters = route.rewriters
+	alias.redirectHandler = route.redirectHandler
+	alias.skipMiddleware = route.skipMiddleware
+	alias.locale = locale
+
+	if route.aliases == nil {
+		route.aliases = make(map[string]*Route)
+	}
+	route.aliases[locale] = alias
+	return route
+}
+
+// localize returns the alias of route for the locale of request, or the
+// route itself if there is no such alias.
+func (route *Route) localize(req *http.Request) *Route {
+	if len(route.aliases) == 0 {
+		return route
+	}
+
+	var locale string
+	if resolver := route.router.root().LocaleResolver; resolver != nil {
+		locale = resolver(req)
+	} else if matched := matchedRoute(req); matched != nil {
+		locale = matched.locale
+	}
+	if alias, ok := route.aliases[locale]; ok {
+		return alias
+	}
+
+	return route
+}
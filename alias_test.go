@@ -0,0 +1,61 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_Alias(t *testing.T) {
+	r := New()
+	var link string
+	r.Get("/products/<id>", func(w http.ResponseWriter, req *http.Request) {
+		link, _ = r.RequestURL(req, "product", "id", Param(req, "id"))
+		w.Write([]byte("product " + Param(req, "id")))
+	}).Name("product").Alias("de", "/produkte/<id>").Alias("fr", "/produits/<id>")
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		link string
+	}{
+		{"/products/1", "/products/1"},
+		{"/produkte/1", "/produkte/1"},
+		{"/produits/1", "/produits/1"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Body.String() != "product 1" {
+			t.Errorf("expect body of %q to be %q, but got %q", test.path, "product 1", w.Body.String())
+		}
+		if link != test.link {
+			t.Errorf("expect link of %q to be %q, but got %q", test.path, test.link, link)
+		}
+	}
+
+	r.LocaleResolver = func(req *http.Request) string {
+		return req.Header.Get("Accept-Language")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	req.Header.Set("Accept-Language", "de")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if link != "/produkte/1" {
+		t.Errorf("expect link to be %q, but got %q", "/produkte/1", link)
+	}
+}
+
+func TestRoute_AliasDuplicate(t *testing.T) {
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for duplicate alias")
+		}
+	}()
+
+	r := New()
+	r.Get("/products", emptyHandler).Alias("de", "/produkte").Alias("de", "/waren")
+}
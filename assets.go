@@ -0,0 +1,198 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// assetEncodings is the precompressed variants of asset, in order of
+// preference.
+var assetEncodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Assets serves fingerprinted static assets according to a manifest.
+//
+// The manifest is a JSON file that maps the logical asset names to the
+// fingerprinted (hashed) filenames, which is usually generated by the
+// asset bundler, for example:
+//
+//	{
+//	    "css/app.css": "css/app.3f2a1b.css",
+//	    "js/app.js": "js/app.9c8d7e.js"
+//	}
+//
+// The fingerprinted files will be served with immutable caching, and
+// the precompressed variants (such as "css/app.3f2a1b.css.br" and
+// "css/app.3f2a1b.css.gz") will be served if the client accepts the
+// corresponding encoding.
+type Assets struct {
+	// the root directory of assets.
+	root http.FileSystem
+
+	// mapping from logical name to fingerprinted filename.
+	manifest map[string]string
+
+	// set of fingerprinted filenames.
+	fingerprinted map[string]bool
+
+	// the route that serves assets.
+	route *Route
+}
+
+// NewAssets returns a new Assets with the given root directory and
+// the manifest file.
+//
+// Returns non-nil error, if the manifest can not be read or parsed.
+func NewAssets(root, manifest string) (*Assets, error) {
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string)
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %q: %v", manifest, err)
+	}
+
+	assets := &Assets{
+		root:          http.Dir(root),
+		manifest:      make(map[string]string, len(m)),
+		fingerprinted: make(map[string]bool, len(m)),
+	}
+	for name, filename := range m {
+		filename = strings.TrimPrefix(path.Clean("/"+filename), "/")
+		assets.manifest[strings.TrimPrefix(path.Clean("/"+name), "/")] = filename
+		assets.fingerprinted[filename] = true
+	}
+
+	return assets, nil
+}
+
+// URL returns the URL of the fingerprinted file of the given logical
+// name, it is reversed from the route that serves assets.
+//
+// Returns non-nil error, if the assets is not served by any router
+// or the name does not exist in manifest.
+func (a *Assets) URL(name string) (string, error) {
+	if a.route == nil {
+		return "", fmt.Errorf("the assets is not served by any router")
+	}
+
+	filename, ok := a.manifest[strings.TrimPrefix(path.Clean("/"+name), "/")]
+	if !ok {
+		return "", fmt.Errorf("the asset %q does not exist in manifest", name)
+	}
+
+	return a.route.URL("filepath", filename)
+}
+
+// FuncMap returns the template functions, it can be passed to
+// the Funcs method of text/template and html/template:
+//
+//	assetURL "css/app.css"
+func (a *Assets) FuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"assetURL": a.URL,
+	}
+}
+
+func (a *Assets) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+Param(req, "filepath")), "/")
+
+	file, stat, err := openFile(a.root, name)
+	if err != nil {
+		fileError(w, req, err)
+		return
+	}
+	defer file.Close()
+
+	if stat.IsDir() {
+		http.NotFound(w, req)
+		return
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if a.fingerprinted[name] {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	acceptEncoding := req.Header.Get("Accept-Encoding")
+	for _, encoding := range assetEncodings {
+		if !acceptsEncoding(acceptEncoding, encoding.name) {
+			continue
+		}
+
+		encoded, encodedStat, err := openFile(a.root, name+encoding.ext)
+		if err != nil {
+			continue
+		}
+		defer encoded.Close()
+
+		if !encodedStat.IsDir() {
+			header.Set("Content-Encoding", encoding.name)
+			serveFile(w, req, name, encoded, encodedStat)
+			return
+		}
+	}
+
+	serveFile(w, req, name, file, stat)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value
+// accepts the given encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		q := ""
+		if i := strings.IndexByte(v, ';'); i >= 0 {
+			v, q = strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:])
+		}
+		if v != encoding && v != "*" {
+			continue
+		}
+		if strings.HasPrefix(q, "q=") {
+			if weight, err := strconv.ParseFloat(q[2:], 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// ServeAssets serves the fingerprinted static assets.
+//
+// The pattern MUST contains parameter placeholder named "filepath",
+// it is related to pattern parser.
+//
+// The assets is bound to the returned route, so that Assets.URL can
+// reverse the URLs of assets.
+func (r *Router) ServeAssets(pattern string, assets *Assets, middleware ...Middleware) *Route {
+	if !strings.Contains(pattern, "filepath") {
+		panic(`the pattern MUST contains parameter placeholder named "filepath"`)
+	}
+
+	route := r.Handle(http.MethodGet, pattern, assets.serveHTTP, middleware...)
+	assets.route = route
+	return route
+}
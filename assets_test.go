@@ -0,0 +1,162 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func newTestAssets(t *testing.T) (*Assets, func()) {
+	dir, err := ioutil.TempDir("", "fastrouter_assets")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+
+	files := map[string]string{
+		"manifest.json":         `{"css/app.css": "css/app.3f2a1b.css"}`,
+		"css/app.3f2a1b.css":    "body{}",
+		"css/app.3f2a1b.css.gz": "gzipped",
+		"css/plain.css":         "plain",
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := ioutil.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatalf("failed to create tmp file: %v", err)
+		}
+	}
+
+	assets, err := NewAssets(dir, filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("failed to create assets: %v", err)
+	}
+
+	return assets, func() { os.RemoveAll(dir) }
+}
+
+func TestNewAssets(t *testing.T) {
+	if _, err := NewAssets(os.TempDir(), "nonexistent.json"); err == nil {
+		t.Error("expect an error for nonexistent manifest, but got nil")
+	}
+}
+
+func TestAssets_URL(t *testing.T) {
+	assets, cleanup := newTestAssets(t)
+	defer cleanup()
+
+	if _, err := assets.URL("css/app.css"); err == nil {
+		t.Error("expect an error for unserved assets, but got nil")
+	}
+
+	r := New()
+	r.Group("static").ServeAssets("/<filepath:.+>", assets)
+
+	url, err := assets.URL("css/app.css")
+	if err != nil {
+		t.Fatalf("failed to get asset URL: %v", err)
+	}
+	if expect := "/static/css/app.3f2a1b.css"; url != expect {
+		t.Errorf("expect asset URL to be %q, but got %q", expect, url)
+	}
+
+	if _, err := assets.URL("nonexistent.css"); err == nil {
+		t.Error("expect an error for nonexistent asset, but got nil")
+	}
+
+	tmpl := template.Must(template.New("").Funcs(assets.FuncMap()).Parse(`{{assetURL "css/app.css"}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+	if expect := "/static/css/app.3f2a1b.css"; buf.String() != expect {
+		t.Errorf("expect template output to be %q, but got %q", expect, buf.String())
+	}
+}
+
+func TestRouter_ServeAssets(t *testing.T) {
+	assets, cleanup := newTestAssets(t)
+	defer cleanup()
+
+	r := New()
+	r.ServeAssets("/assets/<filepath:.+>", assets)
+	r.Prepare()
+
+	var req *http.Request
+	var w *httptest.ResponseRecorder
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/css/app.3f2a1b.css", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if body := "body{}"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+	if cacheControl := "public, max-age=31536000, immutable"; w.Header().Get("Cache-Control") != cacheControl {
+		t.Errorf("expect header Cache-Control to be %q, but got %q", cacheControl, w.Header().Get("Cache-Control"))
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expect no Content-Encoding, but got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/css/app.3f2a1b.css", nil)
+	req.Header.Set("Accept-Encoding", "br;q=0, gzip, deflate")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if body := "gzipped"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("expect header Content-Encoding to be %q, but got %q", "gzip", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expect header Vary to be %q, but got %q", "Accept-Encoding", w.Header().Get("Vary"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/css/plain.css", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Cache-Control") != "" {
+		t.Errorf("expect no Cache-Control for unfingerprinted file, but got %q", w.Header().Get("Cache-Control"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/css", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		encoding string
+		accepted bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"deflate, gzip;q=0.8", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"*", "br", true},
+		{"deflate", "br", false},
+	}
+	for _, test := range tests {
+		if accepted := acceptsEncoding(test.header, test.encoding); accepted != test.accepted {
+			t.Errorf("expect %q accepting %q to be %v, but got %v", test.header, test.encoding, test.accepted, accepted)
+		}
+	}
+}
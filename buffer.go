@@ -0,0 +1,150 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Buffered buffers the whole response of route, including the output
+// of middleware, until the handler returns, so that a handler which
+// fails after partially writing does not produce a torn body:
+//
+//   - if the handler panics, the buffered output is discarded, and the
+//     panic is propagated to the PanicHandler or RecoveryHandler, which
+//     can render a clean error response;
+//   - the handler can discard the buffered output via ResetResponse
+//     before rendering an error response.
+//
+// Calling Flush commits the buffered output and disables the buffering
+// for the rest of response, so the route SHOULD NOT be a streaming
+// route.
+//
+// Returns the route itself for chaining.
+func (route *Route) Buffered() *Route {
+	route.buffered = true
+	return route
+}
+
+// ResetResponse discards the buffered status code, headers and body of
+// the response of a buffered route, see Route.Buffered. It reports false
+// if the response is not buffered or has been committed.
+func ResetResponse(w http.ResponseWriter) bool {
+	for {
+		if bw, ok := w.(*bufferedWriter); ok {
+			return bw.reset()
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// bufferResponse returns a handler that serves the given handler with
+// a bufferedWriter.
+func bufferResponse(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bw := &bufferedWriter{w: w, original: w.Header().Clone()}
+		bw.header = bw.original.Clone()
+		handler.ServeHTTP(bw, req)
+		bw.commit()
+	})
+}
+
+// bufferedWriter is a http.ResponseWriter that buffers the status code,
+// headers and body until it is committed.
+type bufferedWriter struct {
+	w http.ResponseWriter
+
+	// the headers of the underlying writer before buffering.
+	original http.Header
+
+	header http.Header
+	code   int
+	body   bytes.Buffer
+
+	// indicates whether the buffered output was committed.
+	committed bool
+}
+
+func (bw *bufferedWriter) Header() http.Header {
+	if bw.committed {
+		return bw.w.Header()
+	}
+
+	return bw.header
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.committed {
+		bw.w.WriteHeader(code)
+		return
+	}
+	if bw.code == 0 {
+		bw.code = code
+	}
+}
+
+func (bw *bufferedWriter) Write(p []byte) (int, error) {
+	if bw.committed {
+		return bw.w.Write(p)
+	}
+	if bw.code == 0 {
+		bw.code = http.StatusOK
+	}
+
+	return bw.body.Write(p)
+}
+
+// Flush implements http.Flusher, it commits the buffered output.
+func (bw *bufferedWriter) Flush() {
+	bw.commit()
+	if f, ok := bw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (bw *bufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.w
+}
+
+// reset discards the buffered output, it reports false if the output
+// has been committed.
+func (bw *bufferedWriter) reset() bool {
+	if bw.committed {
+		return false
+	}
+
+	bw.header = bw.original.Clone()
+	bw.code = 0
+	bw.body.Reset()
+	return true
+}
+
+// commit writes the buffered output into the underlying writer.
+func (bw *bufferedWriter) commit() {
+	if bw.committed {
+		return
+	}
+	bw.committed = true
+
+	header := bw.w.Header()
+	for k := range header {
+		delete(header, k)
+	}
+	for k, v := range bw.header {
+		header[k] = v
+	}
+	if bw.code != 0 {
+		bw.w.WriteHeader(bw.code)
+	}
+	if bw.body.Len() > 0 {
+		bw.w.Write(bw.body.Bytes())
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_Buffered(t *testing.T) {
+	r := New()
+	r.RecoveryHandler = func(w http.ResponseWriter, req *http.Request, info PanicInfo) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+	r.Get("/panic", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Partial", "1")
+		w.Write([]byte("partial"))
+		panic("boom")
+	}).Buffered()
+	r.Get("/reset", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Partial", "1")
+		w.Write([]byte("partial"))
+		if !ResetResponse(w) {
+			t.Error("expect response to be reset")
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}, newHeaderMiddleware("X-Middleware", "1")).Buffered()
+	r.Get("/ok", helloHandler("ok")).Buffered()
+	r.Get("/unbuffered", func(w http.ResponseWriter, req *http.Request) {
+		if ResetResponse(w) {
+			t.Error("expect unbuffered response not to be reset")
+		}
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path    string
+		code    int
+		body    string
+		partial bool
+	}{
+		{"/panic", http.StatusInternalServerError, "internal error\n", false},
+		{"/reset", http.StatusBadGateway, "bad gateway\n", false},
+		{"/ok", http.StatusOK, "ok", false},
+		{"/unbuffered", http.StatusOK, "", false},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("expect response of %q to be %d %q, but got %d %q", test.path, test.code, test.body, w.Code, w.Body.String())
+		}
+		if partial := w.Header().Get("X-Partial") != ""; partial != test.partial {
+			t.Errorf("expect partial header of %q to be present: %t, but got %t", test.path, test.partial, partial)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// newFoldMatcher returns a matcher that matches request path against
+// the routes case-insensitively, it is always backed by the combined
+// regular expression, regardless of the matching engine.
+func newFoldMatcher(routes []*Route) matcher {
+	return newValidatingMatcher(routes, func(routes []*Route) matcher {
+		m := newRegexpMatcher(routes)
+		m.reg = regexp.MustCompile("(?i)" + m.reg.String())
+		return m
+	})
+}
+
+// prepareFold prepares the case-insensitive matchers and the mapping
+// from lowercase prefix to group.
+func (r *Router) prepareFold() {
+	foldMatchers := make(map[string]matcher, len(r.routes))
+	for method, routes := range r.routes {
+		foldMatchers[method] = newFoldMatcher(sortRoutes(routes))
+	}
+	r.foldMatchers.Store(foldMatchers)
+
+	r.foldGroups = make(map[string]*Router, len(r.groups))
+	// the first prefix in alphabetical order wins if multiple prefixes
+	// are the same case-insensitively.
+	for _, prefix := range r.sortedPrefixes() {
+		group := r.groups[prefix]
+		if _, ok := r.foldGroups[strings.ToLower(prefix)]; !ok && group.prefixReg == nil {
+			r.foldGroups[strings.ToLower(prefix)] = group
+		}
+	}
+}
+
+// fetchGroupFold returns the group which the path belongs to as same
+// as fetchGroup, except that the static prefixes are matched
+// case-insensitively, the canonical is the path prefix in canonical
+// casing.
+func (r *Router) fetchGroupFold(path string) (router *Router, rel string, values []string, canonical string) {
+	router, rel = r, path
+	for rel != "/" {
+		i := 1
+		for ; i < len(rel) && rel[i] != '/'; i++ {
+		}
+		if i == 1 {
+			break
+		}
+
+		segment := rel[1:i]
+		group, ok := router.foldGroups[strings.ToLower(segment)]
+		if ok {
+			segment = group.prefix
+		}
+		for j := 0; !ok && j < len(router.paramGroups); j++ {
+			var vs []string
+			if vs, ok = router.paramGroups[j].matchPrefix(segment); ok {
+				group = router.paramGroups[j]
+				values = append(values, vs...)
+			}
+		}
+		if !ok {
+			break
+		}
+
+		router = group
+		canonical += "/" + segment
+		if i == len(rel) {
+			rel = "/"
+			break
+		}
+		rel = rel[i:]
+	}
+
+	return
+}
+
+// serveFold handles the request which no route matched case-sensitively,
+// with the route which matches the path case-insensitively, or redirects
+// to the path in canonical casing if RedirectFixedCase is enabled, it
+// reports whether a route matched. The restrictions of the group which
+// matched case-insensitively are enforced as same as ServeHTTP, so that
+// they can not be bypassed by changing the casing of path.
+func (r *Router) serveFold(w http.ResponseWriter, req *http.Request) bool {
+	router, path, prefixValues, canonical := r.fetchGroupFold(r.requestPath(req))
+	if router.finalMounted != nil {
+		if !r.admits(w, req, router) {
+			return true
+		}
+		if r.RedirectFixedCase && r.redirectFixedCase(w, req, canonical, path) {
+			return true
+		}
+		router.serveMounted(w, req, path, prefixValues)
+		return true
+	}
+
+	method := req.Method
+	route, values := matchFold(router, method, path, prefixValues)
+	if route == nil && method == http.MethodHead && !r.DisableAutoHead {
+		// falls back to the GET handler.
+		if route, values = matchFold(router, http.MethodGet, path, prefixValues); route != nil {
+			w = &headResponseWriter{w}
+		}
+	}
+	if route == nil {
+		return false
+	}
+	if !r.admits(w, req, router) {
+		return true
+	}
+
+	if r.RedirectFixedCase {
+		if fixed, ok := route.fixCase(path, values[len(prefixValues):]); ok && r.redirectFixedCase(w, req, canonical, fixed) {
+			return true
+		}
+	}
+
+	r.handle(w, req, route, values, nil)
+	return true
+}
+
+// matchFold matches the path against the case-insensitive matcher of
+// the given router and method.
+func matchFold(router *Router, method, path string, prefixValues []string) (*Route, []string) {
+	foldMatchers, _ := router.foldMatchers.Load().(map[string]matcher)
+	if m, ok := foldMatchers[method]; ok {
+		return m.match(path, prefixValues)
+	}
+
+	return nil, nil
+}
+
+// fixCase returns the path relative to the group in canonical casing
+// by building the route pattern with the parameter values, ok is false
+// if the path can not be built.
+func (route *Route) fixCase(path string, values []string) (fixed string, ok bool) {
+	builder, ok := route.router.parser.(BuilderInterface)
+	if !ok {
+		return "", false
+	}
+
+	params := make(map[string]string, len(route.params))
+	for i, name := range route.params {
+		params[name] = values[i]
+	}
+	var err error
+	if p, ok := builder.(Parser); ok {
+		fixed, err = p.build(route.pattern, params, route.router.root().constraints)
+	} else {
+		fixed, err = builder.Build(route.pattern, params)
+	}
+	if err != nil {
+		return "", false
+	}
+
+	// keeps the trailing slashes of request path.
+	if fixed != "/" && strings.HasSuffix(fixed, "/") != strings.HasSuffix(path, "/") {
+		if strings.HasSuffix(path, "/") {
+			fixed += "/"
+		} else {
+			fixed = fixed[:len(fixed)-1]
+		}
+	}
+
+	return fixed, true
+}
+
+// redirectFixedCase redirects the request to the path in canonical
+// casing which consists of the given prefix and the path relative to
+// the group, it reports false without redirecting if the request path
+// is already in canonical casing.
+func (r *Router) redirectFixedCase(w http.ResponseWriter, req *http.Request, prefix, path string) bool {
+	if path == "/" && prefix != "" && !strings.HasSuffix(req.URL.Path, "/") {
+		path = ""
+	}
+	path = prefix + path
+	if path == req.URL.Path {
+		return false
+	}
+
+	r.redirectPermanently(w, req, path)
+	return true
+}
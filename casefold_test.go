@@ -0,0 +1,139 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_CaseInsensitive(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.CaseInsensitive = true
+		r.Get("/users/<name>", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(Param(req, "name")))
+		})
+		api := r.Group("api/v1")
+		api.Get("/", helloHandler("api"))
+		api.Get("/posts", helloHandler("posts"))
+		r.Prepare()
+
+		tests := []struct {
+			method string
+			path   string
+			code   int
+			body   string
+		}{
+			{http.MethodGet, "/USERS/Foo", http.StatusOK, "Foo"},
+			{http.MethodGet, "/Api/V1/Posts", http.StatusOK, "posts"},
+			{http.MethodGet, "/API/v1", http.StatusOK, "api"},
+			{http.MethodHead, "/API/V1/POSTS", http.StatusOK, ""},
+			{http.MethodGet, "/unknown", http.StatusNotFound, "404 page not found\n"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+			if w.Code != test.code || w.Body.String() != test.body {
+				t.Errorf("engine %d: expect response of %s %q to be %d %q, but got %d %q", engine, test.method, test.path, test.code, test.body, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRouter_RedirectFixedCase(t *testing.T) {
+	r := New()
+	r.CaseInsensitive = true
+	r.RedirectFixedCase = true
+	r.Get("/users/<name>", emptyHandler)
+	r.Post("/users/", emptyHandler)
+	api := r.Group("api")
+	api.Get("/", emptyHandler)
+	api.Get("/posts/<id:\\d+>", emptyHandler)
+	r.Mount("/debug", http.HandlerFunc(emptyHandler))
+	r.Prepare()
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/USERS/Foo?page=2", http.StatusMovedPermanently, "/users/Foo?page=2"},
+		{http.MethodPost, "/Users/", http.StatusPermanentRedirect, "/users/"},
+		{http.MethodGet, "/API", http.StatusMovedPermanently, "/api"},
+		{http.MethodGet, "/API/", http.StatusMovedPermanently, "/api/"},
+		{http.MethodGet, "/Api/Posts/1", http.StatusMovedPermanently, "/api/posts/1"},
+		{http.MethodGet, "/DEBUG/vars", http.StatusMovedPermanently, "/debug/vars"},
+		{http.MethodGet, "/users/Foo", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code || w.Header().Get("Location") != test.location {
+			t.Errorf("expect response of %s %q to be %d %q, but got %d %q", test.method, test.path, test.code, test.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRouter_CaseInsensitiveDeterministic(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		r := New()
+		r.CaseInsensitive = true
+		r.Group("users").Get("/", helloHandler("lower"))
+		r.Group("Users").Get("/", helloHandler("upper"))
+		r.Prepare()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/USERS", nil))
+		if w.Body.String() != "upper" {
+			t.Fatalf("expect the first prefix in alphabetical order to win, but got %q", w.Body.String())
+		}
+	}
+}
+
+func TestRouter_CaseInsensitiveRestrictions(t *testing.T) {
+	r := New()
+	r.CaseInsensitive = true
+	internal := r.Group("internal")
+	internal.Networks = []string{"10.0.0.0/8"}
+	internal.Get("/metrics", helloHandler("metrics"))
+	admin := r.Group("admin")
+	admin.Ports = []string{"9000"}
+	admin.Get("/users", helloHandler("users"))
+	tenant := r.Group("tenant")
+	tenant.HeaderLimit = &HeaderLimit{MaxCount: 1}
+	tenant.Get("/", helloHandler("tenant"))
+	r.Mount("/debug", http.HandlerFunc(emptyHandler))
+	r.groups["debug"].Networks = []string{"10.0.0.0/8"}
+	r.Prepare()
+
+	tests := []struct {
+		remoteAddr string
+		path       string
+		code       int
+	}{
+		{"10.1.2.3:1234", "/INTERNAL/metrics", http.StatusOK},
+		{"203.0.113.7:1234", "/internal/metrics", http.StatusNotFound},
+		{"203.0.113.7:1234", "/INTERNAL/metrics", http.StatusNotFound},
+		{"203.0.113.7:1234", "/Internal/Metrics", http.StatusNotFound},
+		{"203.0.113.7:1234", "/Admin/Users", http.StatusNotFound},
+		{"203.0.113.7:1234", "/Tenant", http.StatusRequestHeaderFieldsTooLarge},
+		{"10.1.2.3:1234", "/DEBUG/vars", http.StatusOK},
+		{"203.0.113.7:1234", "/DEBUG/vars", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("X-A", "a")
+		req.Header.Set("X-B", "b")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q from %q to be %d, but got %d", test.path, test.remoteAddr, test.code, w.Code)
+		}
+	}
+}
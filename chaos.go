@@ -0,0 +1,41 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+)
+
+// ErrSyntheticPanic is the panic value of the synthetic panics, see
+// Route.PanicRate.
+var ErrSyntheticPanic = errors.New("fastrouter: synthetic panic")
+
+// PanicRate triggers synthetic panics on the given rate of requests of
+// route for chaos testing, the rate is in range of [0, 1], such as 0.1
+// for 10% of requests, it allows to verify the behaviors of the
+// PanicHandler and recovery middleware in staging.
+//
+// The panics are raised with ErrSyntheticPanic in place of the route
+// handler, that is, after the middleware of route was called, the rate
+// MUST be set before Prepare.
+//
+// Returns the route itself for chaining.
+func (route *Route) PanicRate(rate float64) *Route {
+	route.panicRate = rate
+	return route
+}
+
+// syntheticPanic returns a handler that panics on the given rate of
+// requests, and calls the next handler otherwise.
+func syntheticPanic(next http.Handler, rate float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rand.Float64() < rate {
+			panic(ErrSyntheticPanic)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
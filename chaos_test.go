@@ -0,0 +1,43 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_PanicRate(t *testing.T) {
+	panics := 0
+	r := New()
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		if rcv != ErrSyntheticPanic {
+			t.Errorf("expect panic to be %v, but got %v", ErrSyntheticPanic, rcv)
+		}
+		panics++
+	}
+	r.Get("/always", emptyHandler).PanicRate(1)
+	r.Get("/never", emptyHandler).PanicRate(0)
+	r.Get("/half", emptyHandler).PanicRate(0.5)
+	r.Prepare()
+
+	serve := func(path string, n int) int {
+		panics = 0
+		for i := 0; i < n; i++ {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+		return panics
+	}
+	if n := serve("/always", 10); n != 10 {
+		t.Errorf("expect 10 panics, but got %d", n)
+	}
+	if n := serve("/never", 10); n != 0 {
+		t.Errorf("expect no panics, but got %d", n)
+	}
+	if n := serve("/half", 1000); n < 300 || n > 700 {
+		t.Errorf("expect about 500 panics, but got %d", n)
+	}
+}
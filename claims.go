@@ -0,0 +1,28 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net/http"
+)
+
+type claimsKey struct{}
+
+var contextClaimsKey claimsKey
+
+// WithClaims returns a copy of the given context with the claims of
+// authenticated token, it is usually called by the authentication
+// middleware, such as middleware.JWT.
+func WithClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	return context.WithValue(ctx, contextClaimsKey, claims)
+}
+
+// Claims returns the claims which are stored in the context of request
+// via WithClaims, nil if the request is not authenticated.
+func Claims(req *http.Request) map[string]interface{} {
+	claims, _ := req.Context().Value(contextClaimsKey).(map[string]interface{})
+	return claims
+}
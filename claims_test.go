@@ -0,0 +1,23 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClaims(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if claims := Claims(req); claims != nil {
+		t.Errorf("expect claims to be nil, but got %v", claims)
+	}
+
+	req = req.WithContext(WithClaims(req.Context(), map[string]interface{}{"sub": "foo"}))
+	if sub := Claims(req)["sub"]; sub != "foo" {
+		t.Errorf("expect subject to be %q, but got %v", "foo", sub)
+	}
+}
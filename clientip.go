@@ -0,0 +1,113 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+var contextClientIPKey clientIPKey
+
+// WithClientIP returns a copy of the given context with the resolved
+// client IP, it is usually called by the real IP middleware, such as
+// middleware.RealIP.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextClientIPKey, ip)
+}
+
+// ClientIP returns the client IP which is stored in the context of
+// request via WithClientIP, or the host of the remote address of
+// request if there is no such IP.
+func ClientIP(req *http.Request) string {
+	if ip, ok := req.Context().Value(contextClientIPKey).(string); ok {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ResolveClientIP resolves the client IP of the request which is
+// forwarded by the trusted proxies, the remote is the IP of the peer,
+// and the trusted reports whether the given IP is a trusted proxy. It
+// is shared by the Networks and middleware.RealIP, so that both of
+// them agree on the client of the same request.
+//
+// The client IP is resolved from the first present header of Forwarded,
+// X-Forwarded-For and X-Real-IP, the addresses of the list headers are
+// walked from right to left, and the first address which is not a
+// trusted proxy is the client IP. The remote is returned as it is if
+// it is not a trusted proxy, or the headers are missing or malformed,
+// since the headers can be forged.
+func ResolveClientIP(header http.Header, remote netip.Addr, trusted func(netip.Addr) bool) netip.Addr {
+	if !trusted(remote) {
+		return remote
+	}
+
+	var addrs []string
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		addrs = forwardedFor(values)
+	} else if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			for _, addr := range strings.Split(value, ",") {
+				addrs = append(addrs, strings.TrimSpace(addr))
+			}
+		}
+	} else if value := strings.TrimSpace(header.Get("X-Real-IP")); value != "" {
+		addrs = []string{value}
+	}
+
+	client := remote
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr, err := parseNodeAddr(addrs[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !trusted(addr) {
+			break
+		}
+	}
+
+	return client
+}
+
+// forwardedFor returns the for parameters of the Forwarded headers, in
+// order, see RFC 7239.
+func forwardedFor(values []string) []string {
+	var addrs []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					addrs = append(addrs, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+
+	return addrs
+}
+
+// parseNodeAddr parses the node address which may contain a port or
+// brackets, such as "192.0.2.60:8080" and "[2001:db8::1]", the IPv4
+// mapped IPv6 address is unmapped.
+func parseNodeAddr(s string) (netip.Addr, error) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	return addr.Unmap(), err
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if ip := ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expect client IP to be %q, but got %q", "10.0.0.1", ip)
+	}
+
+	req = req.WithContext(WithClientIP(req.Context(), "203.0.113.7"))
+	if ip := ClientIP(req); ip != "203.0.113.7" {
+		t.Errorf("expect client IP to be %q, but got %q", "203.0.113.7", ip)
+	}
+}
+
+func TestResolveClientIP(t *testing.T) {
+	proxies := netip.MustParsePrefix("10.0.0.0/8")
+	trusted := func(addr netip.Addr) bool {
+		return proxies.Contains(addr)
+	}
+
+	tests := []struct {
+		remote string
+		header http.Header
+		ip     string
+	}{
+		{"203.0.113.7", http.Header{"X-Forwarded-For": {"1.1.1.1"}}, "203.0.113.7"},
+		{"10.0.0.1", nil, "10.0.0.1"},
+		{"10.0.0.1", http.Header{"X-Forwarded-For": {"1.1.1.1, 203.0.113.7", "10.0.0.2"}}, "203.0.113.7"},
+		{"10.0.0.1", http.Header{"X-Real-Ip": {"203.0.113.7"}}, "203.0.113.7"},
+		{"10.0.0.1", http.Header{"Forwarded": {`for=1.1.1.1, for="[2001:db8::1]:8080";proto=https`}, "X-Forwarded-For": {"1.1.1.1"}}, "2001:db8::1"},
+		{"10.0.0.1", http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"10.0.0.1", http.Header{"X-Forwarded-For": {"unknown"}}, "10.0.0.1"},
+	}
+	for _, test := range tests {
+		if ip := ResolveClientIP(test.header, netip.MustParseAddr(test.remote), trusted).String(); ip != test.ip {
+			t.Errorf("expect client IP of %q with %v to be %q, but got %q", test.remote, test.header, test.ip, ip)
+		}
+	}
+}
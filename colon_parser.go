@@ -0,0 +1,116 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NewColonParser returns a new ColonParser.
+func NewColonParser() ColonParser {
+	return ColonParser{}
+}
+
+// ColonParser is a httprouter-style pattern parser which implements
+// ParserInterface and BuilderInterface, it allows the projects that
+// migrating from httprouter or gin to keep their patterns unchanged:
+//
+//	r := fastrouter.NewWithParser(fastrouter.NewColonParser())
+type ColonParser struct {
+}
+
+// Parse implements ParserInterface's Parse method.
+//
+// The pattern MUST be begin with '/', and the named parameters MUST be
+// one of ':name' and '*name':
+//
+//	`:name` // matches a single path segment, will be converted to `([^/]+)`
+//
+//	`*name` // matches the rest of path, will be converted to `(.*)`
+//
+// The catch-all parameter '*name' MUST be at the end of pattern, and
+// the static parts of pattern are matched literally.
+//
+// Examples:
+//
+//	| Pattern                       | Regexp                          | hasTrailingSlashes | Params                      |
+//	|:------------------------------|:--------------------------------|:-------------------|:----------------------------|
+//	| `/`                           | `//?`                           | NO                 |                             |
+//	| `/users/:name`                | `/users/([^/]+)/?`              | NO                 | `[]string{"name"}`          |
+//	| `/users/:name/posts/`         | `/users/([^/]+)/posts/?`        | YES                | `[]string{"name"}`          |
+//	| `/static/*filepath`           | `/static/(.*)`                  | NO                 | `[]string{"filepath"}`      |
+//	| `/users/:name/files/*path`    | `/users/([^/]+)/files/(.*)`     | NO                 | `[]string{"name", "path"}`  |
+func (p ColonParser) Parse(pattern string) (reg string, params []string, hasTrailingSlashes bool, err error) {
+	if pattern == "" || pattern[0] != '/' {
+		err = fmt.Errorf(`the pattern MUST begin with '/' in pattern %q`, pattern)
+		return
+	}
+
+	if pattern != "/" && pattern[len(pattern)-1] == '/' {
+		hasTrailingSlashes = true
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	catchAll := false
+	segments := strings.Split(pattern[1:], "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			segments[i] = regexp.QuoteMeta(segment)
+			continue
+		}
+
+		name := segment[1:]
+		if name == "" {
+			err = fmt.Errorf("the parameter name MUST NOT be empty in pattern %q", pattern)
+			return
+		}
+		params = append(params, name)
+
+		if segment[0] == ':' {
+			segments[i] = `([^/]+)`
+			continue
+		}
+
+		if i != len(segments)-1 || hasTrailingSlashes {
+			err = fmt.Errorf("the catch-all parameter MUST be at the end of pattern %q", pattern)
+			return
+		}
+		segments[i] = `(.*)`
+		catchAll = true
+	}
+
+	reg = "/" + strings.Join(segments, "/")
+	if !catchAll {
+		reg += "/?"
+	}
+
+	return
+}
+
+// Build implements BuilderInterface's Build method.
+//
+// The value of ':name' MUST NOT be empty or contains '/'.
+func (p ColonParser) Build(pattern string, params map[string]string) (path string, err error) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+
+		name := segment[1:]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("the parameter %q is missing in pattern %q", name, pattern)
+		}
+		if segment[0] == ':' && (value == "" || strings.Contains(value, "/")) {
+			return "", fmt.Errorf("the parameter %q MUST NOT be empty or contains '/' in pattern %q", name, pattern)
+		}
+		segments[i] = value
+	}
+
+	return strings.Join(segments, "/"), nil
+}
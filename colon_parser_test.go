@@ -0,0 +1,109 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestColonParser_Parse(t *testing.T) {
+	emptyParams := []string{}
+	testPatterns := map[string]testPattern{
+		"":                         {"", emptyParams, false, errorString(`the pattern MUST begin with '/' in pattern ""`)},
+		"/":                        {"//?", emptyParams, false, nil},
+		"/users":                   {"/users/?", emptyParams, false, nil},
+		"/users/":                  {"/users/?", emptyParams, true, nil},
+		"/app.css":                 {`/app\.css/?`, emptyParams, false, nil},
+		"/users/:name":             {"/users/([^/]+)/?", []string{"name"}, false, nil},
+		"/users/:name/posts/":      {"/users/([^/]+)/posts/?", []string{"name"}, true, nil},
+		"/static/*filepath":        {"/static/(.*)", []string{"filepath"}, false, nil},
+		"/users/:name/files/*path": {"/users/([^/]+)/files/(.*)", []string{"name", "path"}, false, nil},
+		"/users/:":                 {"", []string{}, false, errorString(`the parameter name MUST NOT be empty in pattern "/users/:"`)},
+		"/static/*filepath/edit":   {"", []string{"filepath"}, false, errorString(`the catch-all parameter MUST be at the end of pattern "/static/*filepath/edit"`)},
+		"/static/*filepath/":       {"", []string{"filepath"}, true, errorString(`the catch-all parameter MUST be at the end of pattern "/static/*filepath"`)},
+	}
+
+	parser := NewColonParser()
+	for pattern, v := range testPatterns {
+		reg, params, hasTrailingSlashes, err := parser.Parse(pattern)
+		if err != nil || v.err != nil {
+			if err == nil || v.err == nil || err.Error() != v.err.Error() {
+				t.Errorf("expect the err of pattern %q to be %v, but got %v", pattern, v.err, err)
+			}
+			continue
+		}
+		if v.reg != reg {
+			t.Errorf("expect the reg of pattern %q to be %q, but got %q", pattern, v.reg, reg)
+		}
+		if !compareSlice(v.params, params) {
+			t.Errorf("expect the params of pattern %q to be %v, but got %v", pattern, v.params, params)
+		}
+		if v.hasTrailingSlashes != hasTrailingSlashes {
+			t.Errorf("expect the hasTrailingSlashes of pattern %q to be %v, but got %v", pattern, v.hasTrailingSlashes, hasTrailingSlashes)
+		}
+	}
+}
+
+func TestColonParser_Build(t *testing.T) {
+	parser := NewColonParser()
+	tests := []struct {
+		pattern string
+		params  map[string]string
+		path    string
+		hasErr  bool
+	}{
+		{"/", nil, "/", false},
+		{"/users/:name/", map[string]string{"name": "foo"}, "/users/foo/", false},
+		{"/users/:name", nil, "", true},
+		{"/users/:name", map[string]string{"name": "foo/bar"}, "", true},
+		{"/static/*filepath", map[string]string{"filepath": "css/app.css"}, "/static/css/app.css", false},
+	}
+	for _, test := range tests {
+		path, err := parser.Build(test.pattern, test.params)
+		if path != test.path {
+			t.Errorf("expect the path of pattern %q to be %q, but got %q", test.pattern, test.path, path)
+		}
+		if (err != nil) != test.hasErr {
+			t.Errorf("expect the err of pattern %q to be non-nil: %v, but got %v", test.pattern, test.hasErr, err)
+		}
+	}
+}
+
+func TestColonParser(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithParser(NewColonParser())
+		r.Engine = engine
+		var params map[string]string
+		r.Get("/users/:name", func(w http.ResponseWriter, req *http.Request) {
+			params = Params(req)
+		})
+		r.Get("/static/*filepath", func(w http.ResponseWriter, req *http.Request) {
+			params = Params(req)
+		})
+		r.Prepare()
+
+		tests := map[string]map[string]string{
+			"/users/foo":          {"name": "foo"},
+			"/static/css/app.css": {"filepath": "css/app.css"},
+			"/static/":            {"filepath": ""},
+		}
+		for path, expect := range tests {
+			params = nil
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			if !reflect.DeepEqual(params, expect) {
+				t.Errorf("engine %d: expect params of %q to be %v, but got %v", engine, path, expect, params)
+			}
+		}
+	}
+}
+
+type errorString string
+
+func (e errorString) Error() string {
+	return string(e)
+}
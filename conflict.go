@@ -0,0 +1,127 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RouteConflict is a pair of ambiguous routes which have the same
+// request method and belong to the same group.
+type RouteConflict struct {
+	// The request method.
+	Method string
+
+	// The full prefix of the group which the routes belong to.
+	Prefix string
+
+	// The pattern of route which is ambiguous.
+	Pattern string
+
+	// The pattern of the other route which also matches the path of
+	// Pattern.
+	Conflicting string
+
+	// The pattern of route which wins the path of Pattern.
+	Winner string
+
+	// Indicates whether the patterns are the same, the latter route
+	// is unreachable in that case.
+	Duplicate bool
+}
+
+func (c RouteConflict) String() string {
+	if c.Duplicate {
+		return fmt.Sprintf("duplicate route %s %q in group %q", c.Method, c.Pattern, c.Prefix)
+	}
+
+	return fmt.Sprintf("route %s %q conflicts with %q in group %q, %q wins", c.Method, c.Pattern, c.Conflicting, c.Prefix, c.Winner)
+}
+
+// ConflictError is the error of ambiguous routes, it is returned by
+// PrepareE if the FatalConflicts is enabled.
+type ConflictError struct {
+	Conflicts []RouteConflict
+}
+
+func (e *ConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = c.String()
+	}
+
+	return fmt.Sprintf("%d route conflicts: %s", len(e.Conflicts), strings.Join(conflicts, "; "))
+}
+
+// Unwrap returns ErrConflict, so that the error can be checked via
+// errors.Is.
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Conflicts returns the ambiguous routes of router and its groups, in
+// order of Walk, such as "/users/<id>" and "/users/new", the winner of
+// them depends on the matching engine and the order of registration.
+//
+// The duplicate routes are always reported, and the other conflicts
+// are only reported if one of the routes is static, see Priority, the
+// overlaps between the parameterized routes are not detected.
+//
+// Note that, the router MUST makes preparations before detecting.
+func (r *Router) Conflicts() []RouteConflict {
+	root := r.root()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	conflicts := []RouteConflict{}
+	r.collectConflicts(&conflicts)
+	return conflicts
+}
+
+func (r *Router) collectConflicts(conflicts *[]RouteConflict) {
+	prefix := r.fullPrefix()
+	regs := make(map[*Route]*regexp.Regexp)
+	for _, method := range r.sortedMethods() {
+		routes := r.routes[method]
+		for i, route := range routes {
+			duplicate := false
+			for _, other := range routes[:i] {
+				if other.pattern == route.pattern {
+					duplicate = true
+					*conflicts = append(*conflicts, RouteConflict{method, prefix, route.pattern, other.pattern, other.pattern, true})
+					break
+				}
+			}
+			if duplicate || !route.isStatic() {
+				continue
+			}
+
+			winner, _ := r.loadMatchers()[method].match(route.pattern, nil)
+			for _, other := range routes {
+				if other.pattern == route.pattern {
+					continue
+				}
+				reg, ok := regs[other]
+				if !ok {
+					reg = regexp.MustCompile("^(?:" + other.reg + ")$")
+					regs[other] = reg
+				}
+				if reg.MatchString(route.pattern) && other.validate(reg.FindStringSubmatch(route.pattern)[1 : 1+len(other.params)]) {
+					c := RouteConflict{Method: method, Prefix: prefix, Pattern: route.pattern, Conflicting: other.pattern}
+					if winner != nil {
+						c.Winner = winner.pattern
+					}
+					*conflicts = append(*conflicts, c)
+				}
+			}
+		}
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		r.groups[prefix].collectConflicts(conflicts)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouter_Conflicts(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Constraint("even", isEven)
+		r.Get("/users/<id>", emptyHandler)
+		r.Get("/users/new", emptyHandler)
+		r.Get("/posts/<id:\\d+>", emptyHandler)
+		r.Get("/posts/new", emptyHandler)
+		r.Get("/numbers/<n:even>", emptyHandler)
+		r.Get("/numbers/3", emptyHandler)
+		v1 := r.Group("v1")
+		v1.Get("/", emptyHandler)
+		v1.Get("/", emptyHandler)
+		r.Prepare()
+
+		expect := []RouteConflict{
+			{Method: "GET", Pattern: "/users/new", Conflicting: "/users/<id>", Winner: "/users/new"},
+			{Method: "GET", Prefix: "/v1", Pattern: "/", Conflicting: "/", Winner: "/", Duplicate: true},
+		}
+		if conflicts := r.Conflicts(); !reflect.DeepEqual(conflicts, expect) {
+			t.Errorf("engine %d: expect conflicts to be %v, but got %v", engine, expect, conflicts)
+		}
+	}
+}
+
+func TestRouter_FatalConflicts(t *testing.T) {
+	r := New()
+	r.FatalConflicts = true
+	r.Get("/users/new", emptyHandler)
+	r.Get("/users/<id>", emptyHandler)
+	err := r.PrepareE()
+	conflictErr, ok := err.(*ConflictError)
+	if !ok || len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Winner != "/users/new" {
+		t.Errorf("expect a conflict error, but got %v", err)
+	}
+
+	var buf bytes.Buffer
+	r = New()
+	r.Logger = log.New(&buf, "", 0)
+	r.LogSummary = true
+	r.Get("/users/<id>", emptyHandler).Priority(1)
+	r.Get("/users/new", emptyHandler)
+	if err := r.PrepareE(); err != nil {
+		t.Errorf("expect no error, but got %v", err)
+	}
+	expect := `fastrouter: warning: route GET "/users/new" conflicts with "/users/<id>" in group "", "/users/<id>" wins`
+	if !strings.Contains(buf.String(), expect) {
+		t.Errorf("expect summary to contain %q, but got %q", expect, buf.String())
+	}
+}
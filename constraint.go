@@ -0,0 +1,176 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// constraint is a named parameter constraint.
+type constraint struct {
+	// the regexp of parameter, it is `[^/]+` for validation func.
+	reg string
+
+	// the validation func, nil for regexp constraint.
+	fn func(string) bool
+
+	// the transformer of parameter value, it is nil by default.
+	transform func(string) string
+}
+
+// Constraint registers a named constraint which can be referenced by
+// the named parameters, for example:
+//
+//	r.Constraint("int", `\d+`)
+//	r.Constraint("uuid", regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`))
+//	r.Constraint("even", func(v string) bool {
+//		n, err := strconv.Atoi(v)
+//		return err == nil && n%2 == 0
+//	})
+//	r.Get("/users/<id:uuid>", handler)
+//	r.Get("/posts/<page:int>", handler)
+//
+// The constraint MUST be one of regexp string, *regexp.Regexp and
+// func(string) bool, the validation func is called with the parameter
+// value after the path matched, the route is skipped if it returns
+// false, and the request falls through to the other routes, such as
+// "/posts/<slug>" for "/posts/<page:even>".
+//
+// The constraints are shared by the whole router tree, and MUST be
+// registered before the routes which reference them. They are only
+// supported by Parser, such as NewParser and NewBraceParser.
+//
+// Causes a panic if the name is empty or already exists, or the
+// constraint is invalid.
+func (r *Router) Constraint(name string, c interface{}) {
+	if name == "" {
+		panic(`the constraint name MUST NOT be empty`)
+	}
+
+	root := r.root()
+	if _, ok := root.constraints[name]; ok {
+		panic(fmt.Errorf("the constraint which name equal to %q already exists", name))
+	}
+
+	var cons *constraint
+	switch v := c.(type) {
+	case string:
+		if _, err := regexp.Compile(v); err != nil {
+			panic(err)
+		}
+		cons = &constraint{reg: v}
+	case *regexp.Regexp:
+		cons = &constraint{reg: v.String()}
+	case func(string) bool:
+		cons = &constraint{reg: `[^/]+`, fn: v}
+	default:
+		panic(fmt.Errorf("the constraint %q MUST be one of regexp string, *regexp.Regexp and func(string) bool, but got %T", name, c))
+	}
+
+	if root.constraints == nil {
+		root.constraints = make(map[string]*constraint)
+	}
+	root.constraints[name] = cons
+}
+
+// Transformer registers a transformer for the named constraint, the
+// parameter values which reference the constraint are transformed
+// after validation, before they reach middleware and handlers, so that
+// the normalization can be kept out of handlers, for example:
+//
+//	r.Constraint("slug", `[\w-]+`)
+//	r.Transformer("slug", strings.ToLower)
+//	r.Get("/posts/<slug:slug>", handler)
+//
+// The transformers MUST be registered before the routes which
+// reference the constraint.
+//
+// Causes a panic if the constraint does not exist, or the transformer
+// of constraint already exists.
+func (r *Router) Transformer(name string, fn func(string) string) {
+	c, ok := r.root().constraints[name]
+	if !ok {
+		panic(fmt.Errorf("the constraint which name equal to %q does not exist", name))
+	}
+	if c.transform != nil {
+		panic(fmt.Errorf("the transformer of constraint %q already exists", name))
+	}
+
+	c.transform = fn
+}
+
+// transform transforms the parameter values in place with the
+// transformers of group prefixes and route.
+func (route *Route) transform(values []string) {
+	for i, fn := range route.finalTransformers {
+		if fn != nil {
+			values[i] = fn(values[i])
+		}
+	}
+}
+
+// validate reports whether the parameter values satisfy the
+// validation funcs of route.
+func (route *Route) validate(values []string) bool {
+	for i, fn := range route.validators {
+		if fn != nil && !fn(values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validatingMatcher validates the parameter values of the matched route
+// with the validation funcs, the request falls through to the other
+// routes if the validation failed.
+type validatingMatcher struct {
+	matcher
+
+	routes []*Route
+
+	newMatcher func([]*Route) matcher
+
+	// mapping from the rejected route to the matcher of the other
+	// routes, it is built on demand.
+	rest sync.Map
+}
+
+// newValidatingMatcher returns the matcher of routes which is built via
+// the given func, it is wrapped by validatingMatcher if any route has
+// validation funcs.
+func newValidatingMatcher(routes []*Route, newMatcher func([]*Route) matcher) matcher {
+	m := newMatcher(routes)
+	for _, route := range routes {
+		if route.validators != nil {
+			return &validatingMatcher{matcher: m, routes: routes, newMatcher: newMatcher}
+		}
+	}
+
+	return m
+}
+
+func (m *validatingMatcher) match(path string, values []string) (*Route, []string) {
+	n := len(values)
+	route, vs := m.matcher.match(path, values)
+	if route == nil || route.validate(vs[n:]) {
+		return route, vs
+	}
+
+	rest, ok := m.rest.Load(route)
+	if !ok {
+		routes := make([]*Route, 0, len(m.routes)-1)
+		for _, v := range m.routes {
+			if v != route {
+				routes = append(routes, v)
+			}
+		}
+		rest, _ = m.rest.LoadOrStore(route, newValidatingMatcher(routes, m.newMatcher))
+	}
+
+	return rest.(matcher).match(path, values[:n])
+}
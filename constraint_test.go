@@ -0,0 +1,201 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func isEven(v string) bool {
+	n, err := strconv.Atoi(v)
+	return err == nil && n%2 == 0
+}
+
+func TestRouter_Constraint(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Constraint("int", `\d+`)
+		r.Constraint("uuid", regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`))
+		r.Constraint("even", isEven)
+		r.Get("/users/<id:uuid>", emptyHandler)
+		r.Get("/posts/<page:int>", emptyHandler)
+		r.Get("/numbers/<n:even>", emptyHandler)
+		r.Get("/numbers/<n>/odd", emptyHandler)
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			code int
+		}{
+			{"/users/0f8fad5b-d9cb-469f-a165-70867728950e", http.StatusOK},
+			{"/users/foo", http.StatusNotFound},
+			{"/posts/2", http.StatusOK},
+			{"/posts/two", http.StatusNotFound},
+			{"/numbers/4", http.StatusOK},
+			{"/numbers/3", http.StatusNotFound},
+			{"/numbers/3/odd", http.StatusOK},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("engine %d: expect status code of %q to be %d, but got %d", engine, test.path, test.code, w.Code)
+			}
+		}
+	}
+}
+
+func TestRouter_ConstraintFallThrough(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.CaseInsensitive = true
+		r.Constraint("even", isEven)
+		r.Constraint("short", func(v string) bool {
+			return len(v) <= 2
+		})
+		r.Get("/posts/<n:even>", helloHandler("even"))
+		r.Get("/posts/<n:short>", helloHandler("short"))
+		r.Get("/posts/<slug>", helloHandler("slug"))
+		r.Prepare()
+
+		tests := map[string]string{
+			"/posts/4":   "even",
+			"/posts/3":   "short",
+			"/posts/333": "slug",
+			"/posts/foo": "slug",
+			"/POSTS/3":   "short",
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("engine %d: expect body of %q to be %q, but got %q", engine, path, body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRouter_Constraint2(t *testing.T) {
+	r := New()
+	r.Constraint("int", `\d+`)
+	r.Constraint("even", isEven)
+	r.Get("/posts/<page:int>", emptyHandler).Name("posts")
+	r.Get("/numbers/<n:even>", emptyHandler).Name("numbers")
+
+	if url, err := r.URL("posts", "page", "2"); err != nil || url != "/posts/2" {
+		t.Errorf("expect url to be %q, but got %q, %v", "/posts/2", url, err)
+	}
+	if _, err := r.URL("posts", "page", "two"); err == nil {
+		t.Error("expect an error for invalid parameter, but got nil")
+	}
+	if url, err := r.URL("numbers", "n", "4"); err != nil || url != "/numbers/4" {
+		t.Errorf("expect url to be %q, but got %q, %v", "/numbers/4", url, err)
+	}
+	if _, err := r.URL("numbers", "n", "3"); err == nil {
+		t.Error("expect an error for invalid parameter, but got nil")
+	}
+}
+
+func TestRouter_Constraint3(t *testing.T) {
+	tests := []struct {
+		name string
+		c    interface{}
+	}{
+		{"", `\d+`},
+		{"int", `\d+`},
+		{"invalid", `(`},
+		{"unsupported", 1},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if rcv := recover(); rcv == nil {
+					t.Errorf("expect a panic for constraint %q, but got nil", test.name)
+				}
+			}()
+			r := New()
+			r.Constraint("int", `\d+`)
+			r.Constraint(test.name, test.c)
+		}()
+	}
+}
+
+func TestRouter_Constraint4(t *testing.T) {
+	// group shares the constraints of root.
+	r := NewWithParser(NewBraceParser())
+	admin := r.Group("admin")
+	admin.Constraint("int", `\d+`)
+	admin.Get("/users/{id:int}", emptyHandler)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/1", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRouter_Transformer(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Constraint("slug", `[\w-]+`)
+		r.Transformer("slug", strings.ToLower)
+		r.Constraint("int", `\d+`)
+		r.Transformer("int", func(v string) string {
+			return strings.TrimLeft(v, "0")
+		})
+		users := r.Group("users/<user:slug>")
+		users.Get("/posts/<id:int>/<name>", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(Param(req, "user") + " " + Param(req, "id") + " " + Param(req, "name")))
+		})
+		r.Group("<lang>").Get("/<id:int>", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(Param(req, "lang") + " " + Param(req, "id")))
+		})
+		r.Prepare()
+
+		tests := map[string]string{
+			"/users/Foo-Bar/posts/007/Baz": "foo-bar 7 Baz",
+			"/EN/0042":                     "EN 42",
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("pooled %t: expect body of %q to be %q, but got %q", pooled, path, body, w.Body.String())
+			}
+		}
+
+		_, params, _ := r.Lookup(http.MethodGet, "/users/FOO/posts/01/x")
+		if params["user"] != "foo" || params["id"] != "1" {
+			t.Errorf("pooled %t: expect transformed params, but got %v", pooled, params)
+		}
+	}
+}
+
+func TestRouter_Transformer2(t *testing.T) {
+	tests := []string{"int", "unknown"}
+	for _, name := range tests {
+		func() {
+			defer func() {
+				if rcv := recover(); rcv == nil {
+					t.Errorf("expect a panic for transformer %q, but got nil", name)
+				}
+			}()
+			r := New()
+			r.Constraint("int", `\d+`)
+			r.Transformer("int", strings.TrimSpace)
+			r.Transformer(name, strings.TrimSpace)
+		}()
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+// contextValue is a static value that will be injected into request
+// context.
+type contextValue struct {
+	key   interface{}
+	value interface{}
+}
+
+// ContextValue attaches a static value to the router, the value will
+// be injected into the request context of the routes which belong to
+// the router and its groups before the middleware and handler, for
+// example:
+//
+//	api := r.Group("api")
+//	api.ContextValue(serviceKey, "api")
+//
+// The key follows the same rules as context.WithValue, the values of
+// group take precedence over its parent's.
+func (r *Router) ContextValue(key, value interface{}) {
+	r.contextValues = append(r.contextValues, contextValue{key, value})
+}
+
+// ContextValue attaches a static value to the route, see
+// Router.ContextValue for details, the values of route take precedence
+// over the router's.
+//
+// Returns the route itself for chaining.
+func (route *Route) ContextValue(key, value interface{}) *Route {
+	route.contextValues = append(route.contextValues, contextValue{key, value})
+	return route
+}
+
+// collectContextValues returns the context values of router and its
+// parents, in order of precedence from low to high.
+func (r *Router) collectContextValues() []contextValue {
+	if r.parent == nil {
+		return r.contextValues
+	}
+
+	values := r.parent.collectContextValues()
+	if len(r.contextValues) == 0 {
+		return values
+	}
+
+	return append(values[:len(values):len(values)], r.contextValues...)
+}
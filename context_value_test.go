@@ -0,0 +1,55 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type contextValueKey string
+
+func TestRouter_ContextValue(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		fmt.Fprintf(w, "%v,%v,%v,%s", ctx.Value(contextValueKey("service")), ctx.Value(contextValueKey("tenant")), ctx.Value(contextValueKey("route")), Params(req)["id"])
+	}
+
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.ContextValue(contextValueKey("service"), "web")
+		r.ContextValue(contextValueKey("tenant"), "default")
+		r.Get("/", handler)
+
+		api := r.Group("api")
+		api.ContextValue(contextValueKey("service"), "api")
+		api.Get("/users/<id>", handler).ContextValue(contextValueKey("route"), "user")
+
+		v1 := api.Group("v1")
+		v1.ContextValue(contextValueKey("tenant"), "v1")
+		v1.Get("/users/<id>", handler).ContextValue(contextValueKey("tenant"), "route")
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{"/", "web,default,<nil>,"},
+			{"/api/users/1", "api,default,user,1"},
+			{"/api/v1/users/2", "api,route,<nil>,2"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.ServeHTTP(w, req)
+			if w.Body.String() != test.body {
+				t.Errorf("pooled %t: expect body of %q to be %q, but got %q", pooled, test.path, test.body, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the configuration of CORS.
+type CORSConfig struct {
+	// The allowed origins, such as "https://example.com", "*" allows
+	// any origin.
+	AllowOrigins []string
+
+	// The allowed methods, empty means all the methods of the request
+	// path are allowed, the methods which the path does not support are
+	// never allowed.
+	AllowMethods []string
+
+	// The allowed request headers, empty means the headers requested by
+	// preflight are allowed.
+	AllowHeaders []string
+
+	// The response headers which are exposed to client.
+	ExposeHeaders []string
+
+	// How long the results of preflight can be cached, zero means the
+	// Access-Control-Max-Age header is omitted.
+	MaxAge time.Duration
+
+	// Indicates whether to allow the credentials, such as cookies, the
+	// origin is echoed rather than "*" in that case.
+	Credentials bool
+}
+
+// CORSPolicy is a CORS component that handles the preflight requests
+// with the knowledge of allowed methods of the request path, and sets
+// the CORS headers of the actual requests.
+//
+// The allowed origins of route, see Route.AllowOrigins, take precedence
+// over the AllowOrigins of config.
+type CORSPolicy struct {
+	config  CORSConfig
+	any     bool
+	origins map[string]bool
+	methods map[string]bool
+
+	// the root router which the policy is applied to, it is used for
+	// looking up the route of preflight requests.
+	router *Router
+}
+
+// CORS returns a CORS policy with the given config, it is usually
+// installed via Apply:
+//
+//	fastrouter.CORS(fastrouter.CORSConfig{
+//		AllowOrigins: []string{"https://example.com"},
+//		MaxAge:       time.Hour,
+//	}).Apply(r)
+func CORS(config CORSConfig) *CORSPolicy {
+	c := &CORSPolicy{config: config, origins: make(map[string]bool)}
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			c.any = true
+		}
+		c.origins[origin] = true
+	}
+	if len(config.AllowMethods) > 0 {
+		c.methods = make(map[string]bool, len(config.AllowMethods))
+		for _, method := range config.AllowMethods {
+			c.methods[strings.ToUpper(method)] = true
+		}
+	}
+
+	return c
+}
+
+// Apply installs the policy to the given router, that is, sets the
+// OptionsHandler of root router, and appends the Middleware to the
+// router.
+func (c *CORSPolicy) Apply(r *Router) {
+	c.router = r.root()
+	c.router.OptionsHandler = c.OptionsHandler
+	r.Use(c.Middleware)
+}
+
+// Middleware is a Middleware that sets the CORS headers of the actual
+// requests from the allowed origins.
+func (c *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if origin, ok := c.allowOrigin(w, req, matchedRoute(req)); ok {
+			header := w.Header()
+			header.Set("Access-Control-Allow-Origin", origin)
+			if c.config.Credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(c.config.ExposeHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(c.config.ExposeHeaders, ", "))
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// OptionsHandler handles the OPTIONS requests, it can be used as the
+// OptionsHandler of router. The preflight request is responded with
+// 204 No Content, and the CORS headers are only set if the origin and
+// the requested method are allowed.
+func (c *CORSPolicy) OptionsHandler(w http.ResponseWriter, req *http.Request, methods []string) {
+	header := w.Header()
+	header.Set("Allow", strings.Join(methods, ", "))
+
+	requestMethod := req.Header.Get("Access-Control-Request-Method")
+	if requestMethod == "" {
+		return
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	allowed := c.allowMethods(methods)
+	origin, ok := c.allowOrigin(w, req, c.preflightRoute(req, requestMethod))
+	if ok && contains(allowed, requestMethod) {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+		if len(c.config.AllowHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(c.config.AllowHeaders, ", "))
+		} else if headers := req.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			header.Set("Access-Control-Allow-Headers", headers)
+		}
+		if c.config.Credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if c.config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.config.MaxAge/time.Second)))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckOrigin reports whether the origin of request is allowed by the
+// matched route or the policy, the request without origin is allowed.
+// It is intended to be used as the origin check of WebSocket upgrader
+// in the handler, such as the CheckOrigin of gorilla/websocket:
+//
+//	upgrader := websocket.Upgrader{CheckOrigin: policy.CheckOrigin}
+func (c *CORSPolicy) CheckOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	any, allowed := c.origin(matchedRoute(req))
+	return any || allowed[origin]
+}
+
+// AllowOrigins sets the allowed origins of route, they take precedence
+// over the AllowOrigins of CORSPolicy for both the CORS headers and
+// CORSPolicy.CheckOrigin, it is usually used to restrict the sensitive
+// endpoints:
+//
+//	r.Get("/ws", serveWebSocket).AllowOrigins("https://admin.example.com")
+//
+// Returns the route itself for chaining.
+func (route *Route) AllowOrigins(origins ...string) *Route {
+	route.origins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		route.origins[origin] = true
+	}
+	return route
+}
+
+// origin returns the allowed origins of the given route, or the
+// policy if the route is nil or has no allowed origins, any is true if
+// any origin is allowed.
+func (c *CORSPolicy) origin(route *Route) (any bool, allowed map[string]bool) {
+	if route != nil && route.origins != nil {
+		return route.origins["*"], route.origins
+	}
+
+	return c.any, c.origins
+}
+
+// preflightRoute returns the route which matches the requested method
+// and the path of preflight request, nil if the policy is not applied
+// or no route matched.
+func (c *CORSPolicy) preflightRoute(req *http.Request, method string) *Route {
+	if c.router == nil {
+		return nil
+	}
+
+	route, _ := c.router.lookup(method, c.router.requestPath(req))
+	return route
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin, ok is
+// false if the request has no origin or the origin is not allowed, the
+// Vary header is set if the value depends on the origin.
+func (c *CORSPolicy) allowOrigin(w http.ResponseWriter, req *http.Request, route *Route) (string, bool) {
+	any, allowed := c.origin(route)
+	if !any || c.config.Credentials {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return "", false
+	}
+	if any {
+		if c.config.Credentials {
+			return origin, true
+		}
+		return "*", true
+	}
+
+	return origin, allowed[origin]
+}
+
+// allowMethods returns the allowed methods of the request path.
+func (c *CORSPolicy) allowMethods(methods []string) []string {
+	if c.methods == nil {
+		return methods
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if c.methods[method] {
+			allowed = append(allowed, method)
+		}
+	}
+
+	return allowed
+}
+
+// contains reports whether the given value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
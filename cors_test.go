@@ -0,0 +1,158 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	r := New()
+	CORS(CORSConfig{
+		AllowOrigins: []string{"https://example.com"},
+		AllowMethods: []string{"GET", "PUT", "DELETE"},
+		MaxAge:       time.Hour,
+	}).Apply(r)
+	r.Get("/users/<id>", emptyHandler)
+	r.Put("/users/<id>", emptyHandler)
+	r.Post("/users/<id>", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		origin  string
+		method  string
+		allowed string
+	}{
+		{"https://example.com", "PUT", "GET, PUT"},
+		{"https://example.com", "POST", ""},
+		{"https://evil.com", "PUT", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+		req.Header.Set("Origin", test.origin)
+		req.Header.Set("Access-Control-Request-Method", test.method)
+		req.Header.Set("Access-Control-Request-Headers", "X-Token")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expect status code to be %d, but got %d", http.StatusNoContent, w.Code)
+		}
+		if allowed := w.Header().Get("Access-Control-Allow-Methods"); allowed != test.allowed {
+			t.Errorf("expect allowed methods of %s from %q to be %q, but got %q", test.method, test.origin, test.allowed, allowed)
+		}
+		if test.allowed == "" {
+			continue
+		}
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != test.origin {
+			t.Errorf("expect allowed origin to be %q, but got %q", test.origin, origin)
+		}
+		if headers := w.Header().Get("Access-Control-Allow-Headers"); headers != "X-Token" {
+			t.Errorf("expect allowed headers to be %q, but got %q", "X-Token", headers)
+		}
+		if maxAge := w.Header().Get("Access-Control-Max-Age"); maxAge != "3600" {
+			t.Errorf("expect max age to be %q, but got %q", "3600", maxAge)
+		}
+		vary := w.Header().Values("Vary")
+		if len(vary) != 3 {
+			t.Errorf("expect Vary header to contain Origin and request headers, but got %v", vary)
+		}
+	}
+}
+
+func TestCORS_Middleware(t *testing.T) {
+	tests := []struct {
+		config CORSConfig
+		origin string
+		allow  string
+		vary   string
+	}{
+		{CORSConfig{AllowOrigins: []string{"*"}}, "https://example.com", "*", ""},
+		{CORSConfig{AllowOrigins: []string{"*"}, Credentials: true}, "https://example.com", "https://example.com", "Origin"},
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "https://example.com", "https://example.com", "Origin"},
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "https://evil.com", "", "Origin"},
+		{CORSConfig{AllowOrigins: []string{"https://example.com"}}, "", "", "Origin"},
+	}
+	for _, test := range tests {
+		r := New()
+		CORS(test.config).Apply(r)
+		r.Get("/", emptyHandler)
+		r.Prepare()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if allow := w.Header().Get("Access-Control-Allow-Origin"); allow != test.allow {
+			t.Errorf("expect allowed origin of %q to be %q, but got %q", test.origin, test.allow, allow)
+		}
+		if vary := w.Header().Get("Vary"); vary != test.vary {
+			t.Errorf("expect Vary of %q to be %q, but got %q", test.origin, test.vary, vary)
+		}
+	}
+}
+
+func TestRoute_AllowOrigins(t *testing.T) {
+	r := New()
+	policy := CORS(CORSConfig{AllowOrigins: []string{"*"}})
+	policy.Apply(r)
+	var checked bool
+	r.Get("/ws", func(w http.ResponseWriter, req *http.Request) {
+		checked = policy.CheckOrigin(req)
+	}).AllowOrigins("https://admin.example.com")
+	r.Put("/ws", emptyHandler)
+	r.Get("/public", func(w http.ResponseWriter, req *http.Request) {
+		checked = policy.CheckOrigin(req)
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path    string
+		origin  string
+		allowed string
+		checked bool
+	}{
+		{"/ws", "https://admin.example.com", "https://admin.example.com", true},
+		{"/ws", "https://example.com", "", false},
+		{"/ws", "", "", true},
+		{"/public", "https://example.com", "*", true},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("Origin", test.origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != test.allowed {
+			t.Errorf("expect allowed origin of %q from %q to be %q, but got %q", test.path, test.origin, test.allowed, origin)
+		}
+		if checked != test.checked {
+			t.Errorf("expect origin check of %q from %q to be %t, but got %t", test.path, test.origin, test.checked, checked)
+		}
+	}
+
+	preflights := []struct {
+		method  string
+		origin  string
+		allowed string
+	}{
+		{"GET", "https://admin.example.com", "https://admin.example.com"},
+		{"GET", "https://example.com", ""},
+		{"PUT", "https://example.com", "*"},
+	}
+	for _, test := range preflights {
+		req := httptest.NewRequest(http.MethodOptions, "/ws", nil)
+		req.Header.Set("Origin", test.origin)
+		req.Header.Set("Access-Control-Request-Method", test.method)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != test.allowed {
+			t.Errorf("expect allowed origin of preflight %s from %q to be %q, but got %q", test.method, test.origin, test.allowed, origin)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// AddRoute registers a route as same as Handle after Prepare, such as
+// registering the routes of plugins at runtime, it is safe to call
+// concurrently with ServeHTTP, the route table of the request method is
+// rebuilt and swapped atomically, so that the in-flight requests are
+// not affected.
+//
+// The router MUST be the root router, an existing group or an inline
+// router of them, that is, the groups can not be created after
+// Prepare. The route MUST be fully configured by the given arguments,
+// the route options, such as Name and Meta, MUST NOT be set on the
+// returned route.
+//
+// Causes a panic if the method is not a valid token, or the pattern is
+// invalid.
+func (r *Router) AddRoute(method, pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	if r.inline != nil {
+		return r.inline.AddRoute(method, pattern, handler, append(r.Middleware[:len(r.Middleware):len(r.Middleware)], middleware...)...)
+	}
+
+	root := r.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	route := r.Handle(method, pattern, handler, middleware...)
+	if err := route.compile(); err != nil {
+		routes := r.routes[method]
+		r.routes[method] = routes[:len(routes)-1]
+		panic(err)
+	}
+
+	r.prepareRoute(route)
+	r.swapMatcher(method)
+
+	return route
+}
+
+// RemoveRoute removes the routes with the given method and pattern of
+// router after Prepare, it is safe to call concurrently with ServeHTTP,
+// see AddRoute.
+//
+// Reports whether any route was removed.
+func (r *Router) RemoveRoute(method, pattern string) bool {
+	if r.inline != nil {
+		return r.inline.RemoveRoute(method, pattern)
+	}
+
+	root := r.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	routes := make([]*Route, 0, len(r.routes[method]))
+	for _, route := range r.routes[method] {
+		if route.pattern != pattern {
+			routes = append(routes, route)
+			continue
+		}
+		if route.name != "" {
+			delete(root.names, route.name)
+		}
+	}
+	if len(routes) == len(r.routes[method]) {
+		return false
+	}
+
+	if len(routes) == 0 {
+		delete(r.routes, method)
+	} else {
+		r.routes[method] = routes
+	}
+	r.swapMatcher(method)
+
+	return true
+}
+
+// swapMatcher rebuilds the matchers of the given method, and swaps
+// them atomically, it MUST be called with the lock of root held.
+func (r *Router) swapMatcher(method string) {
+	root := r.root()
+	routes := r.routes[method]
+
+	old := r.loadMatchers()
+	matchers := make(map[string]matcher, len(old)+1)
+	for k, m := range old {
+		matchers[k] = m
+	}
+	delete(matchers, method)
+	if len(routes) > 0 {
+		matchers[method] = newMatcher(root.Engine, sortRoutes(routes))
+	}
+	r.matchers.Store(matchers)
+
+	if root.CaseInsensitive {
+		old, _ := r.foldMatchers.Load().(map[string]matcher)
+		foldMatchers := make(map[string]matcher, len(old)+1)
+		for k, m := range old {
+			foldMatchers[k] = m
+		}
+		delete(foldMatchers, method)
+		if len(routes) > 0 {
+			foldMatchers[method] = newFoldMatcher(sortRoutes(routes))
+		}
+		r.foldMatchers.Store(foldMatchers)
+	}
+}
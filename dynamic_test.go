@@ -0,0 +1,136 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRouter_AddRoute(t *testing.T) {
+	r := New()
+	r.CaseInsensitive = true
+	r.Get("/", helloHandler("index"))
+	plugins := r.Group("plugins")
+	r.Prepare()
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				serve(http.MethodGet, "/plugins/foo/1")
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		plugins.AddRoute(http.MethodGet, fmt.Sprintf("/plugin%d/<id>", i), emptyHandler)
+	}
+	plugins.AddRoute(http.MethodGet, "/foo/<id>", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("foo " + Param(req, "id")))
+	}, newHeaderMiddleware("Plugin", "foo"))
+	close(stop)
+	wg.Wait()
+
+	if w := serve(http.MethodGet, "/plugins/foo/1"); w.Body.String() != "foo 1" || w.Header().Get("Plugin") != "foo" {
+		t.Errorf("expect the added route to be served, but got %q %v", w.Body.String(), w.Header())
+	}
+	if w := serve(http.MethodGet, "/PLUGINS/FOO/1"); w.Body.String() != "foo 1" {
+		t.Errorf("expect the added route to be matched case-insensitively, but got %q", w.Body.String())
+	}
+	if w := serve(http.MethodGet, "/"); w.Body.String() != "index" {
+		t.Errorf("expect the existing route to be served, but got %q", w.Body.String())
+	}
+
+	if !plugins.RemoveRoute(http.MethodGet, "/foo/<id>") {
+		t.Error("expect the route to be removed")
+	}
+	if plugins.RemoveRoute(http.MethodGet, "/foo/<id>") {
+		t.Error("expect no route to be removed")
+	}
+	if w := serve(http.MethodGet, "/plugins/foo/1"); w.Code != http.StatusNotFound {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+	if len(r.Routes()) != 11 {
+		t.Errorf("expect 11 routes, but got %d", len(r.Routes()))
+	}
+}
+
+func TestRouter_AddRoute2(t *testing.T) {
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for invalid pattern, but got nil")
+		}
+	}()
+
+	r := New()
+	r.Prepare()
+	r.AddRoute(http.MethodGet, "/posts/<id:[0-9>", emptyHandler)
+}
+
+func TestRouter_AddRouteInline(t *testing.T) {
+	r := New()
+	plugins := r.Group("plugins")
+	plugins.Use(newHeaderMiddleware("Group", "plugins"))
+	r.Prepare()
+
+	plugins.With(newHeaderMiddleware("Inline", "on")).AddRoute(http.MethodGet, "/foo", helloHandler("foo"), newHeaderMiddleware("Route", "foo"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plugins/foo", nil))
+	if w.Body.String() != "foo" {
+		t.Fatalf("expect the added route to be served, but got %q", w.Body.String())
+	}
+	for _, name := range []string{"Group", "Inline", "Route"} {
+		if w.Header().Get(name) == "" {
+			t.Errorf("expect the %s middleware to be applied", name)
+		}
+	}
+
+	if !plugins.With().RemoveRoute(http.MethodGet, "/foo") {
+		t.Error("expect the route to be removed via inline router")
+	}
+}
+
+func TestRouter_RemoveRouteConcurrently(t *testing.T) {
+	r := New()
+	for i := 0; i < 10; i++ {
+		r.Get(fmt.Sprintf("/posts%d", i), emptyHandler).Name(fmt.Sprintf("post%d", i))
+	}
+	r.Prepare()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			r.RemoveRoute(http.MethodGet, fmt.Sprintf("/posts%d", i))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("post%d", i)
+		r.URL(name)
+		r.RequestURL(httptest.NewRequest(http.MethodGet, "/", nil), name)
+		r.SetEnabled(name, false)
+	}
+	wg.Wait()
+
+	if _, err := r.URL("post0"); err == nil {
+		t.Error("expect the name of removed route to be released")
+	}
+}
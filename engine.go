@@ -0,0 +1,271 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matching engines.
+const (
+	// match request path with a combined regular expression per
+	// request method.
+	RegexpEngine = iota
+
+	// match request path with a tree per request method, and falls
+	// back to the combined regular expression for the routes that
+	// can not be represented by tree, such as the routes which
+	// parameters have regular expressions.
+	//
+	// The routes in tree take precedence over the fallback routes,
+	// and the static segments take precedence over the parameters,
+	// among the routes of the same priority, see Route.Priority.
+	TreeEngine
+)
+
+// matcher matches request path against the routes of a request method.
+type matcher interface {
+	// match returns the matched route and its parameter values in
+	// order, returns nil route if nothing matched.
+	//
+	// The parameter values are appended to the given values.
+	match(path string, values []string) (*Route, []string)
+}
+
+func newMatcher(engine int8, routes []*Route) matcher {
+	if engine == TreeEngine {
+		return newValidatingMatcher(routes, newPriorityMatcher)
+	}
+
+	return newValidatingMatcher(routes, func(routes []*Route) matcher {
+		return newRegexpMatcher(routes)
+	})
+}
+
+// priorityMatcher consists of a tree matcher per route priority, in
+// order of priority from high to low, so that the routes which have
+// higher priority take precedence over the routes in tree, see
+// Route.Priority.
+type priorityMatcher []*treeMatcher
+
+// newPriorityMatcher returns a tree matcher if all routes have the
+// same priority, the routes MUST be sorted via sortRoutes.
+func newPriorityMatcher(routes []*Route) matcher {
+	var m priorityMatcher
+	for i := 0; i < len(routes); {
+		j := i + 1
+		for j < len(routes) && routes[j].priority == routes[i].priority {
+			j++
+		}
+		m = append(m, newTreeMatcher(routes[i:j]))
+		i = j
+	}
+	if len(m) == 1 {
+		return m[0]
+	}
+
+	return m
+}
+
+func (m priorityMatcher) match(path string, values []string) (*Route, []string) {
+	for _, tm := range m {
+		if route, vs := tm.match(path, values); route != nil {
+			return route, vs
+		}
+	}
+
+	return nil, nil
+}
+
+// regexpMatcher joins the regular expressions of routes into a
+// combined regular expression.
+type regexpMatcher struct {
+	reg *regexp.Regexp
+
+	// mapping from capture group index to route, nil for
+	// parameter capture groups.
+	routes []*Route
+}
+
+func newRegexpMatcher(routes []*Route) *regexpMatcher {
+	m := &regexpMatcher{routes: []*Route{nil}}
+	regs := []string{}
+	for _, route := range routes {
+		regs = append(regs, "("+route.reg+")")
+		m.routes = append(m.routes, route)
+		for i := 0; i < len(route.params); i++ {
+			m.routes = append(m.routes, nil)
+		}
+	}
+	m.reg = regexp.MustCompile("^(?:" + strings.Join(regs, "|") + ")$")
+
+	return m
+}
+
+func (m *regexpMatcher) match(path string, values []string) (*Route, []string) {
+	matches := m.reg.FindStringSubmatch(path)
+	if matches == nil {
+		return nil, nil
+	}
+
+	var i = 1
+	for ; i < len(matches) && matches[i] == ""; i++ {
+	}
+	if i == len(matches) {
+		return nil, nil
+	}
+
+	route := m.routes[i]
+	return route, append(values, matches[i+1:i+1+len(route.params)]...)
+}
+
+// paramSegment is the regular expression of parameter segment
+// which can be represented by tree.
+const paramSegment = `([^/]+)`
+
+// treeMatcher matches request path segment by segment.
+type treeMatcher struct {
+	root *treeNode
+
+	// the routes that can not be represented by tree.
+	fallback *regexpMatcher
+}
+
+type treeNode struct {
+	// mapping from static segment to child node.
+	static map[string]*treeNode
+
+	// parameter child node.
+	param *treeNode
+
+	// the route which ends at this node.
+	route *Route
+}
+
+func newTreeMatcher(routes []*Route) *treeMatcher {
+	m := &treeMatcher{root: &treeNode{}}
+	fallback := []*Route{}
+	for _, route := range routes {
+		segments, ok := treeSegments(route.reg)
+		if !ok {
+			fallback = append(fallback, route)
+			continue
+		}
+
+		node := m.root
+		for _, segment := range segments {
+			if segment == paramSegment {
+				if node.param == nil {
+					node.param = &treeNode{}
+				}
+				node = node.param
+				continue
+			}
+
+			if node.static == nil {
+				node.static = make(map[string]*treeNode)
+			}
+			child, ok := node.static[segment]
+			if !ok {
+				child = &treeNode{}
+				node.static[segment] = child
+			}
+			node = child
+		}
+
+		// the first registered route wins, as same as RegexpEngine.
+		if node.route == nil {
+			node.route = route
+		}
+	}
+
+	if len(fallback) > 0 {
+		m.fallback = newRegexpMatcher(fallback)
+	}
+
+	return m
+}
+
+// treeSegments splits the regular expression of route into segments,
+// reports whether the route can be represented by tree, that is,
+// each segment is either a static string or a parameter without
+// regular expression.
+func treeSegments(reg string) ([]string, bool) {
+	if !strings.HasSuffix(reg, "/?") {
+		return nil, false
+	}
+
+	reg = reg[:len(reg)-2]
+	if reg == "/" {
+		return nil, true
+	}
+	if reg == "" || reg[0] != '/' {
+		return nil, false
+	}
+
+	// the parameter segment contains '/', replaces it with a
+	// placeholder before splitting.
+	segments := strings.Split(strings.Replace(reg[1:], paramSegment, "\x00", -1), "/")
+	for i, segment := range segments {
+		if segment == "\x00" {
+			segments[i] = paramSegment
+			continue
+		}
+		if segment == "" || strings.Contains(segment, "\x00") || regexp.QuoteMeta(segment) != segment {
+			return nil, false
+		}
+	}
+
+	return segments, true
+}
+
+func (m *treeMatcher) match(path string, values []string) (*Route, []string) {
+	// the trailing slashes is optional.
+	trimmed := path
+	if trimmed == "/" {
+		trimmed = ""
+	} else if strings.HasSuffix(trimmed, "/") {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+
+	if route, vs := m.root.match(trimmed, values); route != nil {
+		return route, vs
+	}
+
+	if m.fallback != nil {
+		return m.fallback.match(path, values)
+	}
+
+	return nil, nil
+}
+
+// match walks the path segment by segment, the path is either empty
+// or begins with '/'.
+func (n *treeNode) match(path string, values []string) (*Route, []string) {
+	if path == "" {
+		return n.route, values
+	}
+
+	path = path[1:]
+	segment, rest := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		segment, rest = path[:i], path[i:]
+	}
+
+	if child, ok := n.static[segment]; ok {
+		if route, vs := child.match(rest, values); route != nil {
+			return route, vs
+		}
+	}
+
+	if n.param != nil && segment != "" {
+		if route, vs := n.param.match(rest, append(values, segment)); route != nil {
+			return route, vs
+		}
+	}
+
+	return nil, nil
+}
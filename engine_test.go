@@ -0,0 +1,149 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTreeSegments(t *testing.T) {
+	tests := []struct {
+		reg      string
+		segments []string
+		ok       bool
+	}{
+		{"//?", nil, true},
+		{"/users/?", []string{"users"}, true},
+		{"/users/([^/]+)/posts/?", []string{"users", "([^/]+)", "posts"}, true},
+		{`/users/(\d+)/?`, nil, false},
+		{"/app.css/?", nil, false},
+		{"/users", nil, false},
+		{"/users//posts/?", nil, false},
+		{"/users-([^/]+)/?", nil, false},
+	}
+	for _, test := range tests {
+		segments, ok := treeSegments(test.reg)
+		if ok != test.ok || !reflect.DeepEqual(segments, test.segments) {
+			t.Errorf("expect the segments of %q to be %v(%v), but got %v(%v)", test.reg, test.segments, test.ok, segments, ok)
+		}
+	}
+}
+
+func TestRouter_Engine(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		var params map[string]string
+		handler := func(msg string) http.HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request) {
+				params = Params(req)
+				w.Write([]byte(msg))
+			}
+		}
+		r.Get("/", handler("home"))
+		r.Get("/users", handler("users"))
+		r.Get("/users/<name>", handler("user"))
+		r.Get("/users/<name>/posts/", handler("user posts"))
+		r.Get(`/posts/<id:\d+>`, handler("post"))
+		r.Get("/files/<filepath:.+>", handler("file"))
+		r.Get("/files/special", handler("special"))
+		r.Group("v1").Get("/users/<name>", handler("v1 user"))
+		r.Prepare()
+
+		tests := []struct {
+			path   string
+			code   int
+			body   string
+			params map[string]string
+		}{
+			{"/", http.StatusOK, "home", nil},
+			{"/users", http.StatusOK, "users", nil},
+			{"/users/", http.StatusOK, "users", nil},
+			{"/users/foo", http.StatusOK, "user", map[string]string{"name": "foo"}},
+			{"/users/foo/posts", http.StatusOK, "user posts", map[string]string{"name": "foo"}},
+			{"/users/foo/posts/", http.StatusOK, "user posts", map[string]string{"name": "foo"}},
+			{"/posts/1", http.StatusOK, "post", map[string]string{"id": "1"}},
+			{"/posts/foo", http.StatusNotFound, "404 page not found\n", nil},
+			{"/files/css/app.css", http.StatusOK, "file", map[string]string{"filepath": "css/app.css"}},
+			{"/v1/users/bar", http.StatusOK, "v1 user", map[string]string{"name": "bar"}},
+			{"/users//posts", http.StatusNotFound, "404 page not found\n", nil},
+		}
+		if engine == TreeEngine {
+			// static routes take precedence over fallback routes.
+			tests = append(tests, struct {
+				path   string
+				code   int
+				body   string
+				params map[string]string
+			}{"/files/special", http.StatusOK, "special", nil})
+		}
+		for _, test := range tests {
+			params = nil
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("engine %d: expect status code of %q to be %d, but got %d", engine, test.path, test.code, w.Code)
+			}
+			if w.Body.String() != test.body {
+				t.Errorf("engine %d: expect response body of %q to be %q, but got %q", engine, test.path, test.body, w.Body.String())
+			}
+			if !reflect.DeepEqual(params, test.params) {
+				t.Errorf("engine %d: expect params of %q to be %v, but got %v", engine, test.path, test.params, params)
+			}
+		}
+	}
+}
+
+// Static segments take precedence over parameters
+func TestRouter_Engine2(t *testing.T) {
+	r := NewWithEngine(TreeEngine)
+	r.Get("/users/<name>", helloHandler("user"))
+	r.Get("/users/new", helloHandler("new user"))
+	r.Get("/users/<name>/edit", helloHandler("edit user"))
+	r.Get("/users/new/<step>", helloHandler("new user step"))
+	r.Prepare()
+
+	tests := map[string]string{
+		"/users/new":      "new user",
+		"/users/foo":      "user",
+		"/users/new/edit": "new user step",
+		"/users/foo/edit": "edit user",
+	}
+	for path, body := range tests {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != body {
+			t.Errorf("expect response body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+}
+
+func benchmarkEngine(b *testing.B, engine int8) {
+	r := NewWithEngine(engine)
+	for i := 0; i < 500; i++ {
+		r.Get(fmt.Sprintf("/resource%d/<id>", i), emptyHandler)
+	}
+	r.Prepare()
+	m := r.loadMatchers()[http.MethodGet]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.match("/resource499/1", nil)
+	}
+}
+
+func BenchmarkRegexpEngine(b *testing.B) {
+	benchmarkEngine(b, RegexpEngine)
+}
+
+func BenchmarkTreeEngine(b *testing.B) {
+	benchmarkEngine(b, TreeEngine)
+}
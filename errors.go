@@ -0,0 +1,132 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The errors of registration, they are returned by the non-panicking
+// registration APIs, such as HandleE and GroupE, and PrepareE, so that
+// the config loaders can branch on the kinds of error via errors.Is:
+//
+//	if _, err := r.HandleE(method, pattern, handler); errors.Is(err, fastrouter.ErrDuplicateRoute) {
+//		// skips the duplicate route.
+//	}
+var (
+	// ErrEmptyPattern is the error of route which pattern is empty.
+	ErrEmptyPattern = errors.New("fastrouter: empty pattern")
+
+	// ErrDuplicateRoute is the error of route which has the same
+	// method and pattern as an existing route of the router.
+	ErrDuplicateRoute = errors.New("fastrouter: duplicate route")
+
+	// ErrInvalidGroupPrefix is the error of group which prefix is
+	// empty, contains empty segment or catch-all parameter, or is taken
+	// by an existing group.
+	ErrInvalidGroupPrefix = errors.New("fastrouter: invalid group prefix")
+
+	// ErrDuplicateName is the error of registering a route with the name
+	// which is taken by another route.
+	ErrDuplicateName = errors.New("fastrouter: duplicate route name")
+
+	// ErrConflict is the error of ambiguous routes, it is wrapped by
+	// ConflictError.
+	ErrConflict = errors.New("fastrouter: route conflict")
+)
+
+// HandleE registers a route as same as Handle, except that it returns
+// a *RouteError instead of panicking, the underlying error is
+// ErrEmptyPattern if the pattern is empty, ErrDuplicateRoute if the
+// router already has a route with the same method and pattern, or the
+// error of parsing.
+func (r *Router) HandleE(method, pattern string, handler http.HandlerFunc, middleware ...Middleware) (*Route, error) {
+	if r.inline != nil {
+		return r.inline.HandleE(method, pattern, handler, append(r.Middleware[:len(r.Middleware):len(r.Middleware)], middleware...)...)
+	}
+
+	var err error
+	switch {
+	case pattern == "":
+		err = ErrEmptyPattern
+	case !isToken(method):
+		err = fmt.Errorf("the method %q is not a valid token", method)
+	case r.hasRoute(method, pattern):
+		err = ErrDuplicateRoute
+	}
+	var route *Route
+	if err == nil {
+		route, err = r.register(method, pattern, handler, middleware)
+	}
+	if err != nil {
+		return nil, &RouteError{Method: method, Pattern: pattern, Prefix: r.fullPrefix(), Err: err}
+	}
+
+	return route, nil
+}
+
+// hasRoute reports whether the router has a route with the given
+// method and pattern.
+func (r *Router) hasRoute(method, pattern string) bool {
+	for _, route := range r.routes[method] {
+		if route.pattern == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GroupE creates a group as same as Group, except that it returns an
+// error which wraps ErrInvalidGroupPrefix instead of panicking.
+func (r *Router) GroupE(prefix string) (*Router, error) {
+	target := r
+	if r.inline != nil {
+		target = r.inline
+	}
+	if err := target.checkGroupPrefix(prefix); err != nil {
+		return nil, err
+	}
+
+	return r.Group(prefix), nil
+}
+
+// checkGroupPrefix checks the group prefix without creating any group,
+// see groupParent and attachGroup.
+func (r *Router) checkGroupPrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: the group prefix MUST NOT be empty", ErrInvalidGroupPrefix)
+	}
+
+	segments := strings.Split(strings.Trim(prefix, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("%w: the group prefix %q MUST NOT contains empty segment", ErrInvalidGroupPrefix, prefix)
+		}
+	}
+
+	router := r
+	for i, segment := range segments {
+		parsed, _, _, err := r.parseE("/" + segment)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidGroupPrefix, err)
+		}
+		if len(parsed.params) > 0 && !strings.HasSuffix(parsed.reg, "/?") {
+			return fmt.Errorf("%w: the group prefix %q MUST NOT contains catch-all parameter", ErrInvalidGroupPrefix, segment)
+		}
+
+		if router != nil {
+			router = router.groups[segment]
+		}
+		if router != nil && i == len(segments)-1 && !router.implicit {
+			return fmt.Errorf("%w: the group which prefix equal to %q already exists", ErrInvalidGroupPrefix, prefix)
+		}
+	}
+
+	return nil
+}
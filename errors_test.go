@@ -0,0 +1,80 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRouter_HandleE(t *testing.T) {
+	r := New()
+	if _, err := r.HandleE(http.MethodGet, "/users", emptyHandler); err != nil {
+		t.Fatalf("expect no error, but got %v", err)
+	}
+
+	tests := []struct {
+		method  string
+		pattern string
+		err     error
+	}{
+		{http.MethodGet, "", ErrEmptyPattern},
+		{http.MethodGet, "/users", ErrDuplicateRoute},
+	}
+	for _, test := range tests {
+		route, err := r.HandleE(test.method, test.pattern, emptyHandler)
+		if route != nil || !errors.Is(err, test.err) {
+			t.Errorf("expect err of %s %q to be %v, but got %v", test.method, test.pattern, test.err, err)
+		}
+		var routeErr *RouteError
+		if !errors.As(err, &routeErr) || routeErr.Pattern != test.pattern {
+			t.Errorf("expect err of %s %q to be a route error, but got %v", test.method, test.pattern, err)
+		}
+	}
+
+	if _, err := r.HandleE("BAD METHOD", "/", emptyHandler); err == nil {
+		t.Error("expect an error for invalid method")
+	}
+	if _, err := r.HandleE(http.MethodGet, "/files/<*filepath>/edit", emptyHandler); err == nil {
+		t.Error("expect an error for invalid pattern")
+	}
+	if _, err := r.HandleE(http.MethodPost, "/users", emptyHandler); err != nil {
+		t.Errorf("expect no error for another method, but got %v", err)
+	}
+	if count := len(r.routes[http.MethodGet]); count != 1 {
+		t.Errorf("expect the failed routes not to be registered, but got %d GET routes", count)
+	}
+}
+
+func TestRouter_GroupE(t *testing.T) {
+	r := New()
+	if _, err := r.GroupE("api/v1"); err != nil {
+		t.Fatalf("expect no error, but got %v", err)
+	}
+
+	for _, prefix := range []string{"", "api//v2", "api/v1", "files/<*filepath>"} {
+		group, err := r.GroupE(prefix)
+		if group != nil || !errors.Is(err, ErrInvalidGroupPrefix) {
+			t.Errorf("expect err of prefix %q to be %v, but got %v", prefix, ErrInvalidGroupPrefix, err)
+		}
+	}
+	if _, ok := r.groups["files"]; ok {
+		t.Error("expect no group to be created for invalid prefix")
+	}
+	if _, err := r.GroupE("api/v2"); err != nil {
+		t.Errorf("expect no error, but got %v", err)
+	}
+}
+
+func TestConflictError_Is(t *testing.T) {
+	r := New()
+	r.FatalConflicts = true
+	r.Get("/users/new", emptyHandler)
+	r.Get("/users/<id>", emptyHandler)
+	if err := r.PrepareE(); !errors.Is(err, ErrConflict) {
+		t.Errorf("expect err to be %v, but got %v", ErrConflict, err)
+	}
+}
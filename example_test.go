@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/razonyang/fastrouter"
+	"github.com/razonyang/fastrouter/middleware"
 )
 
 func Example() {
@@ -86,35 +87,9 @@ func Example() {
 
 func ExampleMiddleware() {
 	// basic auth middleware
-	basicAuthMiddleware := func(next http.Handler) http.Handler {
-		username := "foo"
-		password := "bar"
-
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if name, passwd, ok := r.BasicAuth(); ok && name == username && passwd == password {
-				// authorized, continue processing
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			w.WriteHeader(http.StatusUnauthorized)
-		})
-	}
-
-	// body limit middleware
-	bodyLimitMiddleware := func(next http.Handler) http.Handler {
-		var limit int64 = 1024
-
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.ContentLength > limit {
-				w.WriteHeader(http.StatusRequestEntityTooLarge)
-				return
-			}
-
-			// continue processing
-			next.ServeHTTP(w, r)
-		})
-	}
+	basicAuthMiddleware := middleware.BasicAuth("posts", middleware.BasicAuthUsers(map[string]string{
+		"foo": "bar",
+	}))
 
 	r := fastrouter.New()
 
@@ -130,7 +105,7 @@ func ExampleMiddleware() {
 	upload := func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Uploaded"))
 	}
-	r.Post(`/upload`, upload, bodyLimitMiddleware)
+	r.Post(`/upload`, upload, middleware.BodyLimit(1024))
 
 	// Make preparations before handling incoming request.
 	// Note that, this method MUST be invoked before handling incoming request,
@@ -182,6 +157,9 @@ func ExampleRouter_ServeFiles() {
 	// cab be matched.
 	r.ServeFiles("/js/<filepath:.+>", "/path-to-js/")
 
+	// the catch-all parameter is equivalent.
+	r.ServeFiles("/images/<*filepath>", "/path-to-images/")
+
 	// Make preparations before handling incoming request.
 	// Note that, this method MUST be invoked before handling incoming request,
 	// otherwise the router can not works as expected.
@@ -0,0 +1,51 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FallbackParam is the name of parameter which holds the remaining path
+// of the request handled by the fallback of group, see Router.Fallback.
+const FallbackParam = "path"
+
+// Fallback registers a group-level catch-all handler which handles the
+// requests of any method to the unmatched paths under the group, that
+// is, no route of group matches the path with any method. Unlike the
+// NotFoundHandler, the fallback runs through the middleware of group,
+// and receives the remaining path relative to the group without the
+// leading slash as the FallbackParam:
+//
+//	app := r.Group("app")
+//	app.Get("/assets/<*file>", serveAsset)
+//	app.Fallback(func(w http.ResponseWriter, req *http.Request) {
+//		serveIndex(w, req, fastrouter.Param(req, fastrouter.FallbackParam))
+//	})
+//
+// The groups of the group are not covered, they have their own
+// fallbacks.
+//
+// Returns the fallback route for chaining, such as Meta.
+func (r *Router) Fallback(handler http.HandlerFunc, middleware ...Middleware) *Route {
+	r.fallback = &Route{
+		router:     r,
+		method:     "*",
+		pattern:    "/*",
+		params:     []string{FallbackParam},
+		middleware: middleware,
+		handler:    handler,
+	}
+	return r.fallback
+}
+
+// serveFallback handles the unmatched request via the fallback of
+// router, the path is relative to the router.
+func (r *Router) serveFallback(w http.ResponseWriter, req *http.Request, router *Router, path string, prefixValues []string) {
+	values := make([]string, len(prefixValues), len(prefixValues)+1)
+	copy(values, prefixValues)
+	r.handle(w, req, router.fallback, append(values, strings.TrimPrefix(path, "/")), nil)
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_Fallback(t *testing.T) {
+	r := New()
+	r.Get("/", helloHandler("home"))
+	app := r.Group("apps/<app>")
+	app.Middleware = append(app.Middleware, newHeaderMiddleware("X-App", "1"))
+	app.Get("/assets/<*file>", helloHandler("asset"))
+	app.Post("/login", helloHandler("login"))
+	app.Fallback(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("fallback " + Param(req, "app") + " " + Param(req, FallbackParam)))
+	})
+	r.Prepare()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/apps/foo/assets/app.js", http.StatusOK, "asset"},
+		{http.MethodGet, "/apps/foo/dashboard/1", http.StatusOK, "fallback foo dashboard/1"},
+		{http.MethodPut, "/apps/foo/dashboard", http.StatusOK, "fallback foo dashboard"},
+		{http.MethodGet, "/apps/foo", http.StatusOK, "fallback foo "},
+		{http.MethodGet, "/apps/foo/login", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/unknown", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %q to be %d, but got %d", test.method, test.path, test.code, w.Code)
+			continue
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("expect body of %s %q to be %q, but got %q", test.method, test.path, test.body, w.Body.String())
+		}
+		if test.code == http.StatusOK && w.Header().Get("X-App") != "1" {
+			t.Errorf("expect middleware of group to be applied to %s %q", test.method, test.path)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fastroutertest provides utilities for testing and
+// benchmarking the routers, it is kept apart from package fastrouter,
+// so that the binaries which import the router do not pull in the
+// testing package.
+package fastroutertest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// BenchCase is a request to be benchmarked.
+type BenchCase struct {
+	Method string
+	Path   string
+}
+
+// BenchResult is the benchmark result of a BenchCase.
+type BenchResult struct {
+	BenchCase
+
+	// The pattern of the matched route, empty if nothing matched.
+	Pattern string
+
+	// The number of iterations.
+	N int
+
+	// The nanoseconds per request.
+	NsPerOp int64
+
+	// The allocations per request.
+	AllocsPerOp int64
+
+	// The allocated bytes per request.
+	AllocedBytesPerOp int64
+}
+
+// Bench benchmarks the given requests against the router via
+// testing.Benchmark, and returns the per-request latency and
+// allocation figures in order of cases, it allows to compare the
+// parser and matcher configurations on the user's own route sets.
+//
+// The requests are served by ServeHTTP, including the middleware and
+// handlers, the router MUST makes preparations before benchmarking.
+func Bench(r *fastrouter.Router, cases []BenchCase) []BenchResult {
+	results := make([]BenchResult, len(cases))
+	for i, c := range cases {
+		results[i].BenchCase = c
+		if route := r.LookupRoute(c.Method, c.Path); route != nil {
+			results[i].Pattern = route.Pattern()
+		}
+
+		orig, err := http.NewRequest(c.Method, c.Path, nil)
+		if err != nil {
+			continue
+		}
+		w := &discardResponseWriter{header: make(http.Header)}
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			// each iteration is served with a copy of the original
+			// request, so that the changes made by the previous
+			// iteration, such as rewriting path, do not leak into the
+			// next one. The copies are reused to keep the allocations
+			// of benchmark itself out of the figures.
+			req, u := new(http.Request), new(url.URL)
+			for j := 0; j < b.N; j++ {
+				*req, *u = *orig, *orig.URL
+				req.URL = u
+				r.ServeHTTP(w, req)
+			}
+		})
+
+		results[i].N = result.N
+		results[i].NsPerOp = result.NsPerOp()
+		results[i].AllocsPerOp = result.AllocsPerOp()
+		results[i].AllocedBytesPerOp = result.AllocedBytesPerOp()
+	}
+
+	return results
+}
+
+// discardResponseWriter is a http.ResponseWriter that discards
+// everything.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(code int) {}
@@ -0,0 +1,46 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastroutertest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestBench(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmark in short mode")
+	}
+
+	r := fastrouter.New()
+	r.Get("/users/<id>", func(w http.ResponseWriter, req *http.Request) {})
+	r.Prepare()
+
+	cases := []BenchCase{
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/not-found"},
+	}
+	results := Bench(r, cases)
+	if len(results) != len(cases) {
+		t.Fatalf("expect %d results, but got %d", len(cases), len(results))
+	}
+
+	if results[0].Pattern != "/users/<id>" {
+		t.Errorf("expect pattern to be %q, but got %q", "/users/<id>", results[0].Pattern)
+	}
+	if results[1].Pattern != "" {
+		t.Errorf("expect empty pattern, but got %q", results[1].Pattern)
+	}
+	for _, result := range results {
+		if result.BenchCase.Method != http.MethodGet || result.N == 0 || result.NsPerOp <= 0 {
+			t.Errorf("expect a valid result, but got %+v", result)
+		}
+	}
+	if results[0].AllocsPerOp == 0 {
+		t.Errorf("expect allocations for parameters, but got %+v", results[0])
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileOffloader is a hook for offloading the file download to the front
+// proxy, such as nginx and Apache, it reports whether the file is
+// offloaded, the response is left to the hook in that case. The name is
+// the cleaned slash-separated path of file which is relative to the
+// root of FileServer.
+type FileOffloader func(w http.ResponseWriter, req *http.Request, name string, stat os.FileInfo) bool
+
+// FileServer serves the static files on top of http.ServeContent, so
+// that the range requests, including the multipart ranges and the
+// If-Range of download resuming, and the conditional requests are
+// supported.
+//
+// The file is passed to http.ServeContent as it is, so that the body is
+// sent via sendfile if the file is an *os.File, such as the ones opened
+// by http.Dir, and the middleware does not hide the io.ReaderFrom of
+// http.ResponseWriter.
+type FileServer struct {
+	// The root directory of files.
+	Root http.FileSystem
+
+	// The index file of directories, defaults to "index.html", the
+	// directory which has no index file is not served, that is, there
+	// is no directory listing. Unlike http.FileServer, the directory
+	// path without trailing slash is not redirected, the index file is
+	// served as it is, so that the relative links of index file SHOULD
+	// be avoided, or the trailing slashes be enforced via the
+	// TrailingSlashesPolicy.
+	Index string
+
+	// The hook for offloading the file downloads, see XAccelRedirect
+	// and XSendfile, nil means the files are served by the router.
+	Offloader FileOffloader
+}
+
+// serveHTTP serves the file of the "filepath" parameter.
+func (s *FileServer) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	name := path.Clean("/" + Param(req, "filepath"))
+	file, stat, err := openFile(s.Root, name)
+	if err != nil {
+		fileError(w, req, err)
+		return
+	}
+	defer file.Close()
+
+	if stat.IsDir() {
+		index := s.Index
+		if index == "" {
+			index = "index.html"
+		}
+		name = path.Join(name, index)
+		indexFile, indexStat, err := openFile(s.Root, name)
+		if err != nil {
+			fileError(w, req, err)
+			return
+		}
+		defer indexFile.Close()
+		if indexStat.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+		file, stat = indexFile, indexStat
+	}
+
+	if s.Offloader != nil && s.Offloader(w, req, name, stat) {
+		return
+	}
+
+	serveFile(w, req, stat.Name(), file, stat)
+}
+
+// openFile opens the file of the given name and returns its stat, the
+// file is closed if it can not be stated.
+func openFile(fs http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	file, err := fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, stat, nil
+}
+
+// serveFile replies to the request with the content of file via
+// http.ServeContent, the name is used for detecting the content type
+// if the Content-Type header is not set.
+//
+// The validator of file which is derived from the modification time
+// and size, it is sent as a strong one, as nginx and Apache do, so
+// that the interrupted downloads can be resumed via If-Range. Note
+// that it is not a true strong validator, the modifications which keep
+// the size within the granularity of the modification time of file
+// system keep it, the same applies to the Last-Modified.
+func serveFile(w http.ResponseWriter, req *http.Request, name string, file http.File, stat os.FileInfo) {
+	header := w.Header()
+	if header.Get("ETag") == "" {
+		header.Set("ETag", `"`+strconv.FormatInt(stat.ModTime().UnixNano(), 16)+"-"+strconv.FormatInt(stat.Size(), 16)+`"`)
+	}
+	http.ServeContent(w, req, name, stat.ModTime(), file)
+}
+
+// fileError replies to the request with the status code of the error
+// of opening file.
+func fileError(w http.ResponseWriter, req *http.Request, err error) {
+	switch {
+	case os.IsNotExist(err):
+		http.NotFound(w, req)
+	case os.IsPermission(err):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// XAccelRedirect returns a FileOffloader which offloads the files which
+// size reaches the minSize to nginx via the X-Accel-Redirect header, the
+// redirect URI is the name prefixed with the prefix, which is usually
+// an internal location:
+//
+//	location /protected/ {
+//	    internal;
+//	    alias /var/www/files/;
+//	}
+//
+//	r.ServeFileServer("/files/<*filepath>", &fastrouter.FileServer{
+//		Root:      http.Dir("/var/www/files"),
+//		Offloader: fastrouter.XAccelRedirect("/protected", 1<<20),
+//	})
+func XAccelRedirect(prefix string, minSize int64) FileOffloader {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(w http.ResponseWriter, req *http.Request, name string, stat os.FileInfo) bool {
+		if stat.Size() < minSize {
+			return false
+		}
+
+		u := url.URL{Path: prefix + name}
+		setOffloadHeader(w, "X-Accel-Redirect", u.EscapedPath(), name)
+		return true
+	}
+}
+
+// XSendfile returns a FileOffloader which offloads the files which size
+// reaches the minSize to Apache or lighttpd via the X-Sendfile header,
+// the header value is the absolute path of file, the root is the
+// directory of files on the proxy host.
+func XSendfile(root string, minSize int64) FileOffloader {
+	return func(w http.ResponseWriter, req *http.Request, name string, stat os.FileInfo) bool {
+		if stat.Size() < minSize {
+			return false
+		}
+
+		setOffloadHeader(w, "X-Sendfile", filepath.Join(root, filepath.FromSlash(name)), name)
+		return true
+	}
+}
+
+// setOffloadHeader sets the offloading header and the content type of
+// file, the body is left to the proxy.
+func setOffloadHeader(w http.ResponseWriter, key, value, name string) {
+	header := w.Header()
+	header.Set(key, value)
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeFiles serve static resources.
+//
+// The pattern MUST contains parameter placeholder named "filepath",
+// it is related to pattern parser.
+//
+// The root is the absolute or relative path of the static resources,
+// they are served via http.FileServer, so that the directories are
+// listed, and the directory paths without trailing slash are
+// redirected. Use ServeFileServer for serving the index files without
+// listing, and for offloading the downloads.
+func (r *Router) ServeFiles(pattern, root string, middleware ...Middleware) *Route {
+	if !strings.Contains(pattern, "filepath") {
+		panic(`the pattern MUST contains parameter placeholder named "filepath"`)
+	}
+
+	fs := http.FileServer(http.Dir(root))
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		// serves a copy of request, so that the path of the original
+		// request stays intact for the middleware.
+		u := *req.URL
+		u.Path, u.RawPath = Param(req, "filepath"), ""
+		fileReq := new(http.Request)
+		*fileReq = *req
+		fileReq.URL = &u
+		fs.ServeHTTP(w, fileReq)
+	}
+
+	return r.Handle(http.MethodGet, pattern, handler, middleware...)
+}
+
+// ServeFileServer serves the static resources via the given FileServer.
+//
+// The pattern MUST contains parameter placeholder named "filepath",
+// it is related to pattern parser.
+func (r *Router) ServeFileServer(pattern string, server *FileServer, middleware ...Middleware) *Route {
+	if !strings.Contains(pattern, "filepath") {
+		panic(`the pattern MUST contains parameter placeholder named "filepath"`)
+	}
+
+	return r.Handle(http.MethodGet, pattern, server.serveHTTP, middleware...)
+}
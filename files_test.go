@@ -0,0 +1,206 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const filesContent = "abcdefghijklmnopqrstuvwxyz"
+
+func newFilesDir(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(filesContent), 0666); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0777); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("index"), 0666); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0777); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	return dir
+}
+
+func TestRouter_ServeFileServer_Range(t *testing.T) {
+	dir := newFilesDir(t)
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.ServeFileServer("/files/<*filepath>", &FileServer{Root: http.Dir(dir)})
+		r.Prepare()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/file.txt", nil))
+		etag := w.Header().Get("ETag")
+		lastModified := w.Header().Get("Last-Modified")
+		if w.Code != http.StatusOK || w.Body.String() != filesContent {
+			t.Fatalf("pooled %t: expect the file to be served, but got %d and %q", pooled, w.Code, w.Body.String())
+		}
+		if !strings.HasPrefix(etag, `"`) || lastModified == "" || w.Header().Get("Accept-Ranges") != "bytes" {
+			t.Fatalf("pooled %t: expect strong ETag, Last-Modified and Accept-Ranges, but got %v", pooled, w.Header())
+		}
+
+		tests := []struct {
+			path         string
+			header       map[string]string
+			code         int
+			contentRange string
+			body         string
+		}{
+			{"/files/file.txt", map[string]string{"Range": "bytes=0-4"}, http.StatusPartialContent, "bytes 0-4/26", "abcde"},
+			{"/files/file.txt", map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "bytes 23-25/26", "xyz"},
+			{"/files/file.txt", map[string]string{"Range": "bytes=20-", "If-Range": etag}, http.StatusPartialContent, "bytes 20-25/26", "uvwxyz"},
+			{"/files/file.txt", map[string]string{"Range": "bytes=20-", "If-Range": lastModified}, http.StatusPartialContent, "bytes 20-25/26", "uvwxyz"},
+			{"/files/file.txt", map[string]string{"Range": "bytes=20-", "If-Range": `"stale"`}, http.StatusOK, "", filesContent},
+			{"/files/file.txt", map[string]string{"Range": "bytes=100-"}, http.StatusRequestedRangeNotSatisfiable, "bytes */26", ""},
+			{"/files/file.txt", map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", ""},
+			{"/files/docs", nil, http.StatusOK, "", "index"},
+			{"/files/empty", nil, http.StatusNotFound, "", ""},
+			{"/files/missing.txt", nil, http.StatusNotFound, "", ""},
+			{"/files/../file.txt", nil, http.StatusOK, "", filesContent},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.URL.Path = test.path
+			for name, value := range test.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("pooled %t: expect status code of %s %v to be %d, but got %d", pooled, test.path, test.header, test.code, w.Code)
+			}
+			if contentRange := w.Header().Get("Content-Range"); contentRange != test.contentRange {
+				t.Errorf("pooled %t: expect Content-Range of %s %v to be %q, but got %q", pooled, test.path, test.header, test.contentRange, contentRange)
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("pooled %t: expect body of %s %v to be %q, but got %q", pooled, test.path, test.header, test.body, w.Body.String())
+			}
+		}
+
+		// multipart ranges.
+		req := httptest.NewRequest(http.MethodGet, "/files/file.txt", nil)
+		req.Header.Set("Range", "bytes=0-1,4-5")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusPartialContent || !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") {
+			t.Errorf("pooled %t: expect multipart ranges, but got %d and %q", pooled, w.Code, w.Header().Get("Content-Type"))
+		}
+		if body := w.Body.String(); !strings.Contains(body, "ab") || !strings.Contains(body, "ef") {
+			t.Errorf("pooled %t: expect body to contain the ranges, but got %q", pooled, body)
+		}
+	}
+}
+
+func TestFileServer_Offloader(t *testing.T) {
+	dir := newFilesDir(t)
+	r := New()
+	r.ServeFileServer("/accel/<*filepath>", &FileServer{Root: http.Dir(dir), Offloader: XAccelRedirect("/protected/", 10)})
+	r.ServeFileServer("/sendfile/<*filepath>", &FileServer{Root: http.Dir(dir), Offloader: XSendfile("/var/www", 10)})
+	r.Prepare()
+
+	tests := []struct {
+		path   string
+		header string
+		value  string
+		body   string
+	}{
+		{"/accel/file.txt", "X-Accel-Redirect", "/protected/file.txt", ""},
+		{"/accel/docs/", "X-Accel-Redirect", "", "index"},
+		{"/sendfile/file.txt", "X-Sendfile", filepath.Join("/var/www", "file.txt"), ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expect status code of %s to be %d, but got %d", test.path, http.StatusOK, w.Code)
+		}
+		if value := w.Header().Get(test.header); value != test.value {
+			t.Errorf("expect %s of %s to be %q, but got %q", test.header, test.path, test.value, value)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %s to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+		if test.value != "" && !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+			t.Errorf("expect Content-Type of %s to be text/plain, but got %q", test.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestFileServer_ModTime(t *testing.T) {
+	dir := newFilesDir(t)
+	r := New()
+	r.ServeFileServer("/files/<*filepath>", &FileServer{Root: http.Dir(dir)})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/file.txt", nil))
+	etag := w.Header().Get("ETag")
+
+	// the modified file invalidates the resuming.
+	modTime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "file.txt"), modTime, modTime); err != nil {
+		t.Fatalf("failed to change the modification time: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/files/file.txt", nil)
+	req.Header.Set("Range", "bytes=20-")
+	req.Header.Set("If-Range", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != filesContent {
+		t.Errorf("expect the modified file to be served entirely, but got %d and %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRouter_ServeFiles_Listing(t *testing.T) {
+	dir := newFilesDir(t)
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.ServeFiles("/files/<*filepath>", dir)
+		r.Prepare()
+
+		tests := []struct {
+			path     string
+			code     int
+			location string
+			body     string
+		}{
+			{"/files/file.txt", http.StatusOK, "", filesContent},
+			{"/files/docs", http.StatusMovedPermanently, "docs/", ""},
+			{"/files/docs/", http.StatusOK, "", "index"},
+			{"/files/", http.StatusOK, "", `<a href="file.txt">`},
+			{"/files/missing.txt", http.StatusNotFound, "", ""},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("pooled %t: expect status code of %s to be %d, but got %d", pooled, test.path, test.code, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != test.location {
+				t.Errorf("pooled %t: expect location of %s to be %q, but got %q", pooled, test.path, test.location, location)
+			}
+			if !strings.Contains(w.Body.String(), test.body) {
+				t.Errorf("pooled %t: expect body of %s to contain %q, but got %q", pooled, test.path, test.body, w.Body.String())
+			}
+			if req.URL.Path != test.path {
+				t.Errorf("pooled %t: expect request path to be intact, but got %q", pooled, req.URL.Path)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import "net/http"
+
+// FlagProvider determines whether the feature flags are enabled, it
+// is consulted per request for the routes marked with a feature flag,
+// so that the rollout tools can gate routes without redeploys.
+type FlagProvider interface {
+	// Enabled reports whether the given feature flag is enabled
+	// for the request.
+	Enabled(req *http.Request, flag string) bool
+}
+
+// FlagProviderFunc is an adapter to allow the use of ordinary
+// functions as FlagProvider.
+type FlagProviderFunc func(req *http.Request, flag string) bool
+
+// Enabled implements FlagProvider's Enabled method.
+func (f FlagProviderFunc) Enabled(req *http.Request, flag string) bool {
+	return f(req, flag)
+}
+
+// Flag marks the route with the given feature flag name.
+//
+// When the feature flag is disabled, the request will be handled by
+// the fallback handler, or the NotFoundHandler if the fallback is nil.
+// The fallback handler is chained with the same middleware as route.
+//
+// Returns the route itself for chaining.
+func (route *Route) Flag(name string, fallback http.Handler) *Route {
+	route.flag = name
+	route.flagFallback = fallback
+	return route
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_Flag(t *testing.T) {
+	flags := map[string]bool{}
+	r := New()
+	r.FlagProvider = FlagProviderFunc(func(req *http.Request, flag string) bool {
+		return flags[flag]
+	})
+	r.Middleware = append(r.Middleware, newHeaderMiddleware("Middleware", "Root"))
+	r.Get("/beta", helloHandler("beta")).Flag("beta", nil)
+	r.Get("/checkout/<id>", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("new checkout " + Params(req)["id"]))
+	}).Flag("new-checkout", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("old checkout " + Params(req)["id"]))
+	}))
+	r.Prepare()
+
+	tests := []struct {
+		flags  map[string]bool
+		path   string
+		code   int
+		body   string
+		header string
+	}{
+		{map[string]bool{}, "/beta", http.StatusNotFound, "404 page not found\n", ""},
+		{map[string]bool{"beta": true}, "/beta", http.StatusOK, "beta", "Root"},
+		{map[string]bool{}, "/checkout/1", http.StatusOK, "old checkout 1", "Root"},
+		{map[string]bool{"new-checkout": true}, "/checkout/1", http.StatusOK, "new checkout 1", "Root"},
+	}
+	for _, test := range tests {
+		flags = test.flags
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q to be %d, but got %d", test.path, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect response body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+		if w.Header().Get("Middleware") != test.header {
+			t.Errorf("expect header Middleware of %q to be %q, but got %q", test.path, test.header, w.Header().Get("Middleware"))
+		}
+	}
+}
+
+// Without FlagProvider
+func TestRoute_Flag2(t *testing.T) {
+	r := New()
+	r.Get("/beta", helloHandler("beta")).Flag("beta", nil)
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/beta", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+}
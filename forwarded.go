@@ -0,0 +1,112 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// parseTrustedProxies parses the given IP addresses and CIDRs.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	return parseNetworks("trusted proxy", proxies)
+}
+
+// parseNetworks parses the given IP addresses and CIDRs, the kind is
+// used for describing the invalid address.
+func parseNetworks(kind string, addrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(addrs))
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s %q", kind, addr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", kind, addr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// isTrustedProxy reports whether the remote address of request is a
+// trusted proxy.
+func (r *Router) isTrustedProxy(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && r.trusts(ip)
+}
+
+// trusts reports whether the given IP is a trusted proxy.
+func (r *Router) trusts(ip net.IP) bool {
+	for _, ipNet := range r.root().trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedPrefix returns the X-Forwarded-Prefix header value without
+// trailing slashes if the request comes from a trusted proxy, returns
+// empty string if the header is missing or invalid.
+func (r *Router) forwardedPrefix(req *http.Request) string {
+	prefix := req.Header.Get("X-Forwarded-Prefix")
+	if prefix == "" || !r.isTrustedProxy(req) {
+		return ""
+	}
+
+	// only accepts absolute path, to prevent redirecting to other hosts.
+	if prefix[0] != '/' || strings.HasPrefix(prefix, "//") || strings.ContainsAny(prefix, "\\?#") {
+		return ""
+	}
+
+	prefix = path.Clean(prefix)
+	if prefix == "/" {
+		return ""
+	}
+
+	return prefix
+}
+
+// RequestURL is similar to URL, except that the X-Forwarded-Prefix of
+// the request is prepended to the path if the request comes from a
+// trusted proxy, see TrustedProxies for details.
+//
+// The localized alias of route is used according to the locale of
+// request, see Route.Alias.
+func (r *Router) RequestURL(req *http.Request, name string, pairs ...string) (string, error) {
+	route, ok := r.namedRoute(name)
+	if !ok {
+		return "", fmt.Errorf("the route which name equal to %q does not exist", name)
+	}
+
+	u, err := route.localize(req).URL(pairs...)
+	if err != nil {
+		return "", err
+	}
+
+	return r.forwardedPrefix(req) + u, nil
+}
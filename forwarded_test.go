@@ -0,0 +1,101 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	nets, err := parseTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("failed to parse trusted proxies: %v", err)
+	}
+	if len(nets) != 3 {
+		t.Errorf("expect 3 networks, but got %v", nets)
+	}
+
+	for _, proxy := range []string{"localhost", "10.0.0.0/33"} {
+		if _, err := parseTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("expect an error for %q, but got nil", proxy)
+		}
+	}
+}
+
+func TestRouter_ForwardedPrefix(t *testing.T) {
+	r := New()
+	r.TrustedProxies = []string{"10.0.0.0/8"}
+	r.Prepare()
+
+	tests := []struct {
+		remoteAddr string
+		prefix     string
+		expect     string
+	}{
+		{"10.0.0.1:1234", "/ingress/", "/ingress"},
+		{"10.0.0.1:1234", "/ingress/../app", "/app"},
+		{"10.0.0.1:1234", "", ""},
+		{"10.0.0.1:1234", "/", ""},
+		{"10.0.0.1:1234", "//evil.com", ""},
+		{"10.0.0.1:1234", "https://evil.com", ""},
+		{"192.168.0.1:1234", "/ingress", ""},
+		{"invalid", "/ingress", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("X-Forwarded-Prefix", test.prefix)
+		if prefix := r.forwardedPrefix(req); prefix != test.expect {
+			t.Errorf("expect the prefix of %q from %q to be %q, but got %q", test.prefix, test.remoteAddr, test.expect, prefix)
+		}
+	}
+}
+
+func TestRouter_RequestURL(t *testing.T) {
+	r := New()
+	r.BasePath = "/myapp"
+	r.TrustedProxies = []string{"10.0.0.1"}
+	r.TrailingSlashesPolicy = RemoveTrailingSlashes
+	r.Get("/users/<name>", emptyHandler).Name("user")
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-Prefix", "/ingress")
+
+	url, err := r.RequestURL(req, "user", "name", "foo")
+	if err != nil {
+		t.Fatalf("failed to reverse URL: %v", err)
+	}
+	if expect := "/ingress/myapp/users/foo"; url != expect {
+		t.Errorf("expect URL to be %q, but got %q", expect, url)
+	}
+	if _, err := r.RequestURL(req, "nonexistent"); err == nil {
+		t.Error("expect an error for nonexistent route, but got nil")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusMovedPermanently, w.Code)
+	}
+	if location := "/ingress/myapp/users/foo"; w.Header().Get("Location") != location {
+		t.Errorf("expect header Location to be %q, but got %q", location, w.Header().Get("Location"))
+	}
+}
+
+func TestRouter_TrustedProxies(t *testing.T) {
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for invalid trusted proxy, but got nil")
+		}
+	}()
+
+	r := New()
+	r.TrustedProxies = []string{"invalid"}
+	r.Prepare()
+}
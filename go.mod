@@ -0,0 +1,3 @@
+module github.com/razonyang/fastrouter
+
+go 1.21
@@ -0,0 +1,43 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// Gone registers a retired endpoint with the given pattern for all
+// standard request methods via Match, the requests are responded with
+// 410 Gone and the given message, the status text is used if the
+// message is empty.
+//
+// The successors are the URLs of the successor endpoints, they are
+// sent as Link headers with rel="successor-version":
+//
+//	r.Gone("/v1/users", "API v1 was retired, please use API v2.", "/v2/users")
+//
+// The retired endpoints are excluded from the allowed methods of
+// Method Not Allowed and OPTIONS responses.
+//
+// Returns the registered routes in order of the standard methods.
+func (r *Router) Gone(pattern, message string, successors ...string) []*Route {
+	if message == "" {
+		message = http.StatusText(http.StatusGone)
+	}
+
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		for _, successor := range successors {
+			w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		http.Error(w, message, http.StatusGone)
+	}
+
+	routes := r.Match(standardMethods, pattern, handler)
+	for _, route := range routes {
+		route.gone = true
+	}
+
+	return routes
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouter_Gone(t *testing.T) {
+	r := New()
+	r.Gone("/v1/users", "API v1 was retired.", "/v2/users", "/v3/users")
+	r.Gone("/posts/<id>", "")
+	r.Get("/posts/<id>/comments", emptyHandler)
+	r.Prepare()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodOptions} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/v1/users", nil))
+		if w.Code != http.StatusGone || w.Body.String() != "API v1 was retired.\n" {
+			t.Errorf("expect %s response to be %d, but got %d %q", method, http.StatusGone, w.Code, w.Body.String())
+		}
+		expect := []string{`</v2/users>; rel="successor-version"`, `</v3/users>; rel="successor-version"`}
+		if links := w.Header()["Link"]; !reflect.DeepEqual(links, expect) {
+			t.Errorf("expect links to be %v, but got %v", expect, links)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+	if w.Code != http.StatusGone || w.Body.String() != "Gone\n" || w.Header().Get("Link") != "" {
+		t.Errorf("expect response to be %d %q, but got %d %q", http.StatusGone, "Gone\n", w.Code, w.Body.String())
+	}
+
+	if methods := r.retrieveMethods("/v1/users"); len(methods) != 0 {
+		t.Errorf("expect no allowed methods for retired endpoint, but got %v", methods)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import "net/http"
+
+// HeaderLimit is the limit of request header, it is enforced after the
+// group was matched, so that the groups can have different limits:
+//
+//	r.HeaderLimit = &fastrouter.HeaderLimit{MaxCount: 100, MaxBytes: 16 << 10}
+//	tenant := r.Group("tenants/<tenant>")
+//	tenant.HeaderLimit = &fastrouter.HeaderLimit{MaxCount: 20, MaxBytes: 4 << 10}
+type HeaderLimit struct {
+	// The maximum number of header fields, each value of the header
+	// with multiple values counts as a field, zero means no limit.
+	MaxCount int
+
+	// The maximum bytes of request header which is the sum of the
+	// length of names and values, zero means no limit.
+	MaxBytes int
+}
+
+// allows reports whether the given header is within the limit.
+func (l *HeaderLimit) allows(header http.Header) bool {
+	if l.MaxCount > 0 && headerCount(header) > l.MaxCount {
+		return false
+	}
+
+	return l.MaxBytes <= 0 || headerBytes(header) <= l.MaxBytes
+}
+
+// headerLimit returns the HeaderLimit of the nearest router, nil if
+// there is no limit.
+func (r *Router) headerLimit() *HeaderLimit {
+	for router := r; router != nil; router = router.parent {
+		if router.HeaderLimit != nil {
+			return router.HeaderLimit
+		}
+	}
+
+	return nil
+}
+
+// allowsHeader reports whether the header of request is within the
+// HeaderLimit of router.
+func (r *Router) allowsHeader(req *http.Request) bool {
+	limit := r.headerLimit()
+	return limit == nil || limit.allows(req.Header)
+}
+
+// headerCount returns the number of header fields.
+func headerCount(header http.Header) int {
+	n := 0
+	for _, values := range header {
+		n += len(values)
+	}
+
+	return n
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRouter_HeaderLimit(t *testing.T) {
+	r := New()
+	r.HeaderLimit = &HeaderLimit{MaxCount: 4, MaxBytes: 128}
+	r.Get("/", emptyHandler)
+	tenant := r.Group("tenant")
+	tenant.HeaderLimit = &HeaderLimit{MaxCount: 2}
+	tenant.Get("/", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path  string
+		count int
+		large bool
+		code  int
+	}{
+		{"/", 4, false, http.StatusOK},
+		{"/", 5, false, http.StatusRequestHeaderFieldsTooLarge},
+		{"/", 1, true, http.StatusRequestHeaderFieldsTooLarge},
+		{"/tenant", 2, false, http.StatusOK},
+		{"/tenant", 3, false, http.StatusRequestHeaderFieldsTooLarge},
+		{"/tenant", 1, true, http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		for i := 0; i < test.count; i++ {
+			req.Header.Add("X-Field", strconv.Itoa(i))
+		}
+		if test.large {
+			req.Header.Set("X-Large", strings.Repeat("x", 128))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q with %d fields to be %d, but got %d", test.path, test.count, test.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ErrResponseTooLarge is returned by the Write of the route which
+// response exceeds the limit, see Route.ResponseLimit.
+var ErrResponseTooLarge = errors.New("fastrouter: response too large")
+
+// ResponseLimit caps the response body of route at n bytes, it protects
+// against accidental huge responses, such as unbounded exports.
+//
+// If the limit is exceeded before any byte of body was written, the
+// response is replaced with 500 Internal Server Error. Otherwise, the
+// response is truncated at the limit. In both cases, the subsequent
+// writes fail with ErrResponseTooLarge, and the violation is logged
+// via the Logger of root router.
+//
+// Returns the route itself for chaining.
+func (route *Route) ResponseLimit(n int64) *Route {
+	route.responseLimit = n
+	return route
+}
+
+// MaxBodySize caps the request body of route at n bytes via
+// http.MaxBytesReader, reading beyond the limit fails with an error,
+// and the connection will be closed after the response was sent.
+//
+// The limit takes effect before any middleware, so that the body
+// consumed by middleware is also limited.
+//
+// Returns the route itself for chaining.
+func (route *Route) MaxBodySize(n int64) *Route {
+	route.maxBodySize = n
+	return route
+}
+
+// RequireContentLength rejects the request which body has no declared
+// length, such as chunked body, with 411 Length Required before the
+// handler reads anything, it is usually used with MaxBodySize and
+// Router.StrictContentLength.
+//
+// Returns the route itself for chaining.
+func (route *Route) RequireContentLength() *Route {
+	route.requireLength = true
+	return route
+}
+
+// limitWriter is a http.ResponseWriter that caps the response body, it
+// defers the status code until the body is written, so that the status
+// code can be replaced if the limit is exceeded by the first write.
+type limitWriter struct {
+	http.ResponseWriter
+
+	route *Route
+	req   *http.Request
+
+	// the remaining bytes.
+	remaining int64
+
+	// the deferred status code, zero if it is not set.
+	code int
+
+	// indicates whether the header was written.
+	wroteHeader bool
+
+	// indicates whether the limit was exceeded.
+	exceeded bool
+}
+
+func newLimitWriter(w http.ResponseWriter, req *http.Request, route *Route) *limitWriter {
+	return &limitWriter{ResponseWriter: w, route: route, req: req, remaining: route.responseLimit}
+}
+
+func (lw *limitWriter) WriteHeader(code int) {
+	if lw.wroteHeader || lw.exceeded || lw.code != 0 {
+		return
+	}
+
+	lw.code = code
+	if length, err := strconv.ParseInt(lw.Header().Get("Content-Length"), 10, 64); err == nil && length > lw.remaining {
+		lw.exceed()
+	}
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+
+	if int64(len(p)) <= lw.remaining {
+		lw.writeHeader()
+		lw.remaining -= int64(len(p))
+		return lw.ResponseWriter.Write(p)
+	}
+
+	if !lw.wroteHeader {
+		lw.exceed()
+		return 0, ErrResponseTooLarge
+	}
+
+	n, err := lw.ResponseWriter.Write(p[:lw.remaining])
+	lw.remaining -= int64(n)
+	lw.exceed()
+	if err == nil {
+		err = ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (lw *limitWriter) Flush() {
+	if lw.exceeded {
+		return
+	}
+
+	lw.writeHeader()
+	if f, ok := lw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeHeader writes the deferred status code.
+func (lw *limitWriter) writeHeader() {
+	if lw.wroteHeader {
+		return
+	}
+	lw.wroteHeader = true
+
+	if lw.code != 0 {
+		lw.ResponseWriter.WriteHeader(lw.code)
+	}
+}
+
+// exceed marks the limit as exceeded and logs the violation, the
+// response is replaced with 500 Internal Server Error if the header
+// has not been written.
+func (lw *limitWriter) exceed() {
+	lw.exceeded = true
+	lw.route.router.logger().Printf("fastrouter: response of %s %q exceeds the limit of %d bytes", lw.req.Method, lw.req.URL.Path, lw.route.responseLimit)
+
+	if !lw.wroteHeader {
+		lw.wroteHeader = true
+		lw.Header().Del("Content-Length")
+		http.Error(lw.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// finish writes the deferred status code if the handler did not write
+// any body.
+func (lw *limitWriter) finish() {
+	if !lw.exceeded {
+		lw.writeHeader()
+	}
+}
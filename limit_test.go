@@ -0,0 +1,135 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoute_ResponseLimit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New()
+	r.Logger = log.New(buf, "", 0)
+	r.Get("/small", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}).ResponseLimit(5)
+	r.Get("/large", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		if _, err := w.Write([]byte("hello world")); err != ErrResponseTooLarge {
+			t.Errorf("expect error to be %v, but got %v", ErrResponseTooLarge, err)
+		}
+	}).ResponseLimit(5)
+	r.Get("/stream", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello"))
+		if n, err := w.Write([]byte(" world")); n != 1 || err != ErrResponseTooLarge {
+			t.Errorf("expect truncated write, but got %d, %v", n, err)
+		}
+	}).ResponseLimit(6)
+	r.Get("/length", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+	}).ResponseLimit(10)
+	r.Get("/empty", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).ResponseLimit(10)
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/small", http.StatusCreated, "hello"},
+		{"/large", http.StatusInternalServerError, "Internal Server Error\n"},
+		{"/stream", http.StatusOK, "hello "},
+		{"/length", http.StatusInternalServerError, "Internal Server Error\n"},
+		{"/empty", http.StatusNoContent, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("expect response of %q to be %d %q, but got %d %q", test.path, test.code, test.body, w.Code, w.Body.String())
+		}
+	}
+
+	if n := strings.Count(buf.String(), "exceeds the limit"); n != 3 {
+		t.Errorf("expect 3 violations to be logged, but got %q", buf.String())
+	}
+}
+
+func TestRouteMaxBodySize(t *testing.T) {
+	r := New()
+	r.Post("/upload", func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.ReadAll(req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte("ok"))
+	}).MaxBodySize(4)
+	r.Prepare()
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{"1234", http.StatusOK},
+		{"12345", http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(test.body)))
+		if w.Code != test.code {
+			t.Errorf("expect status code of body %q to be %d, but got %d", test.body, test.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_StrictContentLength(t *testing.T) {
+	r := New()
+	r.StrictContentLength = true
+	var called bool
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		called = true
+	}
+	r.Post("/upload", handler).MaxBodySize(4).RequireContentLength()
+	r.Post("/stream", handler).MaxBodySize(4)
+	r.Prepare()
+
+	tests := []struct {
+		path    string
+		body    string
+		chunked bool
+		code    int
+	}{
+		{"/upload", "1234", false, http.StatusOK},
+		{"/upload", "12345", false, http.StatusRequestEntityTooLarge},
+		{"/upload", "1234", true, http.StatusLengthRequired},
+		{"/stream", "1234", true, http.StatusOK},
+	}
+	for _, test := range tests {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		if test.chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q with body %q to be %d, but got %d", test.path, test.body, test.code, w.Code)
+		}
+		if expect := test.code == http.StatusOK; called != expect {
+			t.Errorf("expect handler of %q with body %q to be called: %t, but got %t", test.path, test.body, expect, called)
+		}
+	}
+}
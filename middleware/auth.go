@@ -0,0 +1,131 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// BasicAuth returns a middleware which authenticates the requests via
+// HTTP Basic authentication, the credentials are validated by the given
+// validator, the unauthenticated requests are responded with 401
+// Unauthorized and the challenge of the given realm:
+//
+//	admin.Use(middleware.BasicAuth("admin", middleware.BasicAuthUsers(map[string]string{"foo": "bar"})))
+//
+// Causes a panic if the validator is nil.
+func BasicAuth(realm string, validator func(username, password string) bool) fastrouter.Middleware {
+	if validator == nil {
+		panic(`the validator of basic auth MUST NOT be nil`)
+	}
+
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if username, password, ok := req.BasicAuth(); ok && validator(username, password) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// BasicAuthUsers returns a validator which validates the credentials
+// against the given users, keyed by username, the passwords are
+// compared in constant time.
+func BasicAuthUsers(users map[string]string) func(username, password string) bool {
+	hashes := make(map[string][sha256.Size]byte, len(users))
+	for username, password := range users {
+		hashes[username] = sha256.Sum256([]byte(password))
+	}
+
+	return func(username, password string) bool {
+		expected, ok := hashes[username]
+		actual := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare(expected[:], actual[:]) == 1 && ok
+	}
+}
+
+// APIKeyConfig is the configuration of API key authentication.
+type APIKeyConfig struct {
+	// The header which carries the API key, defaults to "X-API-Key" if
+	// both the Header and Query are empty.
+	Header string
+
+	// The query parameter which carries the API key, it is consulted if
+	// the header is absent, empty means the query is ignored.
+	Query string
+
+	// The validator of API key, see APIKeys.
+	Validator func(key string) bool
+
+	// The handler for handling the unauthenticated requests, by default,
+	// they are responded with 401 Unauthorized.
+	ErrorHandler http.Handler
+}
+
+// APIKey returns a middleware which authenticates the requests via the
+// API key which is carried by the header or query parameter:
+//
+//	api.Use(middleware.APIKey(middleware.APIKeyConfig{Validator: middleware.APIKeys(key)}))
+//
+// Causes a panic if the Validator is nil.
+func APIKey(config APIKeyConfig) fastrouter.Middleware {
+	if config.Validator == nil {
+		panic(`the validator of API key MUST NOT be nil`)
+	}
+	if config.Header == "" && config.Query == "" {
+		config.Header = "X-API-Key"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var key string
+			if config.Header != "" {
+				key = req.Header.Get(config.Header)
+			}
+			if key == "" && config.Query != "" {
+				key = req.URL.Query().Get(config.Query)
+			}
+			if key != "" && config.Validator(key) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			config.ErrorHandler.ServeHTTP(w, req)
+		})
+	}
+}
+
+// APIKeys returns a validator which validates the API key against the
+// given keys, the keys are compared in constant time.
+func APIKeys(keys ...string) func(key string) bool {
+	hashes := make([][sha256.Size]byte, len(keys))
+	for i, key := range keys {
+		hashes[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(key string) bool {
+		actual := sha256.Sum256([]byte(key))
+		matched := 0
+		for _, expected := range hashes {
+			matched |= subtle.ConstantTimeCompare(expected[:], actual[:])
+		}
+		return matched == 1
+	}
+}
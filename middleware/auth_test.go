@@ -0,0 +1,106 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := fastrouter.New()
+	admin := r.Group("admin")
+	admin.Use(BasicAuth("admin", BasicAuthUsers(map[string]string{"foo": "bar"})))
+	admin.Get("/", emptyHandler)
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path     string
+		username string
+		password string
+		code     int
+	}{
+		{"/admin", "foo", "bar", http.StatusOK},
+		{"/admin", "foo", "baz", http.StatusUnauthorized},
+		{"/admin", "bar", "bar", http.StatusUnauthorized},
+		{"/admin", "", "", http.StatusUnauthorized},
+		{"/", "", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.username != "" {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q with %q:%q to be %d, but got %d", test.path, test.username, test.password, test.code, w.Code)
+		}
+		if test.code == http.StatusUnauthorized {
+			expect := `Basic realm="admin", charset="UTF-8"`
+			if challenge := w.Header().Get("WWW-Authenticate"); challenge != expect {
+				t.Errorf("expect challenge to be %q, but got %q", expect, challenge)
+			}
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(APIKey(APIKeyConfig{
+		Header:    "X-Token",
+		Query:     "token",
+		Validator: APIKeys("foo", "bar"),
+	}))
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		header string
+		query  string
+		code   int
+	}{
+		{"foo", "", http.StatusOK},
+		{"", "bar", http.StatusOK},
+		{"baz", "", http.StatusUnauthorized},
+		{"baz", "foo", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/?token="+test.query, nil)
+		if test.header != "" {
+			req.Header.Set("X-Token", test.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of header %q and query %q to be %d, but got %d", test.header, test.query, test.code, w.Code)
+		}
+	}
+}
+
+func TestAPIKey_ErrorHandler(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(APIKey(APIKeyConfig{
+		Validator: APIKeys("foo"),
+		ErrorHandler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}),
+	}))
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "bar")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// BodyLimit returns a middleware which limits the request body to n
+// bytes, the exceeded requests are responded with 413 Request Entity
+// Too Large, see BodyLimitHandler.
+//
+//	r.Post("/upload", upload, middleware.BodyLimit(10<<20))
+func BodyLimit(n int64) fastrouter.Middleware {
+	return BodyLimitHandler(n, nil)
+}
+
+// BodyLimitHandler returns a middleware which limits the request body
+// to n bytes, the exceeded requests are handled by the given handler,
+// nil means responding with 413 Request Entity Too Large.
+//
+// The request which declared Content-Length exceeds the limit is
+// rejected without invoking the next handler. Otherwise, such as the
+// chunked request, the body is wrapped with http.MaxBytesReader, and
+// once the handler reads beyond the limit, its response is replaced
+// with the one of the given handler, unless it has been written.
+func BodyLimitHandler(n int64, handler http.Handler) fastrouter.Middleware {
+	if handler == nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			code := http.StatusRequestEntityTooLarge
+			http.Error(w, http.StatusText(code), code)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.ContentLength > n {
+				handler.ServeHTTP(w, req)
+				return
+			}
+			if req.Body == nil || req.Body == http.NoBody {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			lw := &bodyLimitWriter{ResponseWriter: w, req: req, handler: handler}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, req.Body, n), writer: lw}
+			req.Body = body
+			next.ServeHTTP(lw, req)
+			if body.exceeded && !lw.written {
+				lw.reject()
+			}
+		})
+	}
+}
+
+// limitedBody is a request body which records whether the limit was
+// exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	writer   *bodyLimitWriter
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if err != nil && errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+		b.writer.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter is a http.ResponseWriter which replaces the response
+// with the one of limit handler once the limit was exceeded.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	req     *http.Request
+	handler http.Handler
+
+	// indicates whether the body limit was exceeded.
+	exceeded bool
+
+	// indicates whether the response was written.
+	written bool
+
+	// indicates whether the response of handler is discarded.
+	discarded bool
+}
+
+// reject responds via the limit handler, and discards the subsequent
+// writes of handler.
+func (w *bodyLimitWriter) reject() {
+	w.written = true
+	w.discarded = true
+	w.handler.ServeHTTP(w.ResponseWriter, w.req)
+}
+
+// start reports whether the response of handler is allowed to write.
+func (w *bodyLimitWriter) start() bool {
+	if !w.written && w.exceeded {
+		w.reject()
+	}
+	w.written = true
+
+	return !w.discarded
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.start() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *bodyLimitWriter) Write(p []byte) (int, error) {
+	if w.start() {
+		return w.ResponseWriter.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// Flush implements http.Flusher.
+func (w *bodyLimitWriter) Flush() {
+	if !w.start() {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *bodyLimitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.written = true
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("middleware: the ResponseWriter does not implement http.Hijacker")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
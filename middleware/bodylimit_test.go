@@ -0,0 +1,81 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestBodyLimit(t *testing.T) {
+	var called bool
+	r := fastrouter.New()
+	r.Post("/upload", func(w http.ResponseWriter, req *http.Request) {
+		called = true
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}, BodyLimit(8))
+	r.Prepare()
+
+	tests := []struct {
+		body    string
+		chunked bool
+		code    int
+		called  bool
+	}{
+		{"12345678", false, http.StatusOK, true},
+		{"123456789", false, http.StatusRequestEntityTooLarge, false},
+		{"12345678", true, http.StatusOK, true},
+		{"123456789", true, http.StatusRequestEntityTooLarge, true},
+	}
+	for _, test := range tests {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(test.body))
+		if test.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q (chunked: %t) to be %d, but got %d", test.body, test.chunked, test.code, w.Code)
+		}
+		if called != test.called {
+			t.Errorf("expect handler of %q (chunked: %t) to be called: %t, but got %t", test.body, test.chunked, test.called, called)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("expect body to be %q, but got %q", test.body, w.Body.String())
+		}
+	}
+}
+
+func TestBodyLimitHandler(t *testing.T) {
+	r := fastrouter.New()
+	r.Post("/upload", func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.ReadAll(req.Body); err != nil {
+			return
+		}
+	}, BodyLimitHandler(4, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("too large"))
+	})))
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("12345"))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != "too large" {
+		t.Errorf("expect custom response, but got %d %q", w.Code, w.Body.String())
+	}
+}
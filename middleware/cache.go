@@ -0,0 +1,497 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// CachedResponse is a response stored in the Store, its fields are
+// exported so that the stores can serialize it.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// The request header values which the response varies by, keyed by
+	// the canonical header names listed in the Vary header.
+	Vary map[string]string
+
+	// The time when the response was generated.
+	Created time.Time
+
+	// The time until which the response is fresh.
+	Expires time.Time
+
+	// The time until which the stale response can be served while
+	// revalidating, it is equal to the Expires if the
+	// stale-while-revalidate is disabled, the stores MAY evict the
+	// response after it.
+	StaleUntil time.Time
+}
+
+// matches reports whether the response can be served for the request,
+// that is, the request header values which the response varies by are
+// the same.
+func (resp *CachedResponse) matches(req *http.Request) bool {
+	for name, value := range resp.Vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Store is the storage of cached responses, it MUST be safe for
+// concurrent use. The MemoryStore is included, the distributed stores,
+// such as Redis, can be implemented by users.
+type Store interface {
+	// Get returns the response of the given key, nil if not found.
+	Get(ctx context.Context, key string) (*CachedResponse, error)
+
+	// Set stores the response of the given key.
+	Set(ctx context.Context, key string, resp *CachedResponse) error
+
+	// Delete deletes the response of the given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// CacheConfig is the configuration of response cache.
+type CacheConfig struct {
+	// The store of responses, defaults to a MemoryStore with capacity
+	// of 1024.
+	Store Store
+
+	// The time to live of responses, defaults to one minute, the
+	// s-maxage and max-age directives of response take precedence.
+	TTL time.Duration
+
+	// The duration after the TTL in which the stale response is served
+	// while revalidating in the background, zero means disabled, the
+	// stale-while-revalidate directive of response takes precedence.
+	StaleWhileRevalidate time.Duration
+
+	// The func that returns the cache key of request, defaults to the
+	// host and the request URI.
+	KeyFunc func(req *http.Request) string
+
+	// The maximum bytes of cacheable response body, defaults to 1MB.
+	MaxBodySize int
+}
+
+// Cache returns a middleware which caches the successful responses of
+// GET requests, and serves them for the GET and HEAD requests with the
+// same key until expired:
+//
+//	r.Get("/articles", listArticles).Use(middleware.Cache(middleware.CacheConfig{TTL: time.Minute}))
+//
+// It behaves as a shared cache and respects the Cache-Control, that is,
+// the requests with no-store directive or the Authorization header are
+// bypassed, the requests with no-cache or max-age=0 directive skip the
+// lookup, and the responses with no-store, no-cache or private
+// directive, the Set-Cookie header or "Vary: *" are not cached. The
+// Vary header of response is honored, and the cache status is
+// advertised via the X-Cache header, one of HIT, STALE and MISS.
+//
+// The stale response is revalidated by calling the handler with a
+// clone of request which context is detached from cancellation, the
+// revalidation happens in the background unless the request context
+// is pooled, which MUST NOT outlive the handler, in that case, it
+// happens after the stale response is flushed, see
+// fastrouter.PooledContext. The panic of revalidation is recovered, and
+// the stale response is kept.
+func Cache(config CacheConfig) fastrouter.Middleware {
+	return newResponseCache(config).middleware
+}
+
+// responseCache is the response cache.
+type responseCache struct {
+	config CacheConfig
+	now    func() time.Time
+
+	mu sync.Mutex
+	// the keys which are being revalidated.
+	revalidating map[string]bool
+}
+
+func newResponseCache(config CacheConfig) *responseCache {
+	if config.Store == nil {
+		config.Store = NewMemoryStore(1024)
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(req *http.Request) string {
+			return req.Host + req.URL.RequestURI()
+		}
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+
+	return &responseCache{
+		config:       config,
+		now:          time.Now,
+		revalidating: make(map[string]bool),
+	}
+}
+
+func (c *responseCache) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		directives := parseCacheControl(req.Header.Values("Cache-Control"))
+		if _, ok := directives["no-store"]; ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		key := c.config.KeyFunc(req)
+		if _, ok := directives["no-cache"]; !ok && directives["max-age"] != "0" {
+			if resp, err := c.config.Store.Get(req.Context(), key); err == nil && resp != nil && resp.matches(req) {
+				now := c.now()
+				if now.Before(resp.Expires) {
+					c.serve(w, req, resp, "HIT", now)
+					return
+				}
+				if now.Before(resp.StaleUntil) {
+					c.serve(w, req, resp, "STALE", now)
+					c.revalidate(w, req, next, key)
+					return
+				}
+			}
+		}
+
+		// the HEAD response has no body to be cached.
+		if req.Method == http.MethodHead {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		cw := &cacheWriter{ResponseWriter: w, limit: c.config.MaxBodySize}
+		next.ServeHTTP(cw, req)
+		c.store(req, key, cw)
+	})
+}
+
+// serve writes the cached response.
+func (c *responseCache) serve(w http.ResponseWriter, req *http.Request, resp *CachedResponse, status string, now time.Time) {
+	header := w.Header()
+	for name, values := range resp.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set("Age", strconv.Itoa(int(now.Sub(resp.Created)/time.Second)))
+	header.Set("X-Cache", status)
+	w.WriteHeader(resp.StatusCode)
+	if req.Method != http.MethodHead {
+		w.Write(resp.Body)
+	}
+}
+
+// revalidate calls the handler to refresh the stale response of the
+// given key, at most one revalidation is in flight for each key.
+func (c *responseCache) revalidate(w http.ResponseWriter, req *http.Request, next http.Handler, key string) {
+	c.mu.Lock()
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	clone := req.Clone(context.WithoutCancel(req.Context()))
+	clone.Method = http.MethodGet
+	run := func() {
+		defer func() {
+			recover()
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+
+		cw := &cacheWriter{ResponseWriter: &discardWriter{header: make(http.Header)}, limit: c.config.MaxBodySize}
+		next.ServeHTTP(cw, clone)
+		c.store(clone, key, cw)
+	}
+
+	if fastrouter.PooledContext(req) {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		run()
+		return
+	}
+	go run()
+}
+
+// store stores the response which is recorded by the cacheWriter if it
+// is cacheable.
+func (c *responseCache) store(req *http.Request, key string, cw *cacheWriter) {
+	if cw.exceeded || (cw.code != 0 && cw.code != http.StatusOK) {
+		return
+	}
+	header := cw.header
+	if header == nil {
+		header = cw.Header().Clone()
+	}
+	header.Del("X-Cache")
+	if header.Get("Set-Cookie") != "" {
+		return
+	}
+
+	directives := parseCacheControl(header.Values("Cache-Control"))
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return
+		}
+	}
+	ttl := c.config.TTL
+	if age, ok := directives["s-maxage"]; ok {
+		ttl = parseSeconds(age)
+	} else if age, ok := directives["max-age"]; ok {
+		ttl = parseSeconds(age)
+	}
+	if ttl <= 0 {
+		return
+	}
+	stale := c.config.StaleWhileRevalidate
+	if age, ok := directives["stale-while-revalidate"]; ok {
+		stale = parseSeconds(age)
+	}
+
+	var vary map[string]string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return
+			}
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[name] = strings.Join(req.Header.Values(name), ",")
+		}
+	}
+
+	now := c.now()
+	c.config.Store.Set(req.Context(), key, &CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       cw.body,
+		Vary:       vary,
+		Created:    now,
+		Expires:    now.Add(ttl),
+		StaleUntil: now.Add(ttl + stale),
+	})
+}
+
+// parseCacheControl parses the directives of Cache-Control header,
+// the names are lowercased, and the values are unquoted.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+			}
+		}
+	}
+
+	return directives
+}
+
+// parseSeconds parses the delta-seconds of directive, zero if invalid.
+func parseSeconds(value string) time.Duration {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// cacheWriter is a http.ResponseWriter which records the response
+// while writing it.
+type cacheWriter struct {
+	http.ResponseWriter
+
+	limit int
+
+	// the status code, zero if it has not been written.
+	code int
+
+	// the snapshot of header when the status code was written.
+	header http.Header
+
+	body []byte
+
+	// indicates whether the body exceeds the limit.
+	exceeded bool
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if cw.code == 0 {
+		cw.code = code
+		cw.header = cw.Header().Clone()
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.exceeded {
+		if len(cw.body)+len(p) > cw.limit {
+			cw.exceeded = true
+			cw.body = nil
+		} else {
+			cw.body = append(cw.body, p...)
+		}
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (cw *cacheWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// discardWriter is a http.ResponseWriter which discards the response,
+// it is used by the background revalidation.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *discardWriter) WriteHeader(code int) {}
+
+// MemoryStore is an in-memory Store which evicts the least recently
+// used responses if it is full.
+type MemoryStore struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries *list.List
+	items   map[string]*list.Element
+}
+
+// memoryEntry is the entry of MemoryStore.
+type memoryEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryStore returns a MemoryStore which holds up to capacity
+// responses, the capacity defaults to 1024 if it is not positive.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+
+	return &MemoryStore{
+		capacity: capacity,
+		now:      time.Now,
+		entries:  list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements Store's Get method, the response which is no longer
+// servable is evicted.
+func (s *MemoryStore) Get(ctx context.Context, key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	resp := e.Value.(*memoryEntry).resp
+	if !s.now().Before(resp.StaleUntil) {
+		s.entries.Remove(e)
+		delete(s.items, key)
+		return nil, nil
+	}
+	s.entries.MoveToFront(e)
+
+	return resp, nil
+}
+
+// Set implements Store's Set method.
+func (s *MemoryStore) Set(ctx context.Context, key string, resp *CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		e.Value.(*memoryEntry).resp = resp
+		s.entries.MoveToFront(e)
+		return nil
+	}
+
+	s.items[key] = s.entries.PushFront(&memoryEntry{key: key, resp: resp})
+	for s.entries.Len() > s.capacity {
+		e := s.entries.Back()
+		s.entries.Remove(e)
+		delete(s.items, e.Value.(*memoryEntry).key)
+	}
+
+	return nil
+}
+
+// Delete implements Store's Delete method.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.entries.Remove(e)
+		delete(s.items, key)
+	}
+
+	return nil
+}
+
+// Len returns the number of responses.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.Len()
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if cc := req.URL.Query().Get("cc"); cc != "" {
+			w.Header().Set("Cache-Control", cc)
+		}
+		if req.URL.Query().Has("cookie") {
+			w.Header().Set("Set-Cookie", "session=1")
+		}
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprintf(w, "%d", calls)
+	}
+	r := fastrouter.New()
+	r.Use(Cache(CacheConfig{}))
+	r.Get("/", handler)
+	r.Post("/", handler)
+	r.Prepare()
+
+	tests := []struct {
+		method   string
+		path     string
+		header   map[string]string
+		status   string
+		body     string
+		expected int
+	}{
+		{http.MethodGet, "/", nil, "MISS", "1", 1},
+		{http.MethodGet, "/", nil, "HIT", "1", 1},
+		{http.MethodHead, "/", nil, "HIT", "", 1},
+		{http.MethodPost, "/", nil, "", "2", 2},
+		{http.MethodGet, "/", map[string]string{"Authorization": "Bearer token"}, "", "3", 3},
+		{http.MethodGet, "/", map[string]string{"Cache-Control": "no-store"}, "", "4", 4},
+		{http.MethodGet, "/", map[string]string{"Cache-Control": "no-cache"}, "MISS", "5", 5},
+		{http.MethodGet, "/", nil, "HIT", "5", 5},
+		{http.MethodGet, "/", map[string]string{"Accept-Language": "fr"}, "MISS", "6", 6},
+		{http.MethodGet, "/", map[string]string{"Accept-Language": "fr"}, "HIT", "6", 6},
+		{http.MethodGet, "/?cc=no-store", nil, "MISS", "7", 7},
+		{http.MethodGet, "/?cc=no-store", nil, "MISS", "8", 8},
+		{http.MethodGet, "/?cc=private", nil, "MISS", "9", 9},
+		{http.MethodGet, "/?cc=max-age%3D0", nil, "MISS", "10", 10},
+		{http.MethodGet, "/?cookie", nil, "MISS", "11", 11},
+		{http.MethodGet, "/?cookie", nil, "MISS", "12", 12},
+		{http.MethodGet, "/?cc=public%2C+max-age%3D60", nil, "MISS", "13", 13},
+		{http.MethodGet, "/?cc=public%2C+max-age%3D60", nil, "HIT", "13", 13},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		for name, value := range test.header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if status := w.Header().Get("X-Cache"); status != test.status {
+			t.Errorf("%d: expect X-Cache to be %q, but got %q", i, test.status, status)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%d: expect body to be %q, but got %q", i, test.body, w.Body.String())
+		}
+		if calls != test.expected {
+			t.Errorf("%d: expect handler to be called %d times, but got %d", i, test.expected, calls)
+		}
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		now := time.Unix(0, 0)
+		store := NewMemoryStore(0)
+		store.now = func() time.Time { return now }
+		cache := newResponseCache(CacheConfig{Store: store, TTL: 10 * time.Second, StaleWhileRevalidate: 10 * time.Second})
+		cache.now = func() time.Time { return now }
+
+		var calls int32
+		r := fastrouter.New()
+		r.PooledParams = pooled
+		r.Get("/users/<id>", func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%s:%d", fastrouter.Param(req, "id"), atomic.AddInt32(&calls, 1))
+		}).Use(cache.middleware)
+		r.Prepare()
+
+		tests := []struct {
+			elapsed time.Duration
+			status  string
+			body    string
+			age     string
+		}{
+			{0, "MISS", "1:1", ""},
+			{5 * time.Second, "HIT", "1:1", "5"},
+			{10 * time.Second, "STALE", "1:1", "15"},
+			{1 * time.Second, "HIT", "1:2", "1"},
+			{20 * time.Second, "MISS", "1:3", ""},
+		}
+		for i, test := range tests {
+			now = now.Add(test.elapsed)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+			if status := w.Header().Get("X-Cache"); status != test.status {
+				t.Errorf("pooled %t, %d: expect X-Cache to be %q, but got %q", pooled, i, test.status, status)
+			}
+			if w.Body.String() != test.body {
+				t.Errorf("pooled %t, %d: expect body to be %q, but got %q", pooled, i, test.body, w.Body.String())
+			}
+			if age := w.Header().Get("Age"); age != test.age {
+				t.Errorf("pooled %t, %d: expect Age to be %q, but got %q", pooled, i, test.age, age)
+			}
+			if test.status == "STALE" {
+				// waits for the revalidation.
+				for {
+					cache.mu.Lock()
+					n := len(cache.revalidating)
+					cache.mu.Unlock()
+					if n == 0 {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	store := NewMemoryStore(2)
+	store.now = func() time.Time { return now }
+	resp := func(ttl time.Duration) *CachedResponse {
+		return &CachedResponse{StatusCode: http.StatusOK, StaleUntil: now.Add(ttl)}
+	}
+
+	store.Set(ctx, "a", resp(time.Minute))
+	store.Set(ctx, "b", resp(time.Minute))
+	// touches a, so that b is the least recently used.
+	if v, _ := store.Get(ctx, "a"); v == nil {
+		t.Fatal("expect a to be stored")
+	}
+	store.Set(ctx, "c", resp(time.Second))
+	if v, _ := store.Get(ctx, "b"); v != nil {
+		t.Error("expect b to be evicted")
+	}
+	if store.Len() != 2 {
+		t.Errorf("expect length to be 2, but got %d", store.Len())
+	}
+
+	now = now.Add(time.Second)
+	if v, _ := store.Get(ctx, "c"); v != nil {
+		t.Error("expect c to be expired")
+	}
+	store.Delete(ctx, "a")
+	if store.Len() != 0 {
+		t.Errorf("expect length to be 0, but got %d", store.Len())
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// Compressor is a compression writer which can be reused via Reset,
+// such as *gzip.Writer and *flate.Writer.
+type Compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Encoder is a content coding of compression.
+type Encoder struct {
+	// The content coding, such as "br".
+	Name string
+
+	// The func that returns a new compressor which writes to w.
+	New func(w io.Writer) Compressor
+}
+
+// CompressConfig is the configuration of compression.
+type CompressConfig struct {
+	// The compression level of gzip and deflate, defaults to
+	// gzip.DefaultCompression.
+	Level int
+
+	// The minimum bytes of response to be compressed, defaults to 1024.
+	MinSize int
+
+	// The compressible content types, the one ends with "/" matches
+	// the types with the prefix, such as "text/", defaults to the
+	// DefaultCompressibleTypes.
+	ContentTypes []string
+
+	// The additional encoders, such as brotli of the third-party
+	// packages, they are preferred over gzip and deflate in order if
+	// the client accepts multiple codings with the same quality.
+	Encoders []Encoder
+}
+
+// DefaultCompressibleTypes is the default compressible content types.
+var DefaultCompressibleTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// Compress returns a middleware which compresses the response with the
+// content coding negotiated via the Accept-Encoding header, gzip and
+// deflate are supported out of the box:
+//
+//	r.Use(middleware.Compress(middleware.CompressConfig{}))
+//
+// The response is compressed only if its content type is compressible
+// and its size reaches the MinSize, the response which already has the
+// Content-Encoding header is left as it is. The Vary header is set for
+// the compressible responses, the strong ETag of compressed response is
+// weakened, and the compressors are pooled.
+func Compress(config CompressConfig) fastrouter.Middleware {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if config.MinSize <= 0 {
+		config.MinSize = 1024
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = DefaultCompressibleTypes
+	}
+
+	level := config.Level
+	encoders := append(config.Encoders[:len(config.Encoders):len(config.Encoders)],
+		Encoder{Name: "gzip", New: func(w io.Writer) Compressor {
+			cw, err := gzip.NewWriterLevel(w, level)
+			if err != nil {
+				panic(err)
+			}
+			return cw
+		}},
+		Encoder{Name: "deflate", New: func(w io.Writer) Compressor {
+			cw, err := flate.NewWriter(w, level)
+			if err != nil {
+				panic(err)
+			}
+			return cw
+		}},
+	)
+	pools := make(map[string]*sync.Pool, len(encoders))
+	for _, encoder := range encoders {
+		newCompressor := encoder.New
+		pools[encoder.Name] = &sync.Pool{New: func() interface{} {
+			return newCompressor(io.Discard)
+		}}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			coding := negotiateEncoding(req.Header.Get("Accept-Encoding"), encoders)
+			if coding == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				config:         &config,
+				coding:         coding,
+				pool:           pools[coding],
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// negotiateEncoding returns the content coding which has the highest
+// quality in the given Accept-Encoding header, the encoders in front
+// are preferred if the qualities are equal, empty if none acceptable.
+func negotiateEncoding(accept string, encoders []Encoder) string {
+	if accept == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoder := range encoders {
+		q, ok := qualities[encoder.Name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoder.Name, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter is a http.ResponseWriter which buffers the response
+// until it reaches the MinSize, then decides whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+
+	config *CompressConfig
+	coding string
+	pool   *sync.Pool
+
+	// the status code of WriteHeader, zero if it has not been called.
+	code int
+
+	// the buffered response body before deciding.
+	buf []byte
+
+	// indicates whether the compression was decided.
+	decided bool
+
+	// the compressor, nil if the response is not compressed.
+	compressor Compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.code != 0 || cw.decided {
+		return
+	}
+	if code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	cw.code = code
+	if !cw.compressible() {
+		cw.decide(false, false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.config.MinSize {
+			return len(p), nil
+		}
+		compress := cw.compressible()
+		if err := cw.decide(compress, compress); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// compressible reports whether the response is compressible, it does
+// not take the MinSize into account except the declared Content-Length.
+func (cw *compressWriter) compressible() bool {
+	switch cw.code {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusSwitchingProtocols, http.StatusPartialContent:
+		return false
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if length := header.Get("Content-Length"); length != "" {
+		if n, err := strconv.Atoi(length); err == nil && n < cw.config.MinSize {
+			return false
+		}
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		if len(cw.buf) == 0 {
+			return true
+		}
+		contentType = http.DetectContentType(cw.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range cw.config.ContentTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decide writes the header and the buffered body, with or without
+// compression, the Vary header is set if the response varies by the
+// Accept-Encoding.
+func (cw *compressWriter) decide(compress, vary bool) error {
+	cw.decided = true
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+
+	header := cw.Header()
+	if compress {
+		if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+			header.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.coding)
+		// the strong ETag of the uncompressed body does not identify
+		// the compressed representation.
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.compressor = cw.pool.Get().(Compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+	}
+	if vary {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	cw.ResponseWriter.WriteHeader(cw.code)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close flushes the buffered body and closes the compressor, the
+// response which is smaller than MinSize is sent as it is.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.code == 0 && len(cw.buf) == 0 {
+			return
+		}
+		cw.decide(false, cw.compressible())
+	}
+	if cw.compressor != nil {
+		cw.compressor.Close()
+		cw.compressor.Reset(io.Discard)
+		cw.pool.Put(cw.compressor)
+		cw.compressor = nil
+	}
+}
+
+// Flush implements http.Flusher, the undecided response is compressed
+// regardless of the MinSize, since it is streaming.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		compress := cw.compressible()
+		cw.decide(compress, compress)
+	}
+	if f, ok := cw.compressor.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		cw.decided = true
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("middleware: the ResponseWriter does not implement http.Hijacker")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
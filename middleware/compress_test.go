@@ -0,0 +1,159 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("hello world ", 200)
+	r := fastrouter.New()
+	r.Use(Compress(CompressConfig{MinSize: 100}))
+	r.Get("/text", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Length", "2400")
+		w.Write([]byte(large))
+	})
+	r.Get("/small", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	r.Get("/image", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(large))
+	})
+	r.Get("/encoded", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte(large))
+	})
+	r.Get("/sniffed", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(large))
+	})
+	r.Get("/empty", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path     string
+		accept   string
+		encoding string
+		vary     bool
+	}{
+		{"/text", "gzip, deflate", "gzip", true},
+		{"/text", "deflate, gzip;q=0.5", "deflate", true},
+		{"/text", "gzip;q=0, *", "deflate", true},
+		{"/text", "br", "", false},
+		{"/text", "", "", false},
+		{"/small", "gzip", "", true},
+		{"/image", "gzip", "", false},
+		{"/encoded", "gzip", "gzip", false},
+		{"/sniffed", "gzip", "gzip", true},
+		{"/empty", "gzip", "", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("Accept-Encoding", test.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		header := w.Header()
+		if encoding := header.Get("Content-Encoding"); encoding != test.encoding {
+			t.Errorf("expect encoding of %q with %q to be %q, but got %q", test.path, test.accept, test.encoding, encoding)
+		}
+		if vary := header.Get("Vary") == "Accept-Encoding"; vary != test.vary {
+			t.Errorf("expect vary of %q with %q to be %t, but got %t", test.path, test.accept, test.vary, vary)
+		}
+		if test.path == "/encoded" || test.path == "/empty" {
+			continue
+		}
+
+		var body io.Reader = w.Body
+		switch test.encoding {
+		case "gzip":
+			if header.Get("Content-Length") != "" {
+				t.Errorf("expect content length of %q to be removed", test.path)
+			}
+			gr, err := gzip.NewReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gr
+		case "deflate":
+			body = flate.NewReader(body)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.path == "/small" {
+			if string(data) != `{"ok":true}` {
+				t.Errorf("expect body of %q to be kept, but got %q", test.path, data)
+			}
+		} else if string(data) != large {
+			t.Errorf("expect body of %q with %q to be decompressed, but got %d bytes", test.path, test.accept, len(data))
+		}
+	}
+}
+
+func TestCompress_Encoders(t *testing.T) {
+	var created int
+	r := fastrouter.New()
+	r.Use(Compress(CompressConfig{
+		MinSize: 1,
+		Encoders: []Encoder{{Name: "custom", New: func(w io.Writer) Compressor {
+			created++
+			return &upperCompressor{w: w}
+		}}},
+	}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	r.Prepare()
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip, custom")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if encoding := w.Header().Get("Content-Encoding"); encoding != "custom" {
+			t.Errorf("expect encoding to be %q, but got %q", "custom", encoding)
+		}
+		if body := w.Body.String(); body != "HELLO" {
+			t.Errorf("expect body to be %q, but got %q", "HELLO", body)
+		}
+	}
+	if created == 0 {
+		t.Error("expect the custom compressor to be created")
+	}
+}
+
+// upperCompressor is a fake compressor which uppercases the content.
+type upperCompressor struct {
+	w io.Writer
+}
+
+func (c *upperCompressor) Write(p []byte) (int, error) {
+	return c.w.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (c *upperCompressor) Close() error {
+	return nil
+}
+
+func (c *upperCompressor) Reset(w io.Writer) {
+	c.w = w
+}
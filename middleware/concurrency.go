@@ -0,0 +1,124 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// ConcurrencyConfig is the configuration of concurrency limiter.
+type ConcurrencyConfig struct {
+	// The maximum number of in-flight requests.
+	Limit int
+
+	// The maximum number of requests which are queued while the limit
+	// is reached, zero means the requests are shed immediately.
+	QueueSize int
+
+	// The maximum duration of a request waiting in the queue, defaults
+	// to one second.
+	QueueTimeout time.Duration
+
+	// The duration which is advertised via the Retry-After header of
+	// the shed requests, defaults to one second.
+	RetryAfter time.Duration
+}
+
+// ConcurrencyLimit returns a middleware which caps the in-flight
+// requests, the requests beyond the Limit wait in the queue, and they
+// are shed with 503 Service Unavailable and the Retry-After header if
+// the queue is full or the QueueTimeout elapses, so that a slow
+// downstream can not exhaust the whole service. The limiter can be
+// applied globally, to a group or to a route, each middleware returned
+// by ConcurrencyLimit has its own limit:
+//
+//	r.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyConfig{Limit: 1000, QueueSize: 100}))
+//	r.Get("/reports", report).Use(middleware.ConcurrencyLimit(middleware.ConcurrencyConfig{Limit: 4}))
+//
+// The queued request which is canceled by the client is abandoned
+// without response.
+//
+// Causes a panic if the Limit is not positive.
+func ConcurrencyLimit(config ConcurrencyConfig) fastrouter.Middleware {
+	return newConcurrencyLimiter(config).middleware
+}
+
+// concurrencyLimiter is a semaphore with a bounded queue.
+type concurrencyLimiter struct {
+	config ConcurrencyConfig
+	sem    chan struct{}
+
+	// the number of queued requests.
+	queued int64
+}
+
+func newConcurrencyLimiter(config ConcurrencyConfig) *concurrencyLimiter {
+	if config.Limit <= 0 {
+		panic(`the limit of concurrency limiter MUST be positive`)
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = time.Second
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+
+	return &concurrencyLimiter{
+		config: config,
+		sem:    make(chan struct{}, config.Limit),
+	}
+}
+
+func (l *concurrencyLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case l.sem <- struct{}{}:
+		default:
+			if !l.wait(w, req) {
+				return
+			}
+		}
+		defer func() {
+			<-l.sem
+		}()
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// wait waits in the queue until the request acquires the semaphore,
+// reports whether it is acquired, the request is shed otherwise.
+func (l *concurrencyLimiter) wait(w http.ResponseWriter, req *http.Request) bool {
+	if atomic.AddInt64(&l.queued, 1) > int64(l.config.QueueSize) {
+		atomic.AddInt64(&l.queued, -1)
+		l.shed(w)
+		return false
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		l.shed(w)
+	case <-req.Context().Done():
+	}
+
+	return false
+}
+
+// shed rejects the request with 503 Service Unavailable.
+func (l *concurrencyLimiter) shed(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(l.config.RetryAfter.Seconds()))))
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter := newConcurrencyLimiter(ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: time.Minute, RetryAfter: 1500 * time.Millisecond})
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	r := fastrouter.New()
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+	}).Use(limiter.middleware)
+	r.Prepare()
+
+	serve := func() <-chan int {
+		code := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			code <- w.Code
+		}()
+		return code
+	}
+
+	first := serve()
+	<-started
+	second := serve()
+	for atomic.LoadInt64(&limiter.queued) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the queue is full.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("expect Retry-After to be %q, but got %q", "2", retryAfter)
+	}
+
+	release <- struct{}{}
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("expect status code of the first request to be %d, but got %d", http.StatusOK, code)
+	}
+	<-started
+	release <- struct{}{}
+	if code := <-second; code != http.StatusOK {
+		t.Errorf("expect status code of the queued request to be %d, but got %d", http.StatusOK, code)
+	}
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	r := fastrouter.New()
+	r.Use(ConcurrencyLimit(ConcurrencyConfig{Limit: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond}))
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	})
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+	// waits for the slow request to acquire the limit.
+	for {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code == http.StatusServiceUnavailable {
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+				t.Errorf("expect Retry-After to be %q, but got %q", "1", retryAfter)
+			}
+			break
+		}
+	}
+
+	close(release)
+	<-done
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// NoncePlaceholder is the placeholder of the nonce in the policy of
+// ContentSecurityPolicy.
+const NoncePlaceholder = "{nonce}"
+
+type nonceKey struct{}
+
+// ContentSecurityPolicy returns a middleware which generates a nonce per
+// request, sets the Content-Security-Policy header with the given
+// policy which placeholders are replaced with the nonce, and exposes
+// the nonce via Nonce and TemplateFuncs, for example:
+//
+//	r.Use(middleware.ContentSecurityPolicy("script-src 'self' 'nonce-{nonce}'"))
+//
+// If the Content-Security-Policy header has been set by the upstream
+// middleware, such as a secure headers middleware, the placeholders of
+// the existing header are replaced instead, so that the policy is not
+// duplicated.
+func ContentSecurityPolicy(policy string) fastrouter.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			nonce := newNonce()
+			header := w.Header()
+			if existing := header.Values("Content-Security-Policy"); len(existing) > 0 {
+				for i, value := range existing {
+					existing[i] = strings.ReplaceAll(value, NoncePlaceholder, nonce)
+				}
+			} else {
+				header.Set("Content-Security-Policy", strings.ReplaceAll(policy, NoncePlaceholder, nonce))
+			}
+
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), nonceKey{}, nonce)))
+		})
+	}
+}
+
+// Nonce returns the CSP nonce of the request, empty if the request does
+// not pass through ContentSecurityPolicy.
+func Nonce(req *http.Request) string {
+	nonce, _ := req.Context().Value(nonceKey{}).(string)
+	return nonce
+}
+
+// TemplateFuncs returns the template funcs of the request, the cspNonce
+// returns the CSP nonce of request, and the csrfToken returns the CSRF
+// token of request:
+//
+//	<script nonce="{{ cspNonce }}">...</script>
+//	<input type="hidden" name="csrf_token" value="{{ csrfToken }}">
+func TemplateFuncs(req *http.Request) template.FuncMap {
+	nonce := Nonce(req)
+	token := CSRFToken(req)
+	return template.FuncMap{
+		"cspNonce": func() string {
+			return nonce
+		},
+		"csrfToken": func() string {
+			return token
+		},
+	}
+}
+
+// newNonce returns a random nonce of 128 bits which is encoded in
+// base64url, so that it needs no escaping in HTML attributes.
+func newNonce() string {
+	var data [16]byte
+	rand.Read(data[:])
+	return base64.RawURLEncoding.EncodeToString(data[:])
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestContentSecurityPolicy(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(TemplateFuncs(httptest.NewRequest(http.MethodGet, "/", nil))).
+		Parse(`<script nonce="{{ cspNonce }}"></script>`))
+
+	r := fastrouter.New()
+	r.Use(ContentSecurityPolicy("script-src 'self' 'nonce-{nonce}'"))
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		template.Must(tmpl.Clone()).Funcs(TemplateFuncs(req)).Execute(w, nil)
+	}
+	r.Get("/", handler)
+	r.Prepare()
+
+	nonces := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		body := w.Body.String()
+		nonce := strings.TrimSuffix(strings.TrimPrefix(body, `<script nonce="`), `"></script>`)
+		if nonce == "" || nonce == body {
+			t.Fatalf("expect nonce in body, but got %q", body)
+		}
+		if policy := w.Header().Get("Content-Security-Policy"); policy != "script-src 'self' 'nonce-"+nonce+"'" {
+			t.Errorf("expect policy to contain nonce %q, but got %q", nonce, policy)
+		}
+		nonces[nonce] = true
+	}
+	if len(nonces) != 2 {
+		t.Error("expect nonce to be generated per request")
+	}
+}
+
+func TestContentSecurityPolicy_Existing(t *testing.T) {
+	var nonce string
+	r := fastrouter.New()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'nonce-{nonce}'")
+			next.ServeHTTP(w, req)
+		})
+	}, ContentSecurityPolicy("script-src 'nonce-{nonce}'"))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		nonce = Nonce(req)
+	})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	policies := w.Header().Values("Content-Security-Policy")
+	if expect := "default-src 'self'; script-src 'nonce-" + nonce + "'"; len(policies) != 1 || policies[0] != expect {
+		t.Errorf("expect policies to be [%q], but got %q", expect, policies)
+	}
+}
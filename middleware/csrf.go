@@ -0,0 +1,199 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// CSRFExemptMeta is the metadata key of route which exempts the route
+// from CSRF protection, such as the webhooks:
+//
+//	r.Post("/webhooks/github", handler).Meta(middleware.CSRFExemptMeta, true)
+const CSRFExemptMeta = "csrf-exempt"
+
+// csrfTokenLength is the length of the encoded CSRF token, which
+// consists of 256 random bits.
+const csrfTokenLength = 43
+
+type csrfTokenKey struct{}
+
+// CSRFConfig is the configuration of CSRF protection.
+type CSRFConfig struct {
+	// The name of cookie which carries the token, defaults to "_csrf".
+	CookieName string
+
+	// The path of cookie, defaults to "/".
+	CookiePath string
+
+	// The domain of cookie, empty means the host of request.
+	CookieDomain string
+
+	// The max age of cookie, zero means a session cookie.
+	MaxAge time.Duration
+
+	// Indicates whether the cookie is only sent over HTTPS.
+	Secure bool
+
+	// The SameSite attribute of cookie, defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// The header which carries the submitted token, defaults to
+	// "X-CSRF-Token".
+	Header string
+
+	// The form field which carries the submitted token, it is consulted
+	// if the header is absent, defaults to "csrf_token".
+	FormField string
+
+	// The skipper for exempting the requests, the routes which have the
+	// CSRFExemptMeta metadata are exempted regardless.
+	Skipper fastrouter.Skipper
+
+	// The handler for handling the rejected requests, by default, they
+	// are responded with 403 Forbidden.
+	ErrorHandler http.Handler
+}
+
+// CSRF returns a middleware which protects the HTML forms from
+// cross-site request forgery via the double-submit cookie, that is, the
+// token is issued in the cookie, and the unsafe requests, except GET,
+// HEAD, OPTIONS and TRACE, MUST submit the same token via the header or
+// form field:
+//
+//	r.Use(middleware.CSRF(middleware.CSRFConfig{Secure: true}))
+//
+// The token is accessible via CSRFToken and TemplateFuncs, so that it
+// can be embedded into the forms:
+//
+//	<input type="hidden" name="csrf_token" value="{{ csrfToken }}">
+func CSRF(config CSRFConfig) fastrouter.Middleware {
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.Header == "" {
+		config.Header = "X-CSRF-Token"
+	}
+	if config.FormField == "" {
+		config.FormField = "csrf_token"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var token string
+			if cookie, err := req.Cookie(config.CookieName); err == nil && validCSRFToken(cookie.Value) {
+				token = cookie.Value
+			}
+
+			if !safeMethod(req.Method) && !config.exempt(req) {
+				if token == "" || !equalTokens(token, config.submittedToken(req)) {
+					config.ErrorHandler.ServeHTTP(w, req)
+					return
+				}
+			}
+
+			if token == "" {
+				token = newCSRFToken()
+				config.setCookie(w, token)
+			}
+			w.Header().Add("Vary", "Cookie")
+
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), csrfTokenKey{}, token)))
+		})
+	}
+}
+
+// CSRFToken returns the CSRF token of the request, empty if the request
+// does not pass through CSRF.
+func CSRFToken(req *http.Request) string {
+	token, _ := req.Context().Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// exempt reports whether the request is exempted from CSRF protection.
+func (config *CSRFConfig) exempt(req *http.Request) bool {
+	if exempt, _ := fastrouter.RouteMeta(req)[CSRFExemptMeta].(bool); exempt {
+		return true
+	}
+
+	return config.Skipper != nil && config.Skipper(req)
+}
+
+// submittedToken returns the token which is submitted via the header or
+// form field.
+func (config *CSRFConfig) submittedToken(req *http.Request) string {
+	if token := req.Header.Get(config.Header); token != "" {
+		return token
+	}
+
+	return req.PostFormValue(config.FormField)
+}
+
+// setCookie issues the token in the cookie.
+func (config *CSRFConfig) setCookie(w http.ResponseWriter, token string) {
+	cookie := &http.Cookie{
+		Name:     config.CookieName,
+		Value:    token,
+		Path:     config.CookiePath,
+		Domain:   config.CookieDomain,
+		Secure:   config.Secure,
+		HttpOnly: true,
+		SameSite: config.SameSite,
+	}
+	if config.MaxAge > 0 {
+		cookie.MaxAge = int(config.MaxAge / time.Second)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// safeMethod reports whether the method is safe, see RFC 9110.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+// equalTokens compares the tokens in constant time.
+func equalTokens(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// validCSRFToken reports whether the token is issued by newCSRFToken.
+func validCSRFToken(token string) bool {
+	if len(token) != csrfTokenLength {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil
+}
+
+// newCSRFToken returns a random token of 256 bits which is encoded in
+// base64url.
+func newCSRFToken() string {
+	var data [32]byte
+	rand.Read(data[:])
+	return base64.RawURLEncoding.EncodeToString(data[:])
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestCSRF(t *testing.T) {
+	var token string
+	r := fastrouter.New()
+	r.Use(CSRF(CSRFConfig{Secure: true}))
+	r.Get("/form", func(w http.ResponseWriter, req *http.Request) {
+		token = CSRFToken(req)
+	})
+	r.Post("/form", emptyHandler)
+	r.Post("/webhook", emptyHandler).Meta(CSRFExemptMeta, true)
+	r.Prepare()
+
+	// issues the token.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" {
+		t.Fatalf("expect the _csrf cookie to be issued, but got %v", cookies)
+	}
+	cookie := cookies[0]
+	if cookie.Value != token || !validCSRFToken(token) {
+		t.Fatalf("expect cookie %q to be the valid token %q", cookie.Value, token)
+	}
+	if !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expect cookie to be secure, http only and lax, but got %v", cookie)
+	}
+
+	// reuses the token.
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if len(w.Result().Cookies()) != 0 || token != cookie.Value {
+		t.Errorf("expect the token %q to be reused, but got %q", cookie.Value, token)
+	}
+
+	tests := []struct {
+		path     string
+		cookie   bool
+		header   string
+		form     string
+		expected int
+	}{
+		{"/form", true, cookie.Value, "", http.StatusOK},
+		{"/form", true, "", cookie.Value, http.StatusOK},
+		{"/form", true, "", "", http.StatusForbidden},
+		{"/form", true, "invalid", "", http.StatusForbidden},
+		{"/form", false, cookie.Value, "", http.StatusForbidden},
+		{"/webhook", false, "", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		form := url.Values{}
+		if test.form != "" {
+			form.Set("csrf_token", test.form)
+		}
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.cookie {
+			req.AddCookie(cookie)
+		}
+		if test.header != "" {
+			req.Header.Set("X-CSRF-Token", test.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.expected {
+			t.Errorf("expect status code of %s (cookie: %t, header: %q, form: %q) to be %d, but got %d",
+				test.path, test.cookie, test.header, test.form, test.expected, w.Code)
+		}
+	}
+}
+
+func TestCSRF_Skipper(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(CSRF(CSRFConfig{Skipper: fastrouter.SkipPaths("/api")}))
+	r.Post("/api", emptyHandler)
+	r.Post("/form", emptyHandler)
+	r.Prepare()
+
+	tests := map[string]int{
+		"/api":  http.StatusOK,
+		"/form": http.StatusForbidden,
+	}
+	for path, expected := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != expected {
+			t.Errorf("expect status code of %s to be %d, but got %d", path, expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package middleware provides the common middleware of FastRouter, such as
+the access log, the rate limiter, the concurrency limiter, the timeout,
+the compression, the body limit, the authentication, the CSRF
+protection, the security headers, the response cache and the ETag.
+
+	r := fastrouter.New()
+	r.Use(middleware.Logger(slog.Default()))
+*/
+package middleware
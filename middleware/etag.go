@@ -0,0 +1,189 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// ETagConfig is the configuration of ETag generation.
+type ETagConfig struct {
+	// The maximum bytes of response body to be hashed, the larger
+	// response is streamed without ETag, defaults to 1MB.
+	MaxSize int
+
+	// Indicates whether to generate the weak ETags.
+	Weak bool
+}
+
+// ETag returns a middleware which generates the ETag of successful
+// responses of GET and HEAD requests by hashing the body, and answers
+// the requests which If-None-Match matches with 304 Not Modified:
+//
+//	r.Use(middleware.Compress(middleware.CompressConfig{}), middleware.ETag(middleware.ETagConfig{}))
+//
+// The body is hashed while being buffered, the response which exceeds
+// the MaxSize or is flushed by the handler is streamed without ETag,
+// and the ETag which is set by the handler is left as it is.
+//
+// The ETag can be used either inside or outside the Compress, inside
+// is preferred, the ETag is generated from the uncompressed body, and
+// the Compress weakens it for the compressed responses, so that the
+// conditional requests of all the content codings are answered.
+func ETag(config ETagConfig) fastrouter.Middleware {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, req: req, config: &config, hash: sha256.New()}
+			next.ServeHTTP(ew, req)
+			ew.close()
+		})
+	}
+}
+
+// etagWriter is a http.ResponseWriter which buffers and hashes the
+// response body until the handler returns or it exceeds the MaxSize.
+type etagWriter struct {
+	http.ResponseWriter
+
+	req    *http.Request
+	config *ETagConfig
+	hash   hash.Hash
+
+	// the status code of WriteHeader, zero if it has not been called.
+	code int
+
+	// the buffered response body.
+	buf []byte
+
+	// indicates whether the response is streamed without ETag.
+	streaming bool
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.code != 0 || ew.streaming {
+		return
+	}
+	if code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	ew.code = code
+	if code != http.StatusOK {
+		ew.stream()
+	}
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if ew.code == 0 {
+		ew.code = http.StatusOK
+	}
+	if ew.streaming {
+		return ew.ResponseWriter.Write(p)
+	}
+	if len(ew.buf)+len(p) > ew.config.MaxSize {
+		if err := ew.stream(); err != nil {
+			return 0, err
+		}
+		return ew.ResponseWriter.Write(p)
+	}
+
+	ew.buf = append(ew.buf, p...)
+	ew.hash.Write(p)
+	return len(p), nil
+}
+
+// stream writes the header and the buffered body without ETag.
+func (ew *etagWriter) stream() error {
+	ew.streaming = true
+	if ew.code == 0 {
+		ew.code = http.StatusOK
+	}
+	ew.ResponseWriter.WriteHeader(ew.code)
+
+	buf := ew.buf
+	ew.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := ew.ResponseWriter.Write(buf)
+	return err
+}
+
+// close sets the ETag of the buffered response, and writes it or 304
+// Not Modified if the If-None-Match matches.
+func (ew *etagWriter) close() {
+	if ew.streaming || ew.code == 0 {
+		return
+	}
+
+	header := ew.Header()
+	etag := header.Get("ETag")
+	// the HEAD response which has no body can not be hashed.
+	if etag == "" && (ew.req.Method == http.MethodGet || len(ew.buf) > 0) {
+		etag = `"` + base64.RawURLEncoding.EncodeToString(ew.hash.Sum(nil)[:16]) + `"`
+		if ew.config.Weak {
+			etag = "W/" + etag
+		}
+		header.Set("ETag", etag)
+	}
+
+	if etag != "" && matchETag(ew.req.Header.Values("If-None-Match"), etag) {
+		header.Del("Content-Length")
+		header.Del("Content-Type")
+		ew.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	ew.stream()
+}
+
+// Flush implements http.Flusher, the flushed response is streamed
+// without ETag.
+func (ew *etagWriter) Flush() {
+	if !ew.streaming {
+		ew.stream()
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// matchETag reports whether the If-None-Match header values match the
+// given ETag via the weak comparison, see RFC 9110.
+func matchETag(values []string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestETag(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(ETag(ETagConfig{MaxSize: 16}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	r.Post("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	r.Get("/large", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 10)))
+		w.Write([]byte(strings.Repeat("x", 10)))
+	})
+	r.Get("/missing", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "missing", http.StatusNotFound)
+	})
+	r.Get("/custom", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("custom"))
+	})
+	r.Get("/stream", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+	})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || w.Body.String() != "hello" {
+		t.Fatalf("expect a strong ETag and the body, but got %q and %q", etag, w.Body.String())
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		ifNoneMatch string
+		code        int
+		etag        string
+		body        string
+	}{
+		{http.MethodGet, "/", etag, http.StatusNotModified, etag, ""},
+		{http.MethodGet, "/", "W/" + etag, http.StatusNotModified, etag, ""},
+		{http.MethodGet, "/", `"other", ` + etag, http.StatusNotModified, etag, ""},
+		{http.MethodGet, "/", "*", http.StatusNotModified, etag, ""},
+		{http.MethodGet, "/", `"other"`, http.StatusOK, etag, "hello"},
+		{http.MethodHead, "/", "", http.StatusOK, etag, ""},
+		{http.MethodPost, "/", etag, http.StatusOK, "", "hello"},
+		{http.MethodGet, "/large", "", http.StatusOK, "", strings.Repeat("x", 20)},
+		{http.MethodGet, "/missing", "", http.StatusNotFound, "", "missing\n"},
+		{http.MethodGet, "/custom", "", http.StatusOK, `"v1"`, "custom"},
+		{http.MethodGet, "/custom", `"v1"`, http.StatusNotModified, `"v1"`, ""},
+		{http.MethodGet, "/stream", "", http.StatusOK, "", "chunk"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %s (If-None-Match: %s) to be %d, but got %d", test.method, test.path, test.ifNoneMatch, test.code, w.Code)
+		}
+		if actual := w.Header().Get("ETag"); actual != test.etag {
+			t.Errorf("expect ETag of %s %s to be %q, but got %q", test.method, test.path, test.etag, actual)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %s %s to be %q, but got %q", test.method, test.path, test.body, w.Body.String())
+		}
+	}
+}
+
+func TestETag_Compress(t *testing.T) {
+	body := strings.Repeat("hello ", 100)
+	r := fastrouter.New()
+	r.Use(Compress(CompressConfig{MinSize: 10}), ETag(ETagConfig{}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("expect the uncompressed response to have a strong ETag, but got %q", etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") != "W/"+etag {
+		t.Fatalf("expect the compressed response to have the weak ETag %q, but got %q", "W/"+etag, w.Header().Get("ETag"))
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("expect If-None-Match %s to be answered with 304 and empty body, but got %d and %q", ifNoneMatch, w.Code, w.Body.String())
+		}
+	}
+}
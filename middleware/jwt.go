@@ -0,0 +1,501 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// The errors of JWT authentication, they are passed to the
+// ErrorHandler of JWTConfig.
+var (
+	// ErrMissingToken is the error of request without Bearer token.
+	ErrMissingToken = errors.New("middleware: missing token")
+
+	// ErrInvalidToken is the error of malformed token, or the token
+	// which signature or claims are invalid.
+	ErrInvalidToken = errors.New("middleware: invalid token")
+
+	// ErrTokenExpired is the error of expired token.
+	ErrTokenExpired = errors.New("middleware: token expired")
+)
+
+// The supported signing algorithms of JWT.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// JWTConfig is the configuration of JWT authentication.
+type JWTConfig struct {
+	// The func that returns the verification key of the given algorithm
+	// and key ID, that is, []byte for HS256, *rsa.PublicKey for RS256
+	// and *ecdsa.PublicKey for ES256, see StaticKey and JWKS.Key. The
+	// ctx is the context of request, the func SHOULD give up once it
+	// is done.
+	KeyFunc func(ctx context.Context, alg, kid string) (interface{}, error)
+
+	// The allowed algorithms, defaults to all the supported algorithms,
+	// the key returned by KeyFunc MUST match the algorithm anyway.
+	Algorithms []string
+
+	// The expected issuer, empty means the issuer is not checked.
+	Issuer string
+
+	// The expected audience, empty means the audience is not checked.
+	Audience string
+
+	// The leeway of checking the exp and nbf claims, for tolerating
+	// the clock skew.
+	Leeway time.Duration
+
+	// The handler for handling the unauthenticated requests, the err
+	// wraps ErrMissingToken, ErrInvalidToken or ErrTokenExpired. By
+	// default, it responds with 401 Unauthorized and the
+	// WWW-Authenticate header.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+// JWT returns a middleware which authenticates the requests via the
+// Bearer token of Authorization header, the claims of valid token are
+// accessible via fastrouter.Claims, so that the API groups can require
+// authentication declaratively:
+//
+//	jwks := middleware.NewJWKS("https://example.com/.well-known/jwks.json", time.Hour)
+//	api.Use(middleware.JWT(middleware.JWTConfig{KeyFunc: jwks.Key}))
+//
+// Causes a panic if the KeyFunc is nil.
+func JWT(config JWTConfig) fastrouter.Middleware {
+	if config.KeyFunc == nil {
+		panic(`the key func of JWT MUST NOT be nil`)
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{HS256, RS256, ES256}
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = jwtErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			claims, err := config.verify(req)
+			if err != nil {
+				config.ErrorHandler(w, req, err)
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(fastrouter.WithClaims(req.Context(), claims)))
+		})
+	}
+}
+
+// jwtErrorHandler responds with 401 Unauthorized, see RFC 6750.
+func jwtErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	challenge := "Bearer"
+	if !errors.Is(err, ErrMissingToken) {
+		challenge = `Bearer error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// StaticKey returns a key func which always returns the given key, it
+// is usually used with the HMAC secret:
+//
+//	middleware.JWTConfig{KeyFunc: middleware.StaticKey([]byte(secret))}
+func StaticKey(key interface{}) func(ctx context.Context, alg, kid string) (interface{}, error) {
+	return func(ctx context.Context, alg, kid string) (interface{}, error) {
+		return key, nil
+	}
+}
+
+// verify verifies the Bearer token of request, and returns its claims.
+func (c *JWTConfig) verify(req *http.Request) (map[string]interface{}, error) {
+	scheme, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrMissingToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header: %v", ErrInvalidToken, err)
+	}
+	if !contains(c.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("%w: algorithm %q is not allowed", ErrInvalidToken, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature: %v", ErrInvalidToken, err)
+	}
+	key, err := c.KeyFunc(req.Context(), header.Alg, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]interface{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+	if err = c.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// validate validates the registered claims, the exp and nbf claims
+// MUST be numeric if present.
+func (c *JWTConfig) validate(claims map[string]interface{}) error {
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if _, found := claims["exp"]; found && !ok {
+		return fmt.Errorf("%w: malformed exp claim", ErrInvalidToken)
+	}
+	if ok && now.Add(-c.Leeway).After(time.Unix(int64(exp), 0)) {
+		return ErrTokenExpired
+	}
+	nbf, ok := claims["nbf"].(float64)
+	if _, found := claims["nbf"]; found && !ok {
+		return fmt.Errorf("%w: malformed nbf claim", ErrInvalidToken)
+	}
+	if ok && now.Add(c.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if c.Issuer != "" && claims["iss"] != c.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if c.Audience != "" && !hasAudience(claims["aud"], c.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+// hasAudience reports whether the aud claim, which is either a string
+// or an array of strings, contains the given audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verifySignature verifies the signature of the signing input with
+// the given algorithm and key.
+func verifySignature(alg string, key interface{}, input string, signature []byte) error {
+	digest := sha256.Sum256([]byte(input))
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("the key of %s MUST be []byte, but got %T", alg, key)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("the key of %s MUST be *rsa.PublicKey, but got %T", alg, key)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("signature mismatch")
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return fmt.Errorf("the key of %s MUST be P-256 *ecdsa.PublicKey, but got %T", alg, key)
+		}
+		if len(signature) != 64 {
+			return errors.New("signature mismatch")
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errors.New("signature mismatch")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	return nil
+}
+
+// decodeSegment decodes the base64url-encoded JSON segment into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// contains reports whether the given value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minJWKSRefreshInterval is the minimum interval of fetching the key
+// set, except the periodic refreshing, so that neither the forged key
+// IDs nor a failing endpoint floods the JWKS endpoint.
+const minJWKSRefreshInterval = time.Minute
+
+// jwksTimeout is the timeout of fetching the key set via the default
+// client, and the maximum duration of waiting for the in-flight fetch.
+const jwksTimeout = 10 * time.Second
+
+// maxJWKSSize is the maximum size of the key set document.
+const maxJWKSSize = 1 << 20
+
+// jwksClient is the default client for fetching the key set.
+var jwksClient = &http.Client{Timeout: jwksTimeout}
+
+// JWKS is a JSON Web Key Set which is fetched from the URL, and is
+// refreshed periodically, or on demand when an unknown key ID shows up,
+// the RSA and P-256 EC keys are supported.
+//
+// The key set is fetched without blocking the lookups of known keys,
+// the concurrent fetches are coalesced into one, and the failed fetch
+// is not retried within a minute, the stale keys are kept in that case.
+// The lookups of unknown keys wait for the in-flight fetch for ten
+// seconds at most, or until the context of request is done, and the
+// key set document is limited to 1MB.
+type JWKS struct {
+	// The URL of key set.
+	URL string
+
+	// The interval of refreshing the key set, defaults to one hour.
+	RefreshInterval time.Duration
+
+	// The HTTP client for fetching the key set, a client which times
+	// out in ten seconds is used if it is nil.
+	Client *http.Client
+
+	mu   sync.Mutex
+	keys map[string]interface{}
+	// the error of the latest fetch.
+	err error
+	// the time of the latest fetch, regardless of its result.
+	fetched time.Time
+	// it is closed once the in-flight fetch is done, nil if there is no
+	// in-flight fetch.
+	fetching chan struct{}
+}
+
+// NewJWKS returns a key set which is fetched from the given URL, and is
+// refreshed every the given interval.
+func NewJWKS(url string, refreshInterval time.Duration) *JWKS {
+	return &JWKS{URL: url, RefreshInterval: refreshInterval}
+}
+
+// Key returns the key of the given key ID, it can be used as the
+// KeyFunc of JWTConfig.
+func (j *JWKS) Key(ctx context.Context, alg, kid string) (interface{}, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	done := j.fetching
+	if done == nil && j.due(ok) {
+		done = j.refresh()
+	}
+	// the known key is served while fetching.
+	if !ok && done != nil {
+		j.mu.Unlock()
+		timer := time.NewTimer(jwksTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			return nil, errors.New("timed out waiting for the key set")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		j.mu.Lock()
+		key, ok = j.keys[kid]
+	}
+	keys, err := j.keys, j.err
+	j.mu.Unlock()
+
+	if !ok {
+		if keys == nil && err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+// due reports whether the key set ought to be fetched, the found
+// indicates whether the key ID is known, it MUST be called with the
+// lock held.
+func (j *JWKS) due(found bool) bool {
+	if j.fetched.IsZero() {
+		return true
+	}
+
+	interval := j.RefreshInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	elapsed := time.Since(j.fetched)
+	if elapsed >= interval && j.err == nil {
+		return true
+	}
+
+	return elapsed >= minJWKSRefreshInterval && (!found || elapsed >= interval)
+}
+
+// refresh starts fetching the key set in the background, and returns
+// the channel which is closed once it is done, the stale keys are kept
+// if fetching failed, it MUST be called with the lock held.
+func (j *JWKS) refresh() chan struct{} {
+	done := make(chan struct{})
+	j.fetching = done
+	j.fetched = time.Now()
+
+	go func() {
+		keys, err := j.fetch()
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.err = err
+		j.fetching = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+
+	return done
+}
+
+// jwk is a JSON Web Key, see RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch fetches the key set.
+func (j *JWKS) fetch() (map[string]interface{}, error) {
+	client := j.Client
+	if client == nil {
+		client = jwksClient
+	}
+	resp, err := client.Get(j.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d of key set", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKey returns the public key.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeBigInt decodes the base64url-encoded big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// signJWT signs the claims with the given algorithm and key.
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case HS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case RS256:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case ES256:
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]interface{}{"hmac": secret, "rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+
+	var sub interface{}
+	r := fastrouter.New()
+	r.Use(JWT(JWTConfig{
+		KeyFunc: func(ctx context.Context, alg, kid string) (interface{}, error) {
+			if key, ok := keys[kid]; ok {
+				return key, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+		Issuer:   "issuer",
+		Audience: "api",
+	}))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		sub = fastrouter.Claims(req)["sub"]
+	})
+	r.Prepare()
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": []string{"web", "api"}, "exp": now + 60}
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"HS256", signJWT(t, HS256, "hmac", secret, valid), http.StatusOK},
+		{"RS256", signJWT(t, RS256, "rsa", rsaKey, valid), http.StatusOK},
+		{"ES256", signJWT(t, ES256, "ec", ecKey, valid), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"malformed", "foo.bar", http.StatusUnauthorized},
+		{"wrong secret", signJWT(t, HS256, "hmac", []byte("wrong"), valid), http.StatusUnauthorized},
+		{"key confusion", signJWT(t, HS256, "rsa", secret, valid), http.StatusUnauthorized},
+		{"unknown key", signJWT(t, HS256, "unknown", secret, valid), http.StatusUnauthorized},
+		{"expired", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": "api", "exp": now - 60}), http.StatusUnauthorized},
+		{"not before", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": "api", "nbf": now + 60}), http.StatusUnauthorized},
+		{"issuer", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "other", "aud": "api"}), http.StatusUnauthorized},
+		{"audience", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": "web"}), http.StatusUnauthorized},
+		{"malformed exp", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": "api", "exp": "never"}), http.StatusUnauthorized},
+		{"malformed nbf", signJWT(t, HS256, "hmac", secret, map[string]interface{}{"sub": "foo", "iss": "issuer", "aud": "api", "nbf": "now"}), http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		sub = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s to be %d, but got %d", test.name, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && sub != "foo" {
+			t.Errorf("expect subject of %s to be %q, but got %v", test.name, "foo", sub)
+		}
+		if test.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("expect WWW-Authenticate header of %s", test.name)
+		}
+	}
+}
+
+func TestJWT_ErrorHandler(t *testing.T) {
+	var got error
+	r := fastrouter.New()
+	r.Use(JWT(JWTConfig{
+		KeyFunc: StaticKey([]byte("secret")),
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			got = err
+			w.WriteHeader(http.StatusForbidden)
+		},
+	}))
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	token := signJWT(t, HS256, "", []byte("secret"), map[string]interface{}{"exp": time.Now().Unix() - 60})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || !errors.Is(got, ErrTokenExpired) {
+		t.Errorf("expect custom error response for %v, but got %d %v", ErrTokenExpired, w.Code, got)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		}})
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, time.Hour)
+	r := fastrouter.New()
+	r.Use(JWT(JWTConfig{KeyFunc: jwks.Key}))
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	tokens := []string{
+		signJWT(t, RS256, "rsa", rsaKey, map[string]interface{}{"sub": "foo"}),
+		signJWT(t, ES256, "ec", ecKey, map[string]interface{}{"sub": "foo"}),
+	}
+	for _, token := range tokens {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	if _, err := jwks.Key(context.Background(), RS256, "unknown"); err == nil {
+		t.Error("expect an error for unknown key")
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("expect the key set to be fetched once, but got %d", n)
+	}
+}
+
+func TestJWKS_Fetching(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	var fetches int32
+	var failing int32 = 1
+	release := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		}})
+	}))
+	defer server.Close()
+
+	// the failed fetch is not retried within the minimum interval.
+	jwks := NewJWKS(server.URL, time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, err := jwks.Key(context.Background(), ES256, "ec"); err == nil {
+			t.Error("expect an error of the failed fetch")
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("expect the failed fetch to be done once, but got %d", n)
+	}
+
+	// the concurrent fetches are coalesced.
+	atomic.StoreInt32(&failing, 0)
+	atomic.StoreInt32(&fetches, 0)
+	jwks = NewJWKS(server.URL, time.Hour)
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := jwks.Key(context.Background(), ES256, "ec")
+			errs <- err
+		}()
+	}
+	for atomic.LoadInt32(&fetches) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release <- struct{}{}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("expect the key to be found, but got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("expect the concurrent fetches to be coalesced, but got %d", n)
+	}
+
+	// the known key is served while refreshing.
+	jwks.mu.Lock()
+	jwks.fetched = time.Now().Add(-2 * time.Hour)
+	jwks.mu.Unlock()
+	found := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key(context.Background(), ES256, "ec")
+		found <- err
+	}()
+	select {
+	case err := <-found:
+		if err != nil {
+			t.Errorf("expect the known key to be found, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect the known key to be served without waiting for the refresh")
+	}
+	release <- struct{}{}
+}
+
+func TestJWKS_Canceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/large" {
+			w.Write([]byte(`{"keys": [`))
+			for i := 0; i < maxJWKSSize; i += 32 {
+				w.Write([]byte(`{"kty": "RSA", "kid": "padding", "n": "AQAB", "e": "AQAB"},`))
+			}
+			w.Write([]byte(`]}`))
+			return
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// the waiter gives up once the context of request is done.
+	jwks := NewJWKS(server.URL, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := jwks.Key(ctx, RS256, "rsa"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect the error to be %v, but got %v", context.DeadlineExceeded, err)
+	}
+
+	// the key set document is limited.
+	jwks = NewJWKS(server.URL+"/large", time.Hour)
+	if _, err := jwks.Key(context.Background(), RS256, "padding"); err == nil {
+		t.Error("expect an error of the oversized key set")
+	}
+}
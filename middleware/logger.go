@@ -0,0 +1,143 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// Logger returns an access log middleware which logs the requests via
+// the given structured logger, the record contains the request method,
+// path, matched route pattern, status code, response bytes, latency,
+// remote address and user agent.
+func Logger(logger *slog.Logger) fastrouter.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, req)
+
+			logger.LogAttrs(req.Context(), slog.LevelInfo, "request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("pattern", fastrouter.MatchedPattern(req)),
+				slog.Int("status", rw.status()),
+				slog.Int64("bytes", rw.bytes),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote", req.RemoteAddr),
+				slog.String("user_agent", req.UserAgent()),
+			)
+		})
+	}
+}
+
+// CombinedLogger returns an access log middleware which writes the
+// requests to the given writer in Apache combined log format, followed
+// by the matched route pattern and latency, for example:
+//
+//	127.0.0.1 - frank [10/Oct/2017:13:55:36 -0700] "GET /users/1 HTTP/1.1" 200 2326 "http://example.com/" "curl/7.54.0" "/users/<id>" 1.024ms
+func CombinedLogger(w io.Writer) fastrouter.Middleware {
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			lw := &responseWriter{ResponseWriter: rw}
+			next.ServeHTTP(lw, req)
+			latency := time.Since(start)
+
+			host, _, err := net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				host = req.RemoteAddr
+			}
+			user := "-"
+			if username, _, ok := req.BasicAuth(); ok && username != "" {
+				user = username
+			}
+			size := "-"
+			if lw.bytes > 0 {
+				size = strconv.FormatInt(lw.bytes, 10)
+			}
+			line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s %q %q %q %s\n",
+				host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+				req.Method, req.RequestURI, req.Proto, lw.status(), size,
+				req.Referer(), req.UserAgent(), fastrouter.MatchedPattern(req), latency)
+
+			mu.Lock()
+			io.WriteString(w, line)
+			mu.Unlock()
+		})
+	}
+}
+
+// responseWriter is a http.ResponseWriter that captures the status code
+// and the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+
+	code  int
+	bytes int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// status returns the status code, it is 200 if the handler wrote
+// nothing.
+func (w *responseWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+
+	return w.code
+}
+
+// Flush implements http.Flusher.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("middleware: the ResponseWriter does not implement http.Hijacker")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by
+// http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func userHandler(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("user " + fastrouter.Param(req, "id")))
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := fastrouter.New()
+	r.Use(Logger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	r.Post("/users/<id>", userHandler)
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/1", nil)
+	req.Header.Set("User-Agent", "test")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode record %q: %v", buf.String(), err)
+	}
+	expect := map[string]interface{}{
+		"method":     "POST",
+		"path":       "/users/1",
+		"pattern":    "/users/<id>",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(6),
+		"user_agent": "test",
+	}
+	for key, value := range expect {
+		if record[key] != value {
+			t.Errorf("expect %s to be %v, but got %v", key, value, record[key])
+		}
+	}
+	if _, ok := record["latency"]; !ok {
+		t.Error("expect latency to be logged")
+	}
+}
+
+func TestCombinedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := fastrouter.New()
+	r.Use(CombinedLogger(&buf))
+	r.Post("/users/<id>", userHandler)
+	r.Get("/empty", func(w http.ResponseWriter, req *http.Request) {})
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/1?page=2", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.SetBasicAuth("frank", "secret")
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "curl/7.54.0")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/empty", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 lines, but got %q", buf.String())
+	}
+	expects := []*regexp.Regexp{
+		regexp.MustCompile(`^127\.0\.0\.1 - frank \[[^\]]+\] "POST /users/1\?page=2 HTTP/1\.1" 201 6 "http://example\.com/" "curl/7\.54\.0" "/users/<id>" \S+$`),
+		regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "GET /empty HTTP/1\.1" 200 - "" "" "/empty" \S+$`),
+	}
+	for i, expect := range expects {
+		if !expect.Match(lines[i]) {
+			t.Errorf("expect line %d to match %q, but got %q", i, expect, lines[i])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// RateLimitConfig is the configuration of rate limiter.
+type RateLimitConfig struct {
+	// The number of requests which are allowed in every period.
+	Limit int
+
+	// The period of limit, defaults to one second.
+	Period time.Duration
+
+	// The maximum number of requests which are allowed in a burst,
+	// defaults to the Limit.
+	Burst int
+
+	// The func that returns the key of request, the requests with the
+	// same key share the same bucket, defaults to fastrouter.ClientIP.
+	KeyFunc func(req *http.Request) string
+}
+
+// RateLimit returns a token-bucket rate limiter middleware, the bucket
+// of each key holds up to Burst tokens and is refilled at the rate of
+// Limit per Period, each request consumes a token, the requests are
+// rejected with 429 Too Many Requests if the bucket is empty.
+//
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// are set for every request, and the Retry-After header is also set for
+// the rejected requests. The limiter can be applied globally, to a
+// group or to a route, each middleware returned by RateLimit has its
+// own buckets:
+//
+//	api.Use(middleware.RateLimit(middleware.RateLimitConfig{Limit: 100, Period: time.Minute}))
+//	r.Post("/login", login).Use(middleware.RateLimit(middleware.RateLimitConfig{Limit: 5, Period: time.Minute}))
+//
+// Causes a panic if the Limit is not positive.
+func RateLimit(config RateLimitConfig) fastrouter.Middleware {
+	return newRateLimiter(config).middleware
+}
+
+// rateLimiter is a token-bucket rate limiter.
+type rateLimiter struct {
+	burst   float64
+	rate    float64 // tokens per second.
+	keyFunc func(req *http.Request) string
+	now     func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is the token bucket of a key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	if config.Limit <= 0 {
+		panic(`the limit of rate limiter MUST be positive`)
+	}
+	if config.Period <= 0 {
+		config.Period = time.Second
+	}
+	if config.Burst <= 0 {
+		config.Burst = config.Limit
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = fastrouter.ClientIP
+	}
+
+	return &rateLimiter{
+		burst:   float64(config.Burst),
+		rate:    float64(config.Limit) / config.Period.Seconds(),
+		keyFunc: config.KeyFunc,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allowed, remaining := l.take(l.keyFunc(req))
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(int(l.burst)))
+		header.Set("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+		header.Set("RateLimit-Reset", strconv.Itoa(l.seconds(l.burst-remaining)))
+		if !allowed {
+			header.Set("Retry-After", strconv.Itoa(l.seconds(1-remaining)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// take takes a token from the bucket of the given key, reports whether
+// the token is taken, and returns the remaining tokens.
+func (l *rateLimiter) take(key string) (bool, float64) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, b.tokens
+	}
+
+	b.tokens--
+	return true, b.tokens
+}
+
+// sweep removes the buckets which have been refilled, so that the idle
+// keys do not pile up, it runs at most once per refilling duration.
+func (l *rateLimiter) sweep(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// seconds returns the seconds for refilling the given tokens, rounded
+// up, the tiny floating-point error is ignored.
+func (l *rateLimiter) seconds(tokens float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(tokens/l.rate - 1e-9))
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(RateLimitConfig{Limit: 2, Period: time.Minute})
+	limiter.now = func() time.Time { return now }
+	r := fastrouter.New()
+	r.Get("/", emptyHandler).Use(limiter.middleware)
+	r.Prepare()
+
+	tests := []struct {
+		elapsed    time.Duration
+		remoteAddr string
+		code       int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{0, "10.0.0.1:1234", http.StatusOK, "1", "30", ""},
+		{0, "10.0.0.1:1234", http.StatusOK, "0", "60", ""},
+		{0, "10.0.0.1:1234", http.StatusTooManyRequests, "0", "60", "30"},
+		{0, "10.0.0.2:1234", http.StatusOK, "1", "30", ""},
+		{10 * time.Second, "10.0.0.1:1234", http.StatusTooManyRequests, "0", "50", "20"},
+		{20 * time.Second, "10.0.0.1:1234", http.StatusOK, "0", "60", ""},
+	}
+	for i, test := range tests {
+		now = now.Add(test.elapsed)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%d: expect status code to be %d, but got %d", i, test.code, w.Code)
+		}
+		header := w.Header()
+		if limit := header.Get("RateLimit-Limit"); limit != "2" {
+			t.Errorf("%d: expect limit to be %q, but got %q", i, "2", limit)
+		}
+		if remaining := header.Get("RateLimit-Remaining"); remaining != test.remaining {
+			t.Errorf("%d: expect remaining to be %q, but got %q", i, test.remaining, remaining)
+		}
+		if reset := header.Get("RateLimit-Reset"); reset != test.reset {
+			t.Errorf("%d: expect reset to be %q, but got %q", i, test.reset, reset)
+		}
+		if retryAfter := header.Get("Retry-After"); retryAfter != test.retryAfter {
+			t.Errorf("%d: expect retry after to be %q, but got %q", i, test.retryAfter, retryAfter)
+		}
+	}
+}
+
+func TestRateLimit_KeyFunc(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(RateLimit(RateLimitConfig{
+		Limit: 1,
+		KeyFunc: func(req *http.Request) string {
+			return req.Header.Get("X-Api-Key")
+		},
+	}))
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		key  string
+		code int
+	}{
+		{"foo", http.StatusOK},
+		{"bar", http.StatusOK},
+		{"foo", http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", test.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of key %q to be %d, but got %d", test.key, test.code, w.Code)
+		}
+	}
+}
+
+func TestRateLimit_Sweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(RateLimitConfig{Limit: 1})
+	limiter.now = func() time.Time { return now }
+	limiter.take("foo")
+	now = now.Add(2 * time.Second)
+	limiter.take("bar")
+	if _, ok := limiter.buckets["foo"]; ok {
+		t.Error("expect the refilled bucket to be removed")
+	}
+}
+
+func emptyHandler(w http.ResponseWriter, req *http.Request) {}
@@ -0,0 +1,70 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// RealIP returns a middleware which resolves the client IP of the
+// request which comes from the given trusted proxies, the proxies are
+// IP addresses or CIDRs, such as "10.0.0.0/8". The resolved IP is
+// accessible via fastrouter.ClientIP.
+//
+// The client IP is resolved via fastrouter.ResolveClientIP, the same
+// resolver of the Networks of router, the headers of the request which
+// does not come from a trusted proxy are ignored, since they can be
+// forged.
+//
+// Causes a panic if any proxy is invalid.
+func RealIP(trustedProxies ...string) fastrouter.Middleware {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			panic(fmt.Errorf("invalid trusted proxy %q: %v", proxy, err))
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	trusted := func(addr netip.Addr) bool {
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ip := fastrouter.ClientIP(req)
+			if remote, err := netip.ParseAddr(ip); err == nil && trusted(remote.Unmap()) {
+				ip = fastrouter.ResolveClientIP(req.Header, remote.Unmap(), trusted).String()
+			}
+
+			next.ServeHTTP(w, req.WithContext(fastrouter.WithClientIP(req.Context(), ip)))
+		})
+	}
+}
+
+// parsePrefix parses the IP address or CIDR.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestRealIP(t *testing.T) {
+	var ip string
+	r := fastrouter.New()
+	r.Use(RealIP("10.0.0.0/8", "192.168.1.1"))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		ip = fastrouter.ClientIP(req)
+	})
+	r.Prepare()
+
+	tests := []struct {
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		{"203.0.113.7:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "203.0.113.7"},
+		{"10.0.0.1:1234", nil, "10.0.0.1"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+		{"192.168.1.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for=1.1.1.1, for="[2001:db8::1]:8080";proto=https`, "X-Forwarded-For": "1.1.1.1"}, "2001:db8::1"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.1"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remote
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if ip != test.ip {
+			t.Errorf("expect client IP of %q with %v to be %q, but got %q", test.remote, test.headers, test.ip, ip)
+		}
+	}
+}
+
+func TestRealIP_Networks(t *testing.T) {
+	// the Networks of router and RealIP agree on the client.
+	proxies := []string{"10.0.0.0/8", "2001:db8::/64"}
+	var ip string
+	r := fastrouter.New()
+	r.TrustedProxies = proxies
+	r.Use(RealIP(proxies...))
+	internal := r.Group("internal")
+	internal.Networks = []string{"192.168.0.0/16", "2001:db8:1::/64"}
+	internal.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		ip = fastrouter.ClientIP(req)
+	})
+	r.Prepare()
+
+	tests := []struct {
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7, 192.168.1.1"}, "192.168.1.1"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8:1::7]:4711"`}, "2001:db8:1::7"},
+		{"[2001:db8::1]:1234", map[string]string{"Forwarded": `For=192.168.1.1;proto=https`}, "192.168.1.1"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "::ffff:192.168.1.1"}, "192.168.1.1"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "192.168.1.1, 203.0.113.7"}, ""},
+		{"203.0.113.7:1234", map[string]string{"X-Forwarded-For": "192.168.1.1"}, ""},
+	}
+	for _, test := range tests {
+		ip = ""
+		req := httptest.NewRequest(http.MethodGet, "/internal", nil)
+		req.RemoteAddr = test.remote
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if ip != test.ip {
+			t.Errorf("expect client IP of %q with %v to be %q, but got %q and status code %d", test.remote, test.headers, test.ip, ip, w.Code)
+		}
+	}
+}
+
+func TestRealIP_InvalidProxy(t *testing.T) {
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for invalid proxy")
+		}
+	}()
+	RealIP("invalid")
+}
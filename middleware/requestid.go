@@ -0,0 +1,87 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// RequestIDHeader is the header which carries the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength is the maximum length of the incoming request ID.
+const maxRequestIDLength = 128
+
+// RequestID returns a middleware which reads the request ID from the
+// X-Request-Id header, or generates a ULID if the header is absent or
+// invalid, stores it in the request context, so that it is accessible
+// via fastrouter.RequestID, and echoes it in the response header, so
+// that all services behind the router share the correlation IDs.
+//
+// The incoming request ID is valid if it consists of at most 128
+// visible ASCII characters.
+func RequestID() fastrouter.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = NewULID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, req.WithContext(fastrouter.WithRequestID(req.Context(), id)))
+		})
+	}
+}
+
+// validRequestID reports whether the incoming request ID is valid.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// crockford is the Crockford's Base32 alphabet which is used by ULID.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a new ULID, which consists of 48-bit timestamp in
+// milliseconds and 80-bit randomness, encoded as 26 characters in
+// Crockford's Base32, so that the IDs are sortable by time.
+func NewULID() string {
+	var data [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		data[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(data[6:])
+
+	// encodes the 128 bits as 26 characters of 5 bits, the first
+	// character holds the 3 most significant bits.
+	var id [26]byte
+	for i := 25; i >= 0; i-- {
+		bit := 128 - 5*(26-i)
+		var v byte
+		for j := 0; j < 5; j++ {
+			if b := bit + j; b >= 0 && data[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 0x10 >> j
+			}
+		}
+		id[i] = crockford[v]
+	}
+
+	return string(id[:])
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+var ulidRegexp = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+func TestRequestID(t *testing.T) {
+	var id string
+	r := fastrouter.New()
+	r.Use(RequestID())
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		id = fastrouter.RequestID(req)
+	})
+	r.Prepare()
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"abc-123", "abc-123"},
+		{"", ""},
+		{"has space", ""},
+		{strings.Repeat("x", 129), ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			req.Header.Set(RequestIDHeader, test.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if test.expected != "" && id != test.expected {
+			t.Errorf("expect request ID of %q to be %q, but got %q", test.header, test.expected, id)
+		}
+		if test.expected == "" && !ulidRegexp.MatchString(id) {
+			t.Errorf("expect request ID of %q to be a ULID, but got %q", test.header, id)
+		}
+		if echoed := w.Header().Get(RequestIDHeader); echoed != id {
+			t.Errorf("expect response header to be %q, but got %q", id, echoed)
+		}
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	prev := NewULID()
+	for i := 0; i < 100; i++ {
+		id := NewULID()
+		if !ulidRegexp.MatchString(id) {
+			t.Fatalf("expect %q to be a ULID", id)
+		}
+		if id == prev {
+			t.Fatalf("expect ULIDs to be unique, but got %q twice", id)
+		}
+		if id[:10] < prev[:10] {
+			t.Fatalf("expect ULIDs to be sortable by time, but got %q after %q", id, prev)
+		}
+		prev = id
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// SecureHeadersConfig is the configuration of security headers, the
+// header of empty field is not sent.
+type SecureHeadersConfig struct {
+	// The max age of Strict-Transport-Security, zero means the header
+	// is not sent.
+	HSTSMaxAge time.Duration
+
+	// Indicates whether the HSTS policy applies to the subdomains.
+	HSTSIncludeSubdomains bool
+
+	// Indicates whether to opt in the HSTS preload list.
+	HSTSPreload bool
+
+	// The value of X-Content-Type-Options, such as "nosniff".
+	ContentTypeOptions string
+
+	// The value of X-Frame-Options, such as "DENY" and "SAMEORIGIN".
+	FrameOptions string
+
+	// The value of Referrer-Policy, such as "no-referrer".
+	ReferrerPolicy string
+
+	// The value of Content-Security-Policy, the NoncePlaceholder is
+	// replaced if the ContentSecurityPolicy middleware is used after.
+	ContentSecurityPolicy string
+
+	// The value of Permissions-Policy, such as "camera=(), microphone=()".
+	PermissionsPolicy string
+}
+
+// DefaultSecureHeaders is the recommended configuration of security
+// headers, it does not set the Content-Security-Policy and
+// Permissions-Policy, since they depend on the application.
+var DefaultSecureHeaders = SecureHeadersConfig{
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	HSTSIncludeSubdomains: true,
+	ContentTypeOptions:    "nosniff",
+	FrameOptions:          "DENY",
+	ReferrerPolicy:        "strict-origin-when-cross-origin",
+}
+
+// SecureHeaders returns a middleware which sets the security headers,
+// it can be applied globally or per group, and the headers of the
+// latter take precedence:
+//
+//	r.Use(middleware.SecureHeaders(middleware.DefaultSecureHeaders))
+//
+// The Strict-Transport-Security header is sent only if the request is
+// served over HTTPS, that is, via TLS or a proxy which X-Forwarded-Proto
+// is "https".
+func SecureHeaders(config SecureHeadersConfig) fastrouter.Middleware {
+	var hsts string
+	if config.HSTSMaxAge > 0 {
+		directives := []string{"max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge/time.Second), 10)}
+		if config.HSTSIncludeSubdomains {
+			directives = append(directives, "includeSubDomains")
+		}
+		if config.HSTSPreload {
+			directives = append(directives, "preload")
+		}
+		hsts = strings.Join(directives, "; ")
+	}
+	headers := [][2]string{
+		{"X-Content-Type-Options", config.ContentTypeOptions},
+		{"X-Frame-Options", config.FrameOptions},
+		{"Referrer-Policy", config.ReferrerPolicy},
+		{"Content-Security-Policy", config.ContentSecurityPolicy},
+		{"Permissions-Policy", config.PermissionsPolicy},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			header := w.Header()
+			for _, h := range headers {
+				if h[1] != "" {
+					header.Set(h[0], h[1])
+				}
+			}
+			if hsts != "" && isHTTPS(req) {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// isHTTPS reports whether the request is served over HTTPS.
+func isHTTPS(req *http.Request) bool {
+	return req.TLS != nil || strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	r := fastrouter.New()
+	r.Use(SecureHeaders(DefaultSecureHeaders))
+	r.Get("/", emptyHandler)
+	embed := r.Group("embed")
+	embed.Use(SecureHeaders(SecureHeadersConfig{
+		FrameOptions:          "SAMEORIGIN",
+		ContentSecurityPolicy: "frame-ancestors 'self'",
+		PermissionsPolicy:     "camera=()",
+	}))
+	embed.Get("/widget", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path     string
+		https    bool
+		expected map[string]string
+	}{
+		{"/", false, map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "DENY",
+			"Referrer-Policy":           "strict-origin-when-cross-origin",
+			"Strict-Transport-Security": "",
+			"Content-Security-Policy":   "",
+		}},
+		{"/", true, map[string]string{
+			"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		}},
+		{"/embed/widget", false, map[string]string{
+			"X-Content-Type-Options":  "nosniff",
+			"X-Frame-Options":         "SAMEORIGIN",
+			"Content-Security-Policy": "frame-ancestors 'self'",
+			"Permissions-Policy":      "camera=()",
+		}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.https {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		for name, value := range test.expected {
+			if actual := w.Header().Get(name); actual != value {
+				t.Errorf("expect %s of %s (https: %t) to be %q, but got %q", name, test.path, test.https, value, actual)
+			}
+		}
+	}
+}
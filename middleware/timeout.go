@@ -0,0 +1,30 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+// Timeout returns a middleware which derives the request context via
+// context.WithTimeout with the given duration d, and responds with the
+// given status code, such as 503 Service Unavailable or 504 Gateway
+// Timeout, if the handler does not produce any output before the
+// deadline, see fastrouter.TimeoutHandler.
+//
+// It can be applied globally, to a group or to a route:
+//
+//	r.Get("/reports", reports).Use(middleware.Timeout(5*time.Second, http.StatusGatewayTimeout))
+//
+// The per-route handler timeout is also available via Route.Timeout
+// and Route.TimeoutCode.
+func Timeout(d time.Duration, code int) fastrouter.Middleware {
+	return func(next http.Handler) http.Handler {
+		return fastrouter.TimeoutHandler(next, d, code, http.StatusText(code))
+	}
+}
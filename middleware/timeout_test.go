@@ -0,0 +1,83 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/razonyang/fastrouter"
+)
+
+func TestTimeout(t *testing.T) {
+	writeErr := make(chan error, 1)
+	r := fastrouter.New()
+	r.Use(Timeout(20*time.Millisecond, http.StatusGatewayTimeout))
+	r.Get("/fast", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := req.Context().Deadline(); !ok {
+			t.Error("expect request context to have a deadline")
+		}
+		w.Write([]byte("fast"))
+	})
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		w.Header().Set("X-Slow", "true")
+		_, err := w.Write([]byte("slow"))
+		writeErr <- err
+	})
+	r.Get("/started", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("started"))
+		<-req.Context().Done()
+		w.Write([]byte(" and finished"))
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/fast", http.StatusOK, "fast"},
+		{"/slow", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout) + "\n"},
+		{"/started", http.StatusOK, "started and finished"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q to be %d, but got %d", test.path, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+		if test.path == "/slow" {
+			if err := <-writeErr; err != http.ErrHandlerTimeout {
+				t.Errorf("expect write error to be %v, but got %v", http.ErrHandlerTimeout, err)
+			}
+			if w.Header().Get("X-Slow") != "" {
+				t.Error("expect header written after timeout to be discarded")
+			}
+		}
+	}
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	r := fastrouter.New()
+	r.DisableRecovery = true
+	r.Use(Timeout(time.Second, http.StatusServiceUnavailable))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+	r.Prepare()
+
+	defer func() {
+		if rcv := recover(); rcv != "boom" {
+			t.Errorf("expect panic to be propagated, but got %v", rcv)
+		}
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
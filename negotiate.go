@@ -0,0 +1,55 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the best offered content type according to the
+// Accept header, returns the first offer if nothing is acceptable or
+// the Accept header is empty.
+//
+// The offers with the same quality are preferred in order of Accept.
+func negotiate(accept string, offers ...string) string {
+	best, bestQ := offers[0], 0.0
+	for _, v := range strings.Split(accept, ",") {
+		mediaRange, q := strings.TrimSpace(v), 1.0
+		if i := strings.IndexByte(mediaRange, ';'); i >= 0 {
+			for _, param := range strings.Split(mediaRange[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = f
+					}
+				}
+			}
+			mediaRange = strings.TrimSpace(mediaRange[:i])
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		for _, offer := range offers {
+			if mediaRangeMatch(mediaRange, offer) {
+				best, bestQ = offer, q
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// mediaRangeMatch reports whether the media range, such as "text/*",
+// matches the given content type.
+func mediaRangeMatch(mediaRange, contentType string) bool {
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(contentType, mediaRange[:len(mediaRange)-1])
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"text/plain", "application/json", "text/html"}
+	tests := map[string]string{
+		"":                 "text/plain",
+		"application/json": "application/json",
+		"text/html,application/xhtml+xml,*/*;q=0.8": "text/html",
+		"application/json;q=0.5, text/html":         "text/html",
+		"application/*":                             "application/json",
+		"image/png":                                 "text/plain",
+		"*/*":                                       "text/plain",
+		"text/html;q=0, application/json;q=0.1":     "application/json",
+	}
+	for accept, expect := range tests {
+		if contentType := negotiate(accept, offers...); contentType != expect {
+			t.Errorf("expect the negotiated content type of %q to be %q, but got %q", accept, expect, contentType)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// prepareNetworks parses the Networks of router and its groups.
+func (r *Router) prepareNetworks() (err error) {
+	if r.networks, err = parseNetworks("network", r.Networks); err != nil {
+		return err
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		if err = r.groups[prefix].prepareNetworks(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clientNetworks returns the parsed Networks of the nearest router,
+// nil if all networks are served.
+func (r *Router) clientNetworks() []*net.IPNet {
+	for router := r; router != nil; router = router.parent {
+		if len(router.Networks) > 0 {
+			return router.networks
+		}
+	}
+
+	return nil
+}
+
+// servesClient reports whether the router serves the client network
+// which the request comes from.
+func (r *Router) servesClient(req *http.Request) bool {
+	nets := r.clientNetworks()
+	if nets == nil {
+		return true
+	}
+
+	ip := r.root().clientIP(req)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the client IP of request for the Networks, it is the
+// IP which is resolved via WithClientIP if any, since the Networks are
+// enforced before the middleware, the client IP is resolved from the
+// forwarding headers via ResolveClientIP if the request comes from the
+// TrustedProxies.
+func (r *Router) clientIP(req *http.Request) net.IP {
+	if ip, ok := req.Context().Value(contextClientIPKey).(string); ok {
+		return net.ParseIP(ip)
+	}
+
+	remote, err := parseNodeAddr(req.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+
+	return net.IP(ResolveClientIP(req.Header, remote, func(addr netip.Addr) bool {
+		return r.trusts(net.IP(addr.AsSlice()))
+	}).AsSlice())
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_Networks(t *testing.T) {
+	r := New()
+	r.Get("/", helloHandler("public"))
+	internal := r.Group("internal")
+	internal.Networks = []string{"10.0.0.0/8", "192.168.1.1"}
+	internal.Get("/metrics", helloHandler("metrics"))
+	public := internal.Group("public")
+	public.Networks = []string{"0.0.0.0/0", "::/0"}
+	public.Get("/", helloHandler("internal public"))
+	r.Prepare()
+
+	tests := []struct {
+		remoteAddr string
+		path       string
+		code       int
+	}{
+		{"203.0.113.7:1234", "/", http.StatusOK},
+		{"10.1.2.3:1234", "/internal/metrics", http.StatusOK},
+		{"192.168.1.1:1234", "/internal/metrics", http.StatusOK},
+		{"192.168.1.2:1234", "/internal/metrics", http.StatusNotFound},
+		{"203.0.113.7:1234", "/internal/metrics", http.StatusNotFound},
+		{"[2001:db8::1]:1234", "/internal/public", http.StatusOK},
+		{"invalid", "/internal/metrics", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.RemoteAddr = test.remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q from %q to be %d, but got %d", test.path, test.remoteAddr, test.code, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/metrics", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req = req.WithContext(WithClientIP(req.Context(), "10.0.0.1"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect the resolved client IP to be respected, but got %d", w.Code)
+	}
+}
+
+func TestRouter_NetworksProxied(t *testing.T) {
+	r := New()
+	r.TrustedProxies = []string{"10.0.0.1", "10.0.1.0/24"}
+	internal := r.Group("internal")
+	internal.Networks = []string{"10.0.0.0/8"}
+	internal.Get("/metrics", helloHandler("metrics"))
+	r.Prepare()
+
+	tests := []struct {
+		remoteAddr string
+		header     map[string]string
+		code       int
+	}{
+		{"10.0.0.1:1234", nil, http.StatusOK},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, http.StatusNotFound},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.2.3.4"}, http.StatusOK},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.2.3.4, 203.0.113.7, 10.0.1.2"}, http.StatusNotFound},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.2.3.4, 10.0.1.2"}, http.StatusOK},
+		{"10.0.0.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, http.StatusNotFound},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8::1]:80"`, "X-Forwarded-For": "10.2.3.4"}, http.StatusNotFound},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": "for=10.2.3.4;proto=https"}, http.StatusOK},
+		// the headers sent by the untrusted clients are ignored.
+		{"10.2.3.4:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, http.StatusOK},
+		{"203.0.113.7:1234", map[string]string{"X-Forwarded-For": "10.2.3.4"}, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/internal/metrics", nil)
+		req.RemoteAddr = test.remoteAddr
+		for name, value := range test.header {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code from %q with %v to be %d, but got %d", test.remoteAddr, test.header, test.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_NetworksInvalid(t *testing.T) {
+	r := New()
+	r.Group("internal").Networks = []string{"10.0.0.0/33"}
+	if err := r.PrepareE(); err == nil {
+		t.Error("expect an error for invalid network")
+	}
+}
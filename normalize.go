@@ -0,0 +1,118 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Normalization is a bundle of the common request normalizations which
+// are applied ahead of matching, see Router.Normalization, for example:
+//
+//	r.Normalization = &fastrouter.Normalization{
+//		MergeSlashes:   true,
+//		TrimSpaces:     true,
+//		LowercaseHost:  true,
+//		MaxHeaderBytes: 8 << 10,
+//	}
+//
+// Unlike CleanPath, the request is rewritten in place, rather than
+// redirected.
+type Normalization struct {
+	// Indicates whether to merge the duplicate slashes of path, such
+	// as "//users///1" to "/users/1".
+	MergeSlashes bool
+
+	// Indicates whether to trim the leading and trailing spaces from
+	// path, such as "/users/1%20" to "/users/1".
+	TrimSpaces bool
+
+	// Indicates whether to lowercase the Host of request.
+	LowercaseHost bool
+
+	// The maximum bytes of request header which is the sum of the
+	// length of names and values, the request which header exceeds the
+	// limit is responded with 431 Request Header Fields Too Large,
+	// zero means no limit.
+	MaxHeaderBytes int
+}
+
+// normalize returns the normalized request, ok is false if the request
+// was rejected.
+func (n *Normalization) normalize(w http.ResponseWriter, req *http.Request) (_ *http.Request, ok bool) {
+	if n.MaxHeaderBytes > 0 && headerBytes(req.Header) > n.MaxHeaderBytes {
+		code := http.StatusRequestHeaderFieldsTooLarge
+		http.Error(w, http.StatusText(code), code)
+		return req, false
+	}
+
+	path, rawPath := n.normalizePath(req.URL.Path), req.URL.RawPath
+	if rawPath != "" {
+		rawPath = n.normalizePath(rawPath)
+		if unescaped, err := url.PathUnescape(rawPath); err != nil || unescaped != path {
+			rawPath = ""
+		}
+	}
+	host := req.Host
+	if n.LowercaseHost {
+		host = strings.ToLower(host)
+	}
+	if path == req.URL.Path && rawPath == req.URL.RawPath && host == req.Host {
+		return req, true
+	}
+
+	r2 := new(http.Request)
+	*r2 = *req
+	r2.URL = new(url.URL)
+	*r2.URL = *req.URL
+	r2.URL.Path, r2.URL.RawPath, r2.Host = path, rawPath, host
+	return r2, true
+}
+
+// normalizePath normalizes the path or raw path.
+func (n *Normalization) normalizePath(p string) string {
+	if n.TrimSpaces {
+		p = "/" + trimSpaces(strings.TrimPrefix(p, "/"))
+	}
+	if n.MergeSlashes && strings.Contains(p, "//") {
+		var b strings.Builder
+		b.Grow(len(p))
+		for i := 0; i < len(p); i++ {
+			if p[i] == '/' && i > 0 && p[i-1] == '/' {
+				continue
+			}
+			b.WriteByte(p[i])
+		}
+		p = b.String()
+	}
+
+	return p
+}
+
+// trimSpaces trims the leading and trailing spaces, both the literal
+// and escaped ones.
+func trimSpaces(s string) string {
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "%20"), "%20")
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// headerBytes returns the sum of the length of header names and values.
+func headerBytes(header http.Header) int {
+	n := 0
+	for name, values := range header {
+		for _, value := range values {
+			n += len(name) + len(value)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter_Normalization(t *testing.T) {
+	r := New()
+	r.Normalization = &Normalization{
+		MergeSlashes:   true,
+		TrimSpaces:     true,
+		LowercaseHost:  true,
+		MaxHeaderBytes: 64,
+	}
+	r.Get("/users/<id>", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Host + " " + Param(req, "id")))
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path   string
+		host   string
+		header string
+		code   int
+		body   string
+	}{
+		{"/users/1", "example.com", "", http.StatusOK, "example.com 1"},
+		{"//users///1", "example.com", "", http.StatusOK, "example.com 1"},
+		{"/users/1%20%20", "Example.COM", "", http.StatusOK, "example.com 1"},
+		{"/%20users/1", "example.com", "", http.StatusOK, "example.com 1"},
+		{"/users/1", "example.com", strings.Repeat("x", 64), http.StatusRequestHeaderFieldsTooLarge, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://"+test.host+test.path, nil)
+		if test.header != "" {
+			req.Header.Set("X-Large", test.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q to be %d, but got %d", test.path, test.code, w.Code)
+			continue
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("expect body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+	}
+}
+
+func TestNormalization_normalizePath(t *testing.T) {
+	tests := []struct {
+		n    Normalization
+		path string
+		want string
+	}{
+		{Normalization{}, "//users//1", "//users//1"},
+		{Normalization{MergeSlashes: true}, "//users//1/", "/users/1/"},
+		{Normalization{TrimSpaces: true}, "/ users/1 ", "/users/1"},
+		{Normalization{TrimSpaces: true}, "/%20 ", "/"},
+		{Normalization{TrimSpaces: true, MergeSlashes: true}, "/ //users", "/users"},
+	}
+	for _, test := range tests {
+		if got := test.n.normalizePath(test.path); got != test.want {
+			t.Errorf("expect normalized path of %q to be %q, but got %q", test.path, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// NotFoundChain returns a handler that calls the given handlers in
+// order until one writes a response, and falls back to http.NotFound if
+// none of them writes, it is usually used as NotFoundHandler of hybrid
+// apps, for example, serving static files, then the index of SPA, and
+// then a JSON 404:
+//
+//	r.NotFoundHandler = fastrouter.NotFoundChain(staticFiles, spaIndex, jsonNotFound)
+//
+// The headers set by the handler which does not write a response are
+// discarded.
+func NotFoundChain(handlers ...http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, handler := range handlers {
+			cw := &chainWriter{w: w, header: make(http.Header)}
+			handler.ServeHTTP(cw, req)
+			if cw.wrote {
+				return
+			}
+		}
+
+		http.NotFound(w, req)
+	})
+}
+
+// chainWriter is a http.ResponseWriter that stages the header until
+// the handler writes a response.
+type chainWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	// indicates whether the handler wrote a response.
+	wrote bool
+}
+
+// start flushes the staged header into the underlying writer.
+func (cw *chainWriter) start() {
+	if cw.wrote {
+		return
+	}
+	cw.wrote = true
+
+	dst := cw.w.Header()
+	for k, v := range cw.header {
+		dst[k] = v
+	}
+}
+
+func (cw *chainWriter) Header() http.Header {
+	if cw.wrote {
+		return cw.w.Header()
+	}
+
+	return cw.header
+}
+
+func (cw *chainWriter) Write(p []byte) (int, error) {
+	cw.start()
+	return cw.w.Write(p)
+}
+
+func (cw *chainWriter) WriteHeader(code int) {
+	cw.start()
+	cw.w.WriteHeader(code)
+}
+
+// Flush implements http.Flusher.
+func (cw *chainWriter) Flush() {
+	cw.start()
+	if f, ok := cw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
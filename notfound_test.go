@@ -0,0 +1,48 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFoundChain(t *testing.T) {
+	static := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Static", "miss")
+		if strings.HasPrefix(req.URL.Path, "/assets/") {
+			w.Header().Set("X-Static", "hit")
+			w.Write([]byte("asset"))
+		}
+	})
+	spa := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, "/api/") {
+			w.Write([]byte("index"))
+		}
+	})
+	r := New()
+	r.NotFoundHandler = NotFoundChain(static, spa)
+	r.Prepare()
+
+	tests := []struct {
+		path   string
+		code   int
+		body   string
+		static string
+	}{
+		{"/assets/app.js", http.StatusOK, "asset", "hit"},
+		{"/dashboard", http.StatusOK, "index", ""},
+		{"/api/unknown", http.StatusNotFound, "404 page not found\n", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body || w.Header().Get("X-Static") != test.static {
+			t.Errorf("expect response of %q to be %d %q, but got %d %q %v", test.path, test.code, test.body, w.Code, w.Body.String(), w.Header())
+		}
+	}
+}
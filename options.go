@@ -0,0 +1,149 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Option is a configuration option of router, it is the option-based
+// alternative of the exported fields, such as PanicHandler, so that
+// the API can be modernized in stages without breaking the existing
+// users, both of them are honored:
+//
+//	r := fastrouter.New(
+//		fastrouter.WithMiddleware(logger),
+//		fastrouter.WithNotFoundHandler(notFound),
+//	)
+//
+// The options are applied at Prepare time, they take precedence over
+// the corresponding fields, and a warning is logged via Logger if the
+// field was set as well.
+//
+// The options MUST be created via the With* functions, the zero Option
+// is a no-op.
+type Option struct {
+	// the name of the corresponding field.
+	field string
+
+	// reports whether the corresponding field was set, nil if the
+	// option never conflicts with the field.
+	isSet func(r *Router) bool
+
+	apply func(r *Router)
+}
+
+// Configure appends the given options to the router, it is usually
+// used for configuring groups, since the root router can be configured
+// via New.
+func (r *Router) Configure(options ...Option) {
+	r.options = append(r.options, options...)
+}
+
+// WithMiddleware returns an option which appends the given middleware
+// to the Middleware of router.
+func WithMiddleware(middleware ...Middleware) Option {
+	return Option{
+		field: "Middleware",
+		apply: func(r *Router) {
+			r.Middleware = append(r.Middleware, middleware...)
+		},
+	}
+}
+
+// WithPanicHandler returns an option which sets the PanicHandler.
+func WithPanicHandler(handler func(w http.ResponseWriter, req *http.Request, rcv interface{})) Option {
+	return Option{
+		field: "PanicHandler",
+		isSet: func(r *Router) bool { return r.PanicHandler != nil },
+		apply: func(r *Router) { r.PanicHandler = handler },
+	}
+}
+
+// WithNotFoundHandler returns an option which sets the NotFoundHandler.
+func WithNotFoundHandler(handler http.Handler) Option {
+	return Option{
+		field: "NotFoundHandler",
+		isSet: func(r *Router) bool { return r.NotFoundHandler != nil },
+		apply: func(r *Router) { r.NotFoundHandler = handler },
+	}
+}
+
+// WithMethodNotAllowedHandler returns an option which sets the
+// MethodNotAllowedHandler.
+func WithMethodNotAllowedHandler(handler func(w http.ResponseWriter, req *http.Request, methods []string)) Option {
+	return Option{
+		field: "MethodNotAllowedHandler",
+		isSet: func(r *Router) bool { return r.MethodNotAllowedHandler != nil },
+		apply: func(r *Router) { r.MethodNotAllowedHandler = handler },
+	}
+}
+
+// WithOptionsHandler returns an option which sets the OptionsHandler.
+func WithOptionsHandler(handler func(w http.ResponseWriter, req *http.Request, methods []string)) Option {
+	return Option{
+		field: "OptionsHandler",
+		isSet: func(r *Router) bool { return r.OptionsHandler != nil },
+		apply: func(r *Router) { r.OptionsHandler = handler },
+	}
+}
+
+// WithLogger returns an option which sets the Logger.
+func WithLogger(logger Logger) Option {
+	return Option{
+		field: "Logger",
+		isSet: func(r *Router) bool { return r.Logger != nil },
+		apply: func(r *Router) { r.Logger = logger },
+	}
+}
+
+// WithTrailingSlashesPolicy returns an option which sets the
+// TrailingSlashesPolicy.
+//
+// Note that, the field set to IgnoreTrailingSlashes explicitly can not
+// be told apart from the unset one, since it is the zero value, so
+// that no warning is logged in such case.
+func WithTrailingSlashesPolicy(policy int8) Option {
+	return Option{
+		field: "TrailingSlashesPolicy",
+		isSet: func(r *Router) bool { return r.TrailingSlashesPolicy != IgnoreTrailingSlashes },
+		apply: func(r *Router) { r.TrailingSlashesPolicy = policy },
+	}
+}
+
+// applyOptions applies the options of router and its groups, and
+// returns the descriptions of the fields which were overridden, the
+// applied options are discarded.
+func (r *Router) applyOptions() (conflicts []string) {
+	for _, option := range r.options {
+		if option.apply == nil {
+			continue
+		}
+		if option.isSet != nil && option.isSet(r) {
+			conflict := option.field
+			if r.parent != nil {
+				conflict += fmt.Sprintf(" of group %q", r.fullPrefix())
+			}
+			conflicts = append(conflicts, conflict)
+		}
+		option.apply(r)
+	}
+	r.options = nil
+
+	for _, prefix := range r.sortedPrefixes() {
+		conflicts = append(conflicts, r.groups[prefix].applyOptions()...)
+	}
+
+	return conflicts
+}
+
+// reconcileOptions applies the options, and warns on the conflicting
+// settings.
+func (r *Router) reconcileOptions() {
+	for _, conflict := range r.applyOptions() {
+		r.logger().Printf("fastrouter: the field %s is overridden by the option", conflict)
+	}
+}
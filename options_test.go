@@ -0,0 +1,80 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNew_Options(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(
+		WithLogger(log.New(&buf, "", 0)),
+		WithMiddleware(newHeaderMiddleware("X-Option", "true")),
+		WithNotFoundHandler(helloHandler("option not found")),
+		WithTrailingSlashesPolicy(RemoveTrailingSlashes),
+	)
+	r.Use(newHeaderMiddleware("X-Field", "true"))
+	r.NotFoundHandler = helloHandler("field not found")
+	api := r.Group("api")
+	api.Configure(WithNotFoundHandler(helloHandler("api not found")))
+	r.Get("/users", emptyHandler)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Header().Get("X-Option") != "true" || w.Header().Get("X-Field") != "true" {
+		t.Errorf("expect both the option and field middleware to be applied, but got %v", w.Header())
+	}
+
+	tests := map[string]string{
+		"/missing":     "option not found",
+		"/api/missing": "api not found",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/", nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expect trailing slashes to be removed, but got %d", w.Code)
+	}
+
+	logs := buf.String()
+	if !strings.Contains(logs, "the field NotFoundHandler is overridden by the option") {
+		t.Errorf("expect a warning of conflicting NotFoundHandler, but got %q", logs)
+	}
+	if strings.Count(logs, "\n") != 1 {
+		t.Errorf("expect exactly one warning, but got %q", logs)
+	}
+
+	buf.Reset()
+	r.Prepare()
+	if buf.Len() != 0 {
+		t.Errorf("expect the options to be applied once, but got %q", buf.String())
+	}
+}
+
+func TestNew_ZeroOption(t *testing.T) {
+	r := New(Option{}, WithNotFoundHandler(helloHandler("option not found")))
+	r.Configure(Option{})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Body.String() != "option not found" {
+		t.Errorf("expect body to be %q, but got %q", "option not found", w.Body.String())
+	}
+}
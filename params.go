@@ -0,0 +1,147 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ParamList is an ordered list of the parameters of the request path,
+// it is passed to downstream handler if the router's PooledParams is
+// enabled.
+//
+// The ParamList is pooled and reused across requests, it is only valid
+// until the handler returns, it MUST NOT be retained or accessed by the
+// goroutines that outlive the handler, copy the values if needed.
+type ParamList struct {
+	names  []string
+	values []string
+}
+
+// Len returns the number of parameters.
+func (p *ParamList) Len() int {
+	return len(p.names)
+}
+
+// ByIndex returns the value of the i-th parameter, the index is the
+// order of the parameter in pattern, returns empty string if the index
+// is out of range.
+func (p *ParamList) ByIndex(i int) string {
+	if i < 0 || i >= len(p.values) {
+		return ""
+	}
+
+	return p.values[i]
+}
+
+// ByName returns the value of the parameter which named as the given
+// name, returns empty string if the parameter does not exist.
+func (p *ParamList) ByName(name string) string {
+	for i := range p.names {
+		if p.names[i] == name {
+			return p.values[i]
+		}
+	}
+
+	return ""
+}
+
+type paramListKey struct{}
+
+var contextParamListKey paramListKey
+
+// ParamListOf returns the ParamList of the request, returns nil if
+// the router does not pass parameters via ParamList.
+func ParamListOf(r *http.Request) *ParamList {
+	if list, ok := r.Context().Value(contextParamListKey).(*ParamList); ok {
+		return list
+	}
+
+	return nil
+}
+
+// Param returns the value of the parameter which named as the given
+// name, it works with both map and ParamList.
+func Param(r *http.Request, name string) string {
+	if list := ParamListOf(r); list != nil {
+		return list.ByName(name)
+	}
+
+	return Params(r)[name]
+}
+
+type pooledKey struct{}
+
+var contextPooledKey pooledKey
+
+// PooledContext reports whether the request context is pooled by the
+// router, that is, the router's PooledParams is enabled, the context
+// is reused across requests, so that it MUST NOT be accessed by the
+// goroutines that outlive the handler, even if the route has no
+// parameters.
+func PooledContext(r *http.Request) bool {
+	pooled, _ := r.Context().Value(contextPooledKey).(bool)
+	return pooled
+}
+
+// paramsContext is a pooled context that carries the parameters and
+// the matched route, it avoids allocating context on every request.
+type paramsContext struct {
+	context.Context
+
+	params ParamList
+
+	route *Route
+
+	// indicates whether the context is acquired from the pool.
+	pooled bool
+
+	// indicates whether the middleware chain of route is guarded, and
+	// whether the request was aborted, see Abort.
+	abortable bool
+	aborted   bool
+}
+
+func (c *paramsContext) Value(key interface{}) interface{} {
+	switch key {
+	case contextPooledKey:
+		return c.pooled
+	case contextParamListKey:
+		if c.params.names != nil {
+			return &c.params
+		}
+		return nil
+	case contextRouteKey:
+		return c.route
+	case contextAbortKey:
+		if c.abortable {
+			return &c.aborted
+		}
+	}
+
+	return c.Context.Value(key)
+}
+
+var paramsContextPool = sync.Pool{
+	New: func() interface{} {
+		return &paramsContext{params: ParamList{values: make([]string, 0, 8)}, pooled: true}
+	},
+}
+
+func acquireParamsContext() *paramsContext {
+	return paramsContextPool.Get().(*paramsContext)
+}
+
+func releaseParamsContext(c *paramsContext) {
+	c.Context = nil
+	c.route = nil
+	c.abortable = false
+	c.aborted = false
+	c.params.names = nil
+	c.params.values = c.params.values[:0]
+	paramsContextPool.Put(c)
+}
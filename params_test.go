@@ -0,0 +1,112 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParamList(t *testing.T) {
+	list := &ParamList{names: []string{"year", "month"}, values: []string{"2017", "09"}}
+	if list.Len() != 2 {
+		t.Errorf("expect length to be %d, but got %d", 2, list.Len())
+	}
+	if list.ByIndex(1) != "09" {
+		t.Errorf("expect the value of index 1 to be %q, but got %q", "09", list.ByIndex(1))
+	}
+	if list.ByIndex(2) != "" {
+		t.Errorf("expect the value of index 2 to be empty, but got %q", list.ByIndex(2))
+	}
+	if list.ByName("year") != "2017" {
+		t.Errorf("expect the value of year to be %q, but got %q", "2017", list.ByName("year"))
+	}
+	if list.ByName("day") != "" {
+		t.Errorf("expect the value of day to be empty, but got %q", list.ByName("day"))
+	}
+}
+
+func TestRouter_PooledParams(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.PooledParams = true
+		var name, title string
+		var params map[string]string
+		var list *ParamList
+		var route *Route
+		r.Get(`/users/<name>/posts/<title>`, func(w http.ResponseWriter, req *http.Request) {
+			name = Param(req, "name")
+			list = ParamListOf(req)
+			title = list.ByIndex(1)
+			params = Params(req)
+			route = routeFromRequest(req)
+		}).Meta("scope", "admin")
+		r.Get(`/users`, func(w http.ResponseWriter, req *http.Request) {
+			list = ParamListOf(req)
+			params = Params(req)
+		})
+		r.Prepare()
+
+		req := httptest.NewRequest(http.MethodGet, "/users/foo/posts/hello", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if name != "foo" || title != "hello" {
+			t.Errorf("engine %d: expect name and title to be %q and %q, but got %q and %q", engine, "foo", "hello", name, title)
+		}
+		expect := map[string]string{"name": "foo", "title": "hello"}
+		if !reflect.DeepEqual(params, expect) {
+			t.Errorf("engine %d: expect params to be %v, but got %v", engine, expect, params)
+		}
+		if route == nil || route.Pattern() != `/users/<name>/posts/<title>` {
+			t.Errorf("engine %d: expect route in context, but got %v", engine, route)
+		}
+		if list.Len() != 0 {
+			t.Errorf("engine %d: expect the list to be released after handling, but got %v", engine, list)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/users", nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if list != nil || params != nil {
+			t.Errorf("engine %d: expect no params, but got %v and %v", engine, list, params)
+		}
+	}
+}
+
+func TestRouter_PooledParams2(t *testing.T) {
+	r := NewWithEngine(TreeEngine)
+	r.PooledParams = true
+	r.Get(`/users/<name>`, func(w http.ResponseWriter, req *http.Request) {
+		Param(req, "name")
+	})
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+	w := httptest.NewRecorder()
+	allocs := testing.AllocsPerRun(100, func() {
+		r.ServeHTTP(w, req)
+	})
+	// the only allocation is the shallow copy of request.
+	if allocs > 1 {
+		t.Errorf("expect at most 1 allocation per request, but got %v", allocs)
+	}
+}
+
+func TestPooledContext(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		var actual bool
+		r := New()
+		r.PooledParams = pooled
+		r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+			actual = PooledContext(req)
+		})
+		r.Prepare()
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if actual != pooled {
+			t.Errorf("expect PooledContext to be %t, but got %t", pooled, actual)
+		}
+	}
+}
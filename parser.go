@@ -7,6 +7,7 @@ package fastrouter
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // ParserInterface defines a Parse method for parsing pattern.
@@ -29,7 +30,11 @@ type ParserInterface interface {
 	Parse(pattern string) (regexp string, params []string, hasTrailingSlashes bool, err error)
 }
 
-var defaultParserRegexp = regexp.MustCompile(`<([^/:]+)(:([^/]+))?>`)
+var defaultParserRegexp = regexp.MustCompile(`<([^/:]*)(:([^/]+))?>`)
+
+// braceParserRegexp allows one level of nested braces in the regexp
+// of parameter, such as `{year:\d{4}}`.
+var braceParserRegexp = regexp.MustCompile(`\{([^/:{}]*)(:((?:[^/{}]|\{[^/{}]*\})+))?\}`)
 
 // NewParser returns a new parser via NewParserWithReg with the
 // defaultParserRegexp.
@@ -37,6 +42,16 @@ func NewParser() Parser {
 	return NewParserWithReg(defaultParserRegexp)
 }
 
+// NewBraceParser returns a new parser via NewParserWithReg with
+// the braceParserRegexp, it supports chi/gorilla-style patterns,
+// the named parameters MUST be one of '{name}' and '{name:regexp}':
+//
+//	`/users/{name}`
+//	`/posts/{year:\d{4}}/{month:\d{2}}/{title}`
+func NewBraceParser() Parser {
+	return NewParserWithReg(braceParserRegexp)
+}
+
 // NewParserWithReg returns a new parser with the given regexp.
 func NewParserWithReg(reg *regexp.Regexp) Parser {
 	return Parser{reg: reg}
@@ -69,11 +84,23 @@ type Parser struct {
 //     `/users/<name>/posts`
 //     `/posts/<year:\d{4}>/<month:\d{2}>/<title>`
 //     ...
-// Named parameter MUST be one of '<name>' and '<name:regexp>'.
+// Named parameter MUST be one of '<name>', '<name:regexp>' and '<*name>'.
 //     `<name>`        // will be converted to `([^/]+)`
 //
 //     `<name:regexp>` // will be converted to `(regexp)`
 //
+//     `<*name>`       // will be converted to `(.*)`, it matches the rest of path
+//
+// The anonymous parameter '<:regexp>' will be converted to `(?:regexp)`,
+// it matches the path but produces no parameter, so that the handler
+// which does not need the value saves the allocation of parameters.
+// The regexp MAY be a constraint without validation func and
+// transformer, and the route with anonymous parameter can not be
+// reversed into URL.
+//
+// The catch-all parameter '<*name>' MUST be at the end of pattern, and
+// the pattern with catch-all parameter has no optional trailing slashes.
+//
 // Examples:
 //     | Pattern                                     | Error   | Regexp                             | hasTrailingSlashes | Params                               |
 //     |:--------------------------------------------|:--------|:-----------------------------------|:-------------------|:-------------------------------------|
@@ -86,7 +113,21 @@ type Parser struct {
 //     | `/users/<name:\w+>/posts/`                  | nil     | `/users/(\w+)/posts/?`             | YES                | `[]string{"name"}`                   |
 //     | `/orders/<id:\d+>`                          | nil     | `/orders/(\d+)/?`                  | NO                 | `[]string{"id"}`                     |
 //     | `/posts/<year:\d{4}>/<month:\d{2}>/<title>` | nil     | `/posts/(\d{4})/(\d{2})/([^/]+)/?` | NO                 | `[]string{"year", "month", "title"}` |
+//     | `/files/<*filepath>`                        | nil     | `/files/(.*)`                      | NO                 | `[]string{"filepath"}`               |
+//     | `/files/<*filepath>/edit`                   | non-nil |                                    |                    |                                      |
+//     | `/files/<:.+>`                              | nil     | `/files/(?:.+)/?`                  | NO                 |                                      |
+//     | `/files/<>`                                 | non-nil |                                    |                    |                                      |
 func (p Parser) Parse(pattern string) (regexp string, params []string, hasTrailingSlashes bool, err error) {
+	regexp, params, _, _, hasTrailingSlashes, err = p.parse(pattern, nil)
+	return
+}
+
+// parse parses pattern as same as Parse, the parameter regexp which
+// equal to the name of constraint will be replaced with the
+// constraint, the validators and transformers contains the validation
+// funcs and transformers of parameters in order, they are nil if there
+// is no validation func or transformer.
+func (p Parser) parse(pattern string, constraints map[string]*constraint) (regexp string, params []string, validators []func(string) bool, transformers []func(string) string, hasTrailingSlashes bool, err error) {
 	if pattern == "" || pattern[0] != '/' {
 		err = fmt.Errorf(`the pattern MUST begin with '/' in pattern %q`, pattern)
 		return
@@ -98,18 +139,72 @@ func (p Parser) Parse(pattern string) (regexp string, params []string, hasTraili
 	}
 
 	// fetch named parameters.
+	catchAll := false
 	matches := p.reg.FindAllStringSubmatch(pattern, -1)
 	if matches != nil {
-		for _, match := range matches {
-			params = append(params, match[1])
+		for i, match := range matches {
+			name := match[1]
+			if name == "" {
+				if match[3] == "" {
+					err = fmt.Errorf(`the anonymous parameter MUST be in form of '<:regexp>' in pattern %q`, pattern)
+					return
+				}
+				if c, ok := constraints[match[3]]; ok && (c.fn != nil || c.transform != nil) {
+					err = fmt.Errorf(`the anonymous parameter does not support the constraint %q which has validation func or transformer in pattern %q`, match[3], pattern)
+					return
+				}
+				continue
+			}
+			if name[0] == '*' {
+				name = name[1:]
+				if name == "" || match[3] != "" {
+					err = fmt.Errorf(`the catch-all parameter MUST be in form of '<*name>' in pattern %q`, pattern)
+					return
+				}
+				if i != len(matches)-1 || !strings.HasSuffix(pattern, match[0]) || hasTrailingSlashes {
+					err = fmt.Errorf(`the catch-all parameter MUST be at the end of pattern %q`, pattern)
+					return
+				}
+				catchAll = true
+			}
+			params = append(params, name)
+			if c, ok := constraints[match[3]]; ok && c.fn != nil {
+				if validators == nil {
+					validators = make([]func(string) bool, len(matches))
+				}
+				validators[len(params)-1] = c.fn
+			}
+			if c, ok := constraints[match[3]]; ok && c.transform != nil {
+				if transformers == nil {
+					transformers = make([]func(string) string, len(matches))
+				}
+				transformers[len(params)-1] = c.transform
+			}
+		}
+		// trims the slots of anonymous parameters.
+		if validators != nil {
+			validators = validators[:len(params)]
+		}
+		if transformers != nil {
+			transformers = transformers[:len(params)]
 		}
 
 		// convert pattern into a regexp string.
 		i := -1
 		regexp = p.reg.ReplaceAllStringFunc(pattern, func(any string) string {
 			i++
+			group := "("
+			if matches[i][1] == "" {
+				group = "(?:"
+			}
+			if c, ok := constraints[matches[i][3]]; ok {
+				return group + c.reg + ")"
+			}
 			if matches[i][3] != "" {
-				return "(" + matches[i][3] + ")"
+				return group + matches[i][3] + ")"
+			}
+			if matches[i][1][0] == '*' {
+				return `(.*)`
 			}
 
 			return `([^/]+)`
@@ -118,7 +213,81 @@ func (p Parser) Parse(pattern string) (regexp string, params []string, hasTraili
 		regexp = pattern
 	}
 
-	regexp += "/?"
+	if !catchAll {
+		regexp += "/?"
+	}
+
+	return
+}
+
+// BuilderInterface defines a Build method for reverse routing, a
+// parser MUST implements it, otherwise the routes parsed by it can
+// not be reversed into URLs.
+type BuilderInterface interface {
+	// Build fills the named parameters of pattern with the given
+	// params, and returns the path.
+	//
+	// Returns non-nil error, if any parameter is missing or invalid.
+	Build(pattern string, params map[string]string) (path string, err error)
+}
+
+// Build implements BuilderInterface's Build method.
+//
+// The parameter value MUST be matched by the parameter's regexp, and
+// the default parameter (without regexp) MUST NOT be empty or contains
+// '/'.
+func (p Parser) Build(pattern string, params map[string]string) (path string, err error) {
+	return p.build(pattern, params, nil)
+}
+
+// build builds path as same as Build, the parameter value MUST
+// satisfy the constraint if the parameter references a constraint.
+func (p Parser) build(pattern string, params map[string]string, constraints map[string]*constraint) (path string, err error) {
+	matches := p.reg.FindAllStringSubmatchIndex(pattern, -1)
+	last := 0
+	for _, match := range matches {
+		name := pattern[match[2]:match[3]]
+		if name == "" {
+			return "", fmt.Errorf("the anonymous parameter can not be built in pattern %q", pattern)
+		}
+		catchAll := name[0] == '*'
+		if catchAll {
+			name = name[1:]
+		}
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("the parameter %q is missing in pattern %q", name, pattern)
+		}
+
+		switch {
+		case catchAll:
+			// the catch-all parameter accepts any value.
+		case match[6] >= 0:
+			expr := pattern[match[6]:match[7]]
+			if c, ok := constraints[expr]; ok {
+				if c.fn != nil {
+					if value == "" || strings.Contains(value, "/") || !c.fn(value) {
+						return "", fmt.Errorf("the parameter %q does not satisfy the constraint %q in pattern %q", name, expr, pattern)
+					}
+					break
+				}
+				expr = c.reg
+			}
+			reg, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return "", err
+			}
+			if !reg.MatchString(value) {
+				return "", fmt.Errorf("the parameter %q does not match %q in pattern %q", name, reg, pattern)
+			}
+		case value == "" || strings.Contains(value, "/"):
+			return "", fmt.Errorf("the parameter %q MUST NOT be empty or contains '/' in pattern %q", name, pattern)
+		}
+
+		path += pattern[last:match[0]] + value
+		last = match[1]
+	}
+	path += pattern[last:]
 
 	return
 }
@@ -6,6 +6,8 @@ package fastrouter
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -45,6 +47,29 @@ func TestPatternParser_Parse(t *testing.T) {
 			false,
 			nil,
 		},
+		`/files/<*filepath>`: {`/files/(.*)`, []string{"filepath"}, false, nil},
+		`/files/<*filepath>/edit`: {"",
+			[]string{},
+			false,
+			fmt.Errorf(`the catch-all parameter MUST be at the end of pattern %q`, "/files/<*filepath>/edit"),
+		},
+		`/files/<*filepath>/`: {"",
+			[]string{},
+			true,
+			fmt.Errorf(`the catch-all parameter MUST be at the end of pattern %q`, "/files/<*filepath>"),
+		},
+		`/files/<:.+>`:                 {`/files/(?:.+)/?`, emptyParams, false, nil},
+		`/users/<id>/<:(?:edit|view)>`: {`/users/([^/]+)/(?:(?:edit|view))/?`, []string{"id"}, false, nil},
+		`/files/<>`: {"",
+			[]string{},
+			false,
+			fmt.Errorf(`the anonymous parameter MUST be in form of '<:regexp>' in pattern %q`, "/files/<>"),
+		},
+		`/files/<*filepath:.+>`: {"",
+			[]string{},
+			false,
+			fmt.Errorf(`the catch-all parameter MUST be in form of '<*name>' in pattern %q`, "/files/<*filepath:.+>"),
+		},
 	}
 
 	parser := NewParser()
@@ -64,3 +89,111 @@ func TestPatternParser_Parse(t *testing.T) {
 		}
 	}
 }
+
+func TestParser_AnonymousParameter(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%v", Params(req))
+	}
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Constraint("int", `\d+`)
+		r.Constraint("even", isEven)
+		r.Get("/files/<:.+>", handler)
+		r.Get("/users/<id>/<:(?:edit|view)>", handler)
+		r.Get("/posts/<:int>", handler)
+		if _, err := r.HandleE(http.MethodGet, "/numbers/<:even>", handler); err == nil {
+			t.Errorf("engine %d: expect the anonymous parameter with validation func to be rejected", engine)
+		}
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/files/css/app.css", http.StatusOK, "map[]"},
+			{"/users/1/edit", http.StatusOK, "map[id:1]"},
+			{"/users/1/delete", http.StatusNotFound, ""},
+			{"/posts/2", http.StatusOK, "map[]"},
+			{"/posts/two", http.StatusNotFound, ""},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if w.Code != test.code {
+				t.Errorf("engine %d: expect status code of %q to be %d, but got %d", engine, test.path, test.code, w.Code)
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("engine %d: expect body of %q to be %q, but got %q", engine, test.path, test.body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestParser_Build(t *testing.T) {
+	parser := NewParser()
+	tests := []struct {
+		pattern string
+		params  map[string]string
+		path    string
+		hasErr  bool
+	}{
+		{"/", nil, "/", false},
+		{"/users/", nil, "/users/", false},
+		{"/users/<id>", map[string]string{"id": "1"}, "/users/1", false},
+		{"/users/<id>", nil, "", true},
+		{"/users/<id>", map[string]string{"id": ""}, "", true},
+		{`/users/<id:\d+>`, map[string]string{"id": "foo"}, "", true},
+		{`/posts/<year:\d{4}>/<month:\d{2}>/<title>`, map[string]string{"year": "2017", "month": "09", "title": "hello"}, "/posts/2017/09/hello", false},
+		{`/files/<*filepath>`, map[string]string{"filepath": "css/app.css"}, "/files/css/app.css", false},
+		{`/files/<*filepath>`, nil, "", true},
+		{`/files/<:.+>`, nil, "", true},
+	}
+	for _, test := range tests {
+		path, err := parser.Build(test.pattern, test.params)
+		if path != test.path {
+			t.Errorf("expect the path of pattern %q to be %q, but got %q", test.pattern, test.path, path)
+		}
+		if (err != nil) != test.hasErr {
+			t.Errorf("expect the err of pattern %q to be non-nil: %v, but got %v", test.pattern, test.hasErr, err)
+		}
+	}
+}
+
+func TestBraceParser_Parse(t *testing.T) {
+	emptyParams := []string{}
+	testPatterns := map[string]testPattern{
+		"/":               {"//?", emptyParams, false, nil},
+		`/users`:          {"/users/?", emptyParams, false, nil},
+		`/users/{id}/`:    {"/users/([^/]+)/?", []string{"id"}, true, nil},
+		`/users/{id:\d+}`: {`/users/(\d+)/?`, []string{"id"}, false, nil},
+		`/posts/{year:\d{4}}/{month:\d{2}}/{title}`: {
+			`/posts/(\d{4})/(\d{2})/([^/]+)/?`,
+			[]string{"year", "month", "title"},
+			false,
+			nil,
+		},
+	}
+
+	parser := NewBraceParser()
+	for pattern, v := range testPatterns {
+		reg, params, hasTrailingSlashes, err := parser.Parse(pattern)
+		if v.reg != reg {
+			t.Errorf("expect the reg of pattern %q to be %q, but got %q", pattern, v.reg, reg)
+		}
+		if !compareSlice(v.params, params) {
+			t.Errorf("expect the params of pattern %q to be %v, but got %v", pattern, v.params, params)
+		}
+		if v.hasTrailingSlashes != hasTrailingSlashes {
+			t.Errorf("expect the hasTrailingSlashes of pattern %q to be %v, but got %v", pattern, v.hasTrailingSlashes, hasTrailingSlashes)
+		}
+		if !reflect.DeepEqual(v.err, err) {
+			t.Errorf("expect the err of pattern %q to be %v, but got %v", pattern, v.err, err)
+		}
+	}
+
+	path, err := parser.Build(`/posts/{year:\d{4}}/{title}`, map[string]string{"year": "2017", "title": "hello"})
+	if err != nil || path != "/posts/2017/hello" {
+		t.Errorf("expect path to be %q, but got %q(%v)", "/posts/2017/hello", path, err)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net"
+	"net/http"
+)
+
+// ports returns the Ports of the nearest router, nil if all ports are
+// served.
+func (r *Router) ports() []string {
+	for router := r; router != nil; router = router.parent {
+		if len(router.Ports) > 0 {
+			return router.Ports
+		}
+	}
+
+	return nil
+}
+
+// servesPort reports whether the router serves the local port which
+// the request was received on.
+func (r *Router) servesPort(req *http.Request) bool {
+	ports := r.ports()
+	if len(ports) == 0 {
+		return true
+	}
+
+	port := localPort(req)
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+// localPort returns the local port which the request was received on,
+// returns empty string if the local address is unavailable, such as
+// the request is not served by http.Server. The Host header is never
+// consulted, since it is controlled by client.
+func localPort(req *http.Request) string {
+	addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return ""
+	}
+
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+
+	return port
+}
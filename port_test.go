@@ -0,0 +1,63 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRouter_Ports(t *testing.T) {
+	r := New()
+	r.Get("/", helloHandler("public"))
+	admin := r.Group("admin")
+	admin.Ports = []string{"9000"}
+	admin.Get("/users", helloHandler("users"))
+	public := admin.Group("public")
+	public.Ports = []string{"8080", "9000"}
+	public.Get("/", helloHandler("admin public"))
+	r.Prepare()
+
+	tests := []struct {
+		port string
+		path string
+		code int
+	}{
+		{"8080", "/", http.StatusOK},
+		{"9000", "/", http.StatusOK},
+		{"9000", "/admin/users", http.StatusOK},
+		{"8080", "/admin/users", http.StatusNotFound},
+		{"8080", "/admin/public", http.StatusOK},
+		{"", "/admin/users", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.port != "" {
+			port, _ := strconv.Atoi(test.port)
+			addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, addr))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %q on port %q to be %d, but got %d", test.path, test.port, test.code, w.Code)
+		}
+	}
+
+	// the Host header is controlled by client.
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:9000/admin/users", nil)
+	if port := localPort(req); port != "" {
+		t.Errorf("expect port not to fall back to the port of Host, but got %q", port)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect status code of spoofed Host to be %d, but got %d", http.StatusNotFound, w.Code)
+	}
+}
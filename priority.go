@@ -0,0 +1,81 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Priority sets the matching priority of route, it is zero by default.
+//
+// When a path is matched by multiple routes of the same request method
+// and group, the route wins in the following order, regardless of the
+// order of registration:
+//
+// 1. the route which has higher priority;
+//
+// 2. the static route which contains no parameter, either named or
+// anonymous, and no regular expression;
+//
+// 3. the route which static prefix is longer, such as "/users/<id>/posts"
+// beats "/<path:.+>";
+//
+// 4. the route without catch-all parameter;
+//
+// 5. the route which was registered earlier.
+//
+// Note that, the TreeEngine honors the priority, but among the routes
+// of the same priority, the routes in tree take precedence over its
+// fallback routes, and the static segments take precedence over the
+// parameters.
+//
+// Returns the route itself for chaining.
+func (route *Route) Priority(priority int) *Route {
+	route.priority = priority
+	return route
+}
+
+// staticPrefix returns the length of the static prefix of route, that
+// is, the regular expression before the first parameter.
+func (route *Route) staticPrefix() int {
+	if i := strings.IndexByte(route.reg, '('); i >= 0 {
+		return i
+	}
+
+	return len(route.reg)
+}
+
+// isStatic reports whether the route is static, that is, its regular
+// expression matches the literal path only, the anonymous parameters
+// produce no parameter names but they are not static.
+func (route *Route) isStatic() bool {
+	reg := strings.TrimSuffix(route.reg, "/?")
+	return regexp.QuoteMeta(reg) == reg
+}
+
+// sortRoutes returns a copy of routes sorted in order of precedence,
+// see Route.Priority.
+func sortRoutes(routes []*Route) []*Route {
+	sorted := make([]*Route, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		if staticA, staticB := a.isStatic(), b.isStatic(); staticA != staticB {
+			return staticA
+		}
+		if pa, pb := a.staticPrefix(), b.staticPrefix(); pa != pb {
+			return pa > pb
+		}
+		catchAllA, catchAllB := !strings.HasSuffix(a.reg, "/?"), !strings.HasSuffix(b.reg, "/?")
+		return !catchAllA && catchAllB
+	})
+
+	return sorted
+}
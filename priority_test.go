@@ -0,0 +1,97 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_Priority(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Get("/<*path>", helloHandler("catch-all"))
+		r.Get("/<page:.+>", helloHandler("page"))
+		r.Get("/users/<id:\\d+>", helloHandler("user"))
+		r.Get("/users/<name>", helloHandler("name"))
+		r.Get("/users/new", helloHandler("new"))
+		r.Get("/posts/<id:\\d+>", helloHandler("post"))
+		r.Get("/posts/<slug:[\\w-]+>", helloHandler("slug")).Priority(1)
+		r.Prepare()
+
+		tests := map[string]string{
+			"/users/new": "new",
+			"/users/1":   "user",
+			"/users/foo": "name",
+			"/posts/1":   "slug",
+			"/about":     "page",
+		}
+		if engine == TreeEngine {
+			// the routes in tree take precedence over the fallback routes.
+			tests["/users/1"] = "name"
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("engine %d: expect body of %q to be %q, but got %q", engine, path, body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRoute_PriorityTree(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Get("/users/<name>", helloHandler("name"))
+		r.Get("/users/<id:\\d+>", helloHandler("user")).Priority(1)
+		r.Get("/posts/new", helloHandler("new"))
+		r.Get("/posts/<slug>", helloHandler("slug")).Priority(1)
+		r.Get("/pages/<name>", helloHandler("page")).Priority(-1)
+		r.Get("/pages/<id:\\d+>", helloHandler("page id"))
+		r.Prepare()
+
+		tests := map[string]string{
+			"/users/1":   "user",
+			"/users/foo": "name",
+			"/posts/new": "slug",
+			"/pages/1":   "page id",
+			"/pages/foo": "page",
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("engine %d: expect body of %q to be %q, but got %q", engine, path, body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRoute_PriorityAnonymous(t *testing.T) {
+	for _, engine := range []int8{RegexpEngine, TreeEngine} {
+		r := NewWithEngine(engine)
+		r.Get("/files/<name>", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("name " + Param(req, "name")))
+		})
+		r.Get("/files/<:.+>", helloHandler("anonymous"))
+		r.Get("/files/index", helloHandler("index"))
+		r.Prepare()
+
+		tests := map[string]string{
+			"/files/index": "index",
+			"/files/foo":   "name foo",
+			"/files/a/b":   "anonymous",
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("engine %d: expect body of %q to be %q, but got %q", engine, path, body, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicInfo is the information of panic which is passed to the
+// RecoveryHandler.
+type PanicInfo struct {
+	// The recovered value, Value = recover().
+	Value interface{}
+
+	// The stack trace which was captured at recovery.
+	Stack []byte
+}
+
+// hasPanicHandler reports whether the router or its parents has either
+// PanicHandler or RecoveryHandler.
+func (r *Router) hasPanicHandler() bool {
+	for router := r; router != nil; router = router.parent {
+		if router.RecoveryHandler != nil || router.PanicHandler != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recoverPanic recovers from panic and handles it via the nearest
+// RecoveryHandler or PanicHandler, or the default recovery, it MUST be
+// called via defer.
+//
+// The http.ErrAbortHandler is propagated by the default recovery, so
+// that the server aborts the response as usual.
+func (r *Router) recoverPanic(w http.ResponseWriter, req *http.Request) {
+	rcv := recover()
+	if rcv == nil {
+		return
+	}
+
+	if !r.handlePanic(w, req, PanicInfo{Value: rcv, Stack: debug.Stack()}) {
+		panic(rcv)
+	}
+}
+
+// handlePanic handles the recovered panic via the nearest
+// RecoveryHandler or PanicHandler, or the default recovery, it reports
+// false if the panic ought to be propagated.
+func (r *Router) handlePanic(w http.ResponseWriter, req *http.Request, info PanicInfo) bool {
+	for router := r; router != nil; router = router.parent {
+		if router.RecoveryHandler != nil {
+			router.RecoveryHandler(w, req, info)
+			return true
+		}
+		if router.PanicHandler != nil {
+			router.PanicHandler(w, req, info.Value)
+			return true
+		}
+	}
+
+	root := r.root()
+	if root.DisableRecovery || info.Value == http.ErrAbortHandler {
+		return false
+	}
+
+	root.logPanic(req, info)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	return true
+}
+
+// logPanic logs the panic via the Logger if it is set, or emits a
+// structured record via the default slog.Logger with the request method
+// and path, the panic value and the stack trace.
+func (r *Router) logPanic(req *http.Request, info PanicInfo) {
+	if logger := r.root().Logger; logger != nil {
+		logger.Printf("fastrouter: panic serving %s %q: %v\n%s", req.Method, req.URL.Path, info.Value, info.Stack)
+		return
+	}
+
+	slog.ErrorContext(req.Context(), "fastrouter: panic serving request",
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Any("panic", info.Value),
+		slog.String("stack", string(info.Stack)),
+	)
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panicHandler(w http.ResponseWriter, req *http.Request) {
+	panic("boom")
+}
+
+func TestRouter_DefaultRecovery(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New()
+	r.Logger = log.New(buf, "", 0)
+	r.Get("/panic", panicHandler)
+	r.Get("/abort", func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusInternalServerError, w.Code)
+	}
+	if msg := buf.String(); !strings.Contains(msg, `panic serving GET "/panic": boom`) || !strings.Contains(msg, "recovery_test.go") {
+		t.Errorf("expect panic to be logged with stack trace, but got %q", msg)
+	}
+
+	func() {
+		defer func() {
+			if rcv := recover(); rcv != http.ErrAbortHandler {
+				t.Errorf("expect panic to be %v, but got %v", http.ErrAbortHandler, rcv)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	}()
+
+	r.DisableRecovery = true
+	func() {
+		defer func() {
+			if rcv := recover(); rcv != "boom" {
+				t.Errorf("expect panic to be %q, but got %v", "boom", rcv)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+}
+
+func TestRouter_DefaultRecoverySlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	// slog.SetDefault redirects the standard logger, restores it as well.
+	defer log.SetFlags(log.Flags())
+	defer log.SetOutput(log.Writer())
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+
+	r := New()
+	r.Get("/panic", panicHandler)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expect a JSON record, but got %q: %v", buf.String(), err)
+	}
+	expected := map[string]string{"level": "ERROR", "method": http.MethodGet, "path": "/panic", "panic": "boom"}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expect %s to be %q, but got %v", key, value, record[key])
+		}
+	}
+	if stack, _ := record["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("expect stack trace to be logged, but got %q", stack)
+	}
+}
+
+func TestRouter_RecoveryHandler(t *testing.T) {
+	var info PanicInfo
+	r := New()
+	r.RecoveryHandler = func(w http.ResponseWriter, req *http.Request, i PanicInfo) {
+		info = i
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		t.Error("expect RecoveryHandler to take precedence over PanicHandler")
+	}
+	r.Get("/panic", panicHandler)
+	api := r.Group("api")
+	api.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+	api.Get("/panic", panicHandler)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if info.Value != "boom" || !bytes.Contains(info.Stack, []byte("panicHandler")) {
+		t.Errorf("expect panic info with stack trace, but got %v and %s", info.Value, info.Stack)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusTeapot, w.Code)
+	}
+}
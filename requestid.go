@@ -0,0 +1,28 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+var contextRequestIDKey requestIDKey
+
+// WithRequestID returns a copy of the given context with the request
+// ID, it is usually called by the request ID middleware, such as
+// middleware.RequestID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextRequestIDKey, id)
+}
+
+// RequestID returns the request ID which is stored in the context of
+// request via WithRequestID, empty if there is no request ID.
+func RequestID(req *http.Request) string {
+	id, _ := req.Context().Value(contextRequestIDKey).(string)
+	return id
+}
@@ -0,0 +1,23 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if id := RequestID(req); id != "" {
+		t.Errorf("expect no request ID, but got %q", id)
+	}
+
+	req = req.WithContext(WithRequestID(req.Context(), "abc"))
+	if id := RequestID(req); id != "abc" {
+		t.Errorf("expect request ID to be %q, but got %q", "abc", id)
+	}
+}
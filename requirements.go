@@ -0,0 +1,51 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import "net/http"
+
+// RateLimitPolicyMeta is the metadata key for declaring the rate limit
+// policy of route, the value MUST be a string in the format of the
+// RateLimit-Policy header, such as "100;w=60".
+//
+// The policies of the routes of request path are advertised in the
+// RateLimit-Policy header of the automatic OPTIONS and 405 Method Not
+// Allowed responses, so that the clients can discover the requirements
+// programmatically.
+//
+//	r.Post("/login", login).Meta(fastrouter.RateLimitPolicyMeta, "5;w=60")
+const RateLimitPolicyMeta = "ratelimit-policy"
+
+// AuthChallengeMeta is the metadata key for declaring the auth scheme
+// of route, the value MUST be a string in the format of the challenge
+// of WWW-Authenticate header, such as `Bearer realm="api"`.
+//
+// The challenges of the routes of request path are advertised in the
+// WWW-Authenticate header of the automatic OPTIONS and 405 Method Not
+// Allowed responses, see RateLimitPolicyMeta.
+const AuthChallengeMeta = "auth-challenge"
+
+// requirementHeaders is the metadata keys of requirements and their
+// header names.
+var requirementHeaders = []struct{ meta, header string }{
+	{RateLimitPolicyMeta, "RateLimit-Policy"},
+	{AuthChallengeMeta, "WWW-Authenticate"},
+}
+
+// writeRequirements sets the RateLimit-Policy and WWW-Authenticate
+// headers with the metadata of the routes which match the given path
+// and methods, the duplicate values are omitted.
+func (r *Router) writeRequirements(header http.Header, path string, methods []string) {
+	seen := make(map[string]bool)
+	r.pathRoutes(path, methods, func(method string, route *Route) {
+		for _, v := range requirementHeaders {
+			value, ok := route.meta[v.meta].(string)
+			if ok && value != "" && !seen[v.header+value] {
+				seen[v.header+value] = true
+				header.Add(v.header, value)
+			}
+		}
+	})
+}
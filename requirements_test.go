@@ -0,0 +1,49 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouter_Requirements(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler).
+		Meta(RateLimitPolicyMeta, "100;w=60").
+		Meta(AuthChallengeMeta, `Bearer realm="api"`)
+	r.Post("/users", emptyHandler).
+		Meta(RateLimitPolicyMeta, "10;w=60").
+		Meta(AuthChallengeMeta, `Bearer realm="api"`)
+	r.Get("/public", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		method    string
+		path      string
+		code      int
+		policies  []string
+		challenge []string
+	}{
+		{http.MethodOptions, "/users", http.StatusOK, []string{"100;w=60", "10;w=60"}, []string{`Bearer realm="api"`}},
+		{http.MethodDelete, "/users", http.StatusMethodNotAllowed, []string{"100;w=60", "10;w=60"}, []string{`Bearer realm="api"`}},
+		{http.MethodOptions, "/public", http.StatusOK, nil, nil},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %s to be %d, but got %d", test.method, test.path, test.code, w.Code)
+		}
+		if policies := w.Header().Values("RateLimit-Policy"); !reflect.DeepEqual(policies, test.policies) {
+			t.Errorf("expect policies of %s %s to be %v, but got %v", test.method, test.path, test.policies, policies)
+		}
+		if challenge := w.Header().Values("WWW-Authenticate"); !reflect.DeepEqual(challenge, test.challenge) {
+			t.Errorf("expect challenge of %s %s to be %v, but got %v", test.method, test.path, test.challenge, challenge)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// RequestRewriter rewrites the matched request, such as the URL path,
+// headers and context, it MUST return a non-nil request, usually the
+// given request itself or the one returned by req.WithContext.
+type RequestRewriter func(req *http.Request) *http.Request
+
+// Rewrite attaches a request rewriter to the router, the rewriter will
+// be applied to the requests of the routes which belong to the router
+// and its groups after matching and before the middleware and handler,
+// so that the legacy requests can be adapted without writing a full
+// middleware, for example:
+//
+//	legacy := r.Group("legacy")
+//	legacy.Rewrite(func(req *http.Request) *http.Request {
+//		query := req.URL.Query()
+//		query.Set("user_id", query.Get("uid"))
+//		req.URL.RawQuery = query.Encode()
+//		return req
+//	})
+//
+// The rewriters are applied in order, the ones of parent are applied
+// before the group's. Since the request has been matched, the rewritten
+// path does not affect the routing, and the parameters and route are
+// accessible via Params and MatchedPattern.
+func (r *Router) Rewrite(rewriter RequestRewriter) {
+	r.rewriters = append(r.rewriters, rewriter)
+}
+
+// Rewrite attaches a request rewriter to the route, see Router.Rewrite
+// for details, the rewriters of route are applied after the router's.
+//
+// Returns the route itself for chaining.
+func (route *Route) Rewrite(rewriter RequestRewriter) *Route {
+	route.rewriters = append(route.rewriters, rewriter)
+	return route
+}
+
+// collectRewriters returns the rewriters of router and its parents, in
+// order of application.
+func (r *Router) collectRewriters() []RequestRewriter {
+	if r.parent == nil {
+		return r.rewriters
+	}
+
+	rewriters := r.parent.collectRewriters()
+	if len(r.rewriters) == 0 {
+		return rewriters
+	}
+
+	return append(rewriters[:len(rewriters):len(rewriters)], r.rewriters...)
+}
+
+// rewrite applies the rewriters of route to the request.
+func (route *Route) rewrite(req *http.Request) *http.Request {
+	for _, rewriter := range route.finalRewriters {
+		req = rewriter(req)
+	}
+
+	return req
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter_Rewrite(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s,%s,%v,%s", req.URL.Query().Get("user_id"), strings.Join(req.Header.Values("X-Trace"), "|"), req.Context().Value(contextValueKey("tenant")), Params(req)["id"])
+	}
+	trace := func(value string) RequestRewriter {
+		return func(req *http.Request) *http.Request {
+			req.Header.Add("X-Trace", value)
+			return req
+		}
+	}
+
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Rewrite(trace("root"))
+		r.Get("/", handler)
+
+		legacy := r.Group("legacy")
+		legacy.Rewrite(func(req *http.Request) *http.Request {
+			query := req.URL.Query()
+			query.Set("user_id", query.Get("uid"))
+			req.URL.RawQuery = query.Encode()
+			return req
+		})
+		legacy.Rewrite(trace("legacy"))
+		legacy.Get("/users/<id>", handler).Rewrite(func(req *http.Request) *http.Request {
+			return req.WithContext(context.WithValue(req.Context(), contextValueKey("tenant"), "legacy"))
+		})
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{"/", ",root,<nil>,"},
+			{"/legacy/users/1?uid=2", "2,root|legacy,legacy,1"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.ServeHTTP(w, req)
+			if w.Body.String() != test.body {
+				t.Errorf("pooled %t: expect body of %q to be %q, but got %q", pooled, test.path, test.body, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// RouteDef is the definition of a route, it enables table-driven
+// route definitions, see Router.Register.
+type RouteDef struct {
+	// The request method.
+	Method string
+
+	// The pattern of route.
+	Pattern string
+
+	// The handler of route.
+	Handler http.HandlerFunc
+
+	// The name of route for reverse routing, empty means unnamed.
+	Name string
+
+	// The metadata of route, see Route.Meta.
+	Meta map[string]interface{}
+
+	// The middleware of route.
+	Middleware []Middleware
+}
+
+// Register registers the routes of the given definitions in order, and
+// returns the registered routes, for example:
+//
+//	r.Register([]fastrouter.RouteDef{
+//		{Method: http.MethodGet, Pattern: "/users", Handler: listUsers, Name: "users"},
+//		{Method: http.MethodPost, Pattern: "/users", Handler: createUser, Meta: map[string]interface{}{"scope": "admin"}},
+//	})
+//
+// Causes a panic if any definition is invalid, see RegisterE.
+func (r *Router) Register(defs []RouteDef) []*Route {
+	routes, err := r.RegisterE(defs)
+	if err != nil {
+		panic(err)
+	}
+
+	return routes
+}
+
+// RegisterE registers the routes as same as Register, except that it
+// returns a *RouteError instead of panicking, the underlying error is
+// one of the errors of HandleE, or ErrDuplicateName if the name is
+// taken. The names are checked before registering, but the routes
+// which are registered before a parsing error are kept.
+func (r *Router) RegisterE(defs []RouteDef) ([]*Route, error) {
+	names := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		if def.Name == "" {
+			continue
+		}
+		if _, ok := r.namedRoute(def.Name); ok || names[def.Name] {
+			return nil, &RouteError{Method: def.Method, Pattern: def.Pattern, Prefix: r.fullPrefix(), Err: ErrDuplicateName}
+		}
+		names[def.Name] = true
+	}
+
+	routes := make([]*Route, 0, len(defs))
+	for _, def := range defs {
+		route, err := r.HandleE(def.Method, def.Pattern, def.Handler, def.Middleware...)
+		if err != nil {
+			return routes, err
+		}
+		if def.Name != "" {
+			route.Name(def.Name)
+		}
+		for key, value := range def.Meta {
+			route.Meta(key, value)
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_Register(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %v %v", MatchedPattern(req), RouteMeta(req)["scope"], req.Context().Value(contextValueKey("mw")))
+	}
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextValueKey("mw"), "on")))
+		})
+	}
+
+	r := New()
+	api := r.Group("api")
+	routes := api.Register([]RouteDef{
+		{Method: http.MethodGet, Pattern: "/users", Handler: handler, Name: "users"},
+		{Method: http.MethodPost, Pattern: "/users", Handler: handler, Meta: map[string]interface{}{"scope": "admin"}, Middleware: []Middleware{mw}},
+	})
+	r.Prepare()
+
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, but got %d", len(routes))
+	}
+	if u, err := r.URL("users"); err != nil || u != "/api/users" {
+		t.Errorf("expect URL of users to be %q, but got %q, %v", "/api/users", u, err)
+	}
+
+	tests := []struct {
+		method string
+		body   string
+	}{
+		{http.MethodGet, "/api/users <nil> <nil>"},
+		{http.MethodPost, "/api/users admin on"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, "/api/users", nil))
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %s to be %q, but got %q", test.method, test.body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_RegisterE(t *testing.T) {
+	r := New()
+	r.Get("/", emptyHandler).Name("home")
+
+	tests := []struct {
+		defs     []RouteDef
+		expected error
+		routes   int
+	}{
+		{[]RouteDef{{Method: http.MethodGet, Pattern: "/a", Name: "home"}}, ErrDuplicateName, 0},
+		{[]RouteDef{{Method: http.MethodGet, Pattern: "/a", Name: "a"}, {Method: http.MethodGet, Pattern: "/b", Name: "a"}}, ErrDuplicateName, 0},
+		{[]RouteDef{{Method: http.MethodGet, Pattern: "/a"}, {Method: http.MethodGet, Pattern: "/"}}, ErrDuplicateRoute, 1},
+		{[]RouteDef{{Method: http.MethodGet, Pattern: ""}}, ErrEmptyPattern, 0},
+	}
+	for i, test := range tests {
+		routes, err := r.RegisterE(test.defs)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%d: expect error to be %v, but got %v", i, test.expected, err)
+		}
+		var routeErr *RouteError
+		if !errors.As(err, &routeErr) {
+			t.Errorf("%d: expect a *RouteError, but got %T", i, err)
+		}
+		if len(routes) != test.routes {
+			t.Errorf("%d: expect %d registered routes, but got %d", i, test.routes, len(routes))
+		}
+	}
+}
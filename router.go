@@ -6,10 +6,20 @@ package fastrouter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"html"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Trailing slashes policies.
@@ -33,40 +43,180 @@ type ParamsKey struct{}
 
 var contextParamsKey ParamsKey
 
+type routeKey struct{}
+
+var contextRouteKey routeKey
+
 // New returns a new Router with the default parser
-// via NewWithParser.
-func New() *Router {
-	return NewWithParser(NewParser())
+// via NewWithParser, and the given options, see Option.
+func New(options ...Option) *Router {
+	r := NewWithParser(NewParser())
+	r.options = options
+	return r
+}
+
+// NewWithEngine returns a new Router with the default parser
+// and the given matching engine.
+func NewWithEngine(engine int8) *Router {
+	r := New()
+	r.Engine = engine
+	return r
 }
 
 // NewWithParser returns a new Router with the given
 // parser.
 func NewWithParser(parser ParserInterface) *Router {
 	return &Router{
-		combinedRegexps:       make(map[string]*regexp.Regexp),
 		groups:                make(map[string]*Router),
+		names:                 make(map[string]*Route),
 		parser:                parser,
-		routes:                make(map[string][]*route),
+		routes:                make(map[string][]*Route),
 		TrailingSlashesPolicy: IgnoreTrailingSlashes,
 	}
 }
 
 // Router is an implementation of http.Handler for handling HTTP requests.
 type Router struct {
+	// the number of requests, it is only used by root router, it is
+	// the first field for 64-bit alignment of atomic operations.
+	requests uint64
+
+	// indicates whether the maintenance mode is enabled, it is only
+	// used by root router.
+	maintenance int32
+
+	// indicates whether the router is the admin group.
+	admin bool
+
+	// the lock for adding and removing routes after Prepare, it is
+	// only used by root router.
+	mu sync.RWMutex
+
 	// parent router.
 	parent *Router
 
+	// the router which the routes are registered to, it is only set
+	// for the inline router which created via With.
+	inline *Router
+
+	// group prefix.
+	prefix string
+
 	// Middleware.
+	//
+	// Deprecated: use Use or the WithMiddleware option instead, the
+	// field is still honored, see Option.
 	Middleware []Middleware
 
-	// mapping from request method to combined regular expression.
-	combinedRegexps map[string]*regexp.Regexp
+	// the options which are applied at Prepare time.
+	options []Option
+
+	// The local ports which the routes of router are served on, such
+	// as "9000", it allows to serve multiple ports from one router with
+	// per-port route subsets, for example, serving the admin group on
+	// ":9000" only, and the others on all ports.
+	//
+	// The requests to the other ports are handled as Not Found by the
+	// root router, the ports of group take precedence over its
+	// parent's, all ports are served if it is empty. The port is taken
+	// from the local address of http.Server, the request without it is
+	// not served, the Host header is never trusted.
+	Ports []string
+
+	// The client networks which the routes of router are served for,
+	// such as "10.0.0.0/8", it allows to expose the internal groups to
+	// the internal clients only. Since it is enforced before the
+	// middleware, such as middleware.RealIP, the client address is
+	// resolved from the Forwarded, X-Forwarded-For or X-Real-IP header
+	// of the requests which come from the TrustedProxies, the address
+	// which is resolved via WithClientIP outside the router takes
+	// precedence.
+	//
+	// The requests from the other networks are handled as Not Found by
+	// the root router, the networks of group take precedence over its
+	// parent's, all networks are served if it is empty. It MUST be set
+	// before Prepare, the invalid networks are reported by PrepareE.
+	Networks []string
+
+	// parsed networks.
+	networks []*net.IPNet
+
+	// The limit of request header, the request which header exceeds the
+	// limit is responded with 431 Request Header Fields Too Large, it
+	// allows the tenants of gateway to have their own limits, which are
+	// finer than the MaxHeaderBytes of http.Server.
+	//
+	// The limit of group takes precedence over its parent's, see
+	// HeaderLimit.
+	HeaderLimit *HeaderLimit
+
+	// Matched-route middleware, it runs once the route is matched,
+	// before the middleware chain, see MatchedMiddleware.
+	MatchedMiddleware []MatchedMiddleware
+
+	// mapping from request method to matcher, it is swapped atomically
+	// while adding and removing routes, see AddRoute.
+	matchers atomic.Value
 
 	// mapping from prefix to group router.
 	groups map[string]*Router
 
-	// mapping from request method to []route.
-	routes map[string][]*route
+	// the case-insensitive matchers and the mapping from lowercase
+	// prefix to group router, they are only prepared if the
+	// CaseInsensitive is enabled.
+	foldMatchers atomic.Value
+	foldGroups   map[string]*Router
+
+	// the groups which prefixes contain parameters, in order of
+	// registration.
+	paramGroups []*Router
+
+	// the parsing result of prefix.
+	prefixParsed parsedPattern
+
+	// indicates whether the group was created implicitly as an
+	// intermediate group of multi-segment prefix, see Group.
+	implicit bool
+
+	// the regexp, parameter names, validation funcs and transformers
+	// of the prefix, nil if the prefix contains no parameter.
+	prefixReg          *regexp.Regexp
+	prefixParams       []string
+	prefixValidators   []func(string) bool
+	prefixTransformers []func(string) string
+
+	// mapping from name to named route, it is only used by root router.
+	names map[string]*Route
+
+	// mapping from the type of parser and pattern to the restored
+	// parsing result, it is only used by root router, see parsedKey.
+	parsed map[string]parsedPattern
+
+	// mapping from name to parameter constraint, it is only used by
+	// root router.
+	constraints map[string]*constraint
+
+	// mapping from request method to []*Route.
+	routes map[string][]*Route
+
+	// the static values that will be injected into request context.
+	contextValues []contextValue
+
+	// the request rewriters, see Router.Rewrite.
+	rewriters []RequestRewriter
+
+	// the handler and middleware which is mounted via Mount, and the
+	// handler that chained with middleware.
+	mounted           http.Handler
+	mountedMiddleware []Middleware
+	finalMounted      http.Handler
+
+	// the catch-all route of group, see Fallback.
+	fallback *Route
+
+	// the handler of unmatched requests that chained with middleware,
+	// nil if the WrapErrorHandlers is disabled.
+	finalUnmatched http.Handler
 
 	// pattern parser.
 	parser ParserInterface
@@ -75,299 +225,1737 @@ type Router struct {
 	//
 	// The rcv contains panic information, rcv = recover().
 	//
-	// This options is only effective in root router.
+	// The handler of group takes precedence over its parent's.
+	//
+	// Deprecated: use the WithPanicHandler option instead, the field is
+	// still honored, see Option.
 	PanicHandler func(w http.ResponseWriter, req *http.Request, rcv interface{})
 
+	// The handler for handling panic with the stack trace which was
+	// captured at recovery, see PanicInfo.
+	//
+	// It takes precedence over the PanicHandler of the same router, and
+	// the handler of group takes precedence over its parent's.
+	RecoveryHandler func(w http.ResponseWriter, req *http.Request, info PanicInfo)
+
+	// Indicates whether to disable the default recovery, which logs the
+	// panic with stack trace via Logger, or via the default slog.Logger
+	// if the Logger is not set, and responds with 500 Internal Server
+	// Error, if there is neither PanicHandler nor RecoveryHandler.
+	// The panic is propagated to the server if it is disabled.
+	//
+	// This options is only effective in root router.
+	DisableRecovery bool
+
 	// The handler for handling OPTIONS request.
 	//
-	// The methods contains all allowed methods of the request path.
+	// The methods contains all allowed methods of the request path, it
+	// is nil if the request path is unknown, that is, no route matches
+	// the path.
 	//
 	// This options is only effective in root router.
+	//
+	// Deprecated: use the WithOptionsHandler option instead, the field
+	// is still honored, see Option.
 	OptionsHandler func(w http.ResponseWriter, req *http.Request, methods []string)
 
+	// The static headers of the automatic OPTIONS responses, it is
+	// not used if the OptionsHandler is set.
+	//
+	// The headers of group take precedence over its parent's.
+	OptionsHeader http.Header
+
+	// The Access-Control-Max-Age of the automatic OPTIONS responses,
+	// it allows browsers to cache preflight requests, it is not used
+	// if the OptionsHandler is set.
+	//
+	// The max age of group takes precedence over its parent's.
+	OptionsMaxAge time.Duration
+
+	// The static body of the automatic OPTIONS responses, it is not
+	// used if the OptionsHandler is set.
+	//
+	// The body of group takes precedence over its parent's.
+	OptionsBody []byte
+
+	// Indicates whether to handle the OPTIONS request to an unknown
+	// path as Not Found, rather than responding with an empty Allow
+	// header, it takes precedence over the OptionsHandler.
+	//
+	// This options is only effective in root router.
+	OptionsNotFound bool
+
+	// Indicates whether to apply the middleware of router and its
+	// parents to the Not Found, Method Not Allowed and OPTIONS
+	// responses, such as logging and CORS, these responses bypass the
+	// middleware by default.
+	//
+	// This options is only effective in root router.
+	WrapErrorHandlers bool
+
+	// LocaleResolver resolves the locale of request for reversing the
+	// localized routes via RequestURL, see Route.Alias. If it is nil,
+	// the locale of matched alias is used, so that the generated URLs
+	// are in the same language as the request path.
+	//
+	// This options is only effective in root router.
+	LocaleResolver func(req *http.Request) string
+
+	// Indicates whether to describe the matched routes in the body
+	// of automatic OPTIONS responses as JSON, it is not used if the
+	// OptionsHandler or OptionsBody is set, see Route.Description.
+	//
+	// This options is only effective in root router.
+	OptionsDescribe bool
+
+	// Indicates whether to disable the automatic HEAD, by default, the
+	// HEAD request falls back to the GET handler with response body
+	// suppressed if no HEAD route matched, and the GET routes are
+	// paired with HEAD in the Allow header, route information and
+	// admin statistics.
+	//
+	// This options is only effective in root router.
+	DisableAutoHead bool
+
 	// The handler for handling Method Not Allowed.
 	//
 	// The methods contains all allowed methods of the request path.
 	//
-	// This options is only effective in root router.
+	// The handler of group takes precedence over its parent's, by
+	// default, the response is rendered as JSON, HTML or plain text
+	// according to the Accept header.
+	//
+	// Deprecated: use the WithMethodNotAllowedHandler option instead,
+	// the field is still honored, see Option.
 	MethodNotAllowedHandler func(w http.ResponseWriter, req *http.Request, methods []string)
 
 	// The handler for handling Not Found.
 	//
-	// This options is only effective in root router.
+	// The handler of group takes precedence over its parent's, it
+	// handles the unmatched paths under the group prefix, so that the
+	// mounted sub-apps can fully own their URL space.
+	//
+	// Deprecated: use the WithNotFoundHandler option instead, the field
+	// is still honored, see Option.
 	NotFoundHandler http.Handler
 
-	// Trailing slashes policy:
-	//     IgnoreTrailingSlashes, by default
-	//     AppendTrailingSlashes
-	//     RemoveTrailingSlashes
-	//     StrictTrailingSlashes
-	//
-	// This options is only effective in root router.
-	TrailingSlashesPolicy int8
+	// The handler for handling the reserved routes which handler is
+	// nil, the reserved routes are responded with 501 Not Implemented
+	// by default.
+	//
+	// The handler of group takes precedence over its parent's.
+	NotImplementedHandler http.Handler
+
+	// Indicates whether to match the request path case-insensitively
+	// if no route matched, such as matching "/Users" to "/users".
+	//
+	// This options is only effective in root router, and MUST be set
+	// before Prepare.
+	CaseInsensitive bool
+
+	// Indicates whether to redirect the request which matched
+	// case-insensitively to the path in canonical casing, rather than
+	// handling it directly, it takes no effect if the CaseInsensitive
+	// is disabled.
+	//
+	// The canonical path is built from the route pattern, so that the
+	// parameter values are kept as they are.
+	//
+	// This options is only effective in root router.
+	RedirectFixedCase bool
+
+	// Indicates whether to match the request path against the RawPath
+	// of URL, that is, the original encoded path, so that the encoded
+	// slashes "%2F" in parameter values do not change routing, such as
+	// matching "/files/a%2Fb" against "/files/<name>".
+	//
+	// The RawPath is only used if it is a valid encoding of the path
+	// and differs from the default encoding, see url.URL.
+	//
+	// This options is only effective in root router.
+	UseRawPath bool
+
+	// Indicates whether to unescape the parameter values that matched
+	// against the RawPath, it takes no effect if the UseRawPath is
+	// disabled.
+	//
+	// This options is only effective in root router.
+	UnescapePathValues bool
+
+	// Indicates whether to redirect the request which path contains
+	// duplicate slashes or dot segments, such as "//users/./1/../2",
+	// to the cleaned path before matching, such as "/users/2", the
+	// trailing slashes are kept.
+	//
+	// This options is only effective in root router.
+	CleanPath bool
+
+	// The name of request header which declares the time budget of
+	// caller, such as "X-Request-Timeout", the request context is derived
+	// with the deadline of budget, so that downstream calls inherit the
+	// timeout of caller. Empty means the header is ignored.
+	//
+	// The value is either a duration, such as "1.5s" and "300ms", or an
+	// integer number of milliseconds, the invalid and non-positive
+	// values are ignored.
+	//
+	// This options is only effective in root router.
+	RequestTimeoutHeader string
+
+	// The maximum budget that is honored from RequestTimeoutHeader, the
+	// larger budgets are capped, zero means no limit.
+	//
+	// This options is only effective in root router.
+	MaxRequestTimeout time.Duration
+
+	// Indicates whether to reject the request which declared
+	// Content-Length exceeds the MaxBodySize of route with 413 Request
+	// Entity Too Large before the handler reads anything, rather than
+	// failing the reads of body.
+	//
+	// This options is only effective in root router.
+	StrictContentLength bool
+
+	// The request normalizations which are applied ahead of any other
+	// processing, nil means no normalization, see Normalization.
+	//
+	// This options is only effective in root router.
+	Normalization *Normalization
+
+	// Trailing slashes policy:
+	//     IgnoreTrailingSlashes, by default
+	//     AppendTrailingSlashes
+	//     RemoveTrailingSlashes
+	//     StrictTrailingSlashes
+	//
+	// This options is only effective in root router.
+	//
+	// Deprecated: use the WithTrailingSlashesPolicy option instead, the
+	// field is still honored, see Option.
+	TrailingSlashesPolicy int8
+
+	// The handler for emitting the redirects of trailing slashes policy,
+	// the url is the redirect target, and the code is 301 for GET
+	// request, and 308 for the others. It allows to add headers, render
+	// an HTML interstitial or log the redirects, by default, they are
+	// emitted via http.Redirect.
+	//
+	// The handler of group takes precedence over its parent's, see also
+	// Route.RedirectHandler.
+	RedirectHandler func(w http.ResponseWriter, req *http.Request, url string, code int)
+
+	// Matching engine:
+	//     RegexpEngine, by default
+	//     TreeEngine
+	//
+	// This options is only effective in root router, and MUST be set
+	// before Prepare.
+	Engine int8
+
+	// The provider for determining whether the feature flags of
+	// routes are enabled, the feature flags are ignored if it is nil.
+	//
+	// This options is only effective in root router.
+	FlagProvider FlagProvider
+
+	// The base path which the router is served under, such as "/myapp",
+	// it is usually used when the router is served behind a reverse
+	// proxy that strips the base path from request path.
+	//
+	// The base path will be prepended to the reversed URLs and the
+	// Location of redirects.
+	//
+	// This options is only effective in root router.
+	BasePath string
+
+	// Indicates whether to pass parameters via the pooled ParamList
+	// instead of map, it avoids allocating map and context on every
+	// request, see ParamList for details.
+	//
+	// This options is only effective in root router.
+	PooledParams bool
+
+	// Indicates whether the middleware can skip the downstream
+	// middleware and handler via Abort, each middleware of the routes
+	// is guarded for checking the aborted request, it costs nothing
+	// if disabled.
+	//
+	// This options is only effective in root router, and MUST be
+	// set before Prepare.
+	Abortable bool
+
+	// The IP addresses and CIDRs of trusted proxies, such as
+	// "10.0.0.0/8" and "127.0.0.1", the X-Forwarded-Prefix header
+	// sent by trusted proxies will be prepended to the Location of
+	// redirects and the URLs generated by RequestURL, in front of
+	// the BasePath, and the client address of Networks is resolved
+	// from the forwarding headers sent by them.
+	//
+	// This options is only effective in root router, and MUST be
+	// set before Prepare.
+	TrustedProxies []string
+
+	// parsed trusted proxies.
+	trustedProxies []*net.IPNet
+
+	// The logger, the standard logger is used if it is nil.
+	//
+	// This options is only effective in root router.
+	//
+	// Deprecated: use the WithLogger option instead, the field is still
+	// honored, see Option.
+	Logger Logger
+
+	// Indicates whether to log a concise summary of the route table
+	// at Prepare time, including the number of routes per method,
+	// groups, middleware and the validation warnings, such as the
+	// route conflicts.
+	//
+	// This options is only effective in root router.
+	LogSummary bool
+
+	// Indicates whether to treat the route conflicts as fatal, the
+	// PrepareE returns a *ConflictError if any conflict is detected,
+	// see Conflicts. By default, the conflicts are logged as warnings
+	// of the summary if LogSummary is enabled.
+	//
+	// This options is only effective in root router.
+	FatalConflicts bool
+
+	// Indicates whether to reject all the non-safe requests, that is,
+	// the requests which method is not one of GET, HEAD and OPTIONS,
+	// it is usually used for read replicas and static mirrors.
+	//
+	// The non-safe requests will be rejected with ReadOnlyStatusCode
+	// without matching routes.
+	//
+	// This options is only effective in root router.
+	ReadOnly bool
+
+	// The complete set of request methods that the service supports,
+	// such as GET, HEAD, POST and OPTIONS, the requests with the other
+	// methods are responded with 501 Not Implemented immediately without
+	// matching routes, all the methods are supported if it is empty.
+	//
+	// This options is only effective in root router.
+	AllowedMethods []string
+
+	// the set of AllowedMethods, nil if all methods are allowed.
+	allowedMethods map[string]bool
+
+	// Indicates whether to enable debug mode, the routing decision of
+	// each request is exposed in the TraceHeader of response, so that
+	// developers can see it directly from curl. It SHOULD NOT be enabled
+	// in production, since the routes are exposed.
+	//
+	// This options is only effective in root router.
+	Debug bool
+
+	// The status code for rejecting the non-safe requests in read-only
+	// mode, it MUST be one of http.StatusMethodNotAllowed (by default)
+	// and http.StatusForbidden.
+	//
+	// The Method Not Allowed response is handled as same as usual,
+	// except that the allowed methods only contains the safe methods.
+	//
+	// This options is only effective in root router.
+	ReadOnlyStatusCode int
+}
+
+// Prepare makes preparations before handling requests:
+//
+// 1. combines route's regular expressions;
+//
+// 2. chaining middleware;
+//
+// 3. logs a summary of the route table if LogSummary is enabled.
+//
+// Note that, router MUST makes preparations before handling request,
+// otherwise it can not works as expected.
+//
+// Causes a panic if preparing failed, see PrepareE.
+func (r *Router) Prepare() {
+	if err := r.PrepareE(); err != nil {
+		panic(err)
+	}
+}
+
+// PrepareE makes preparations as same as Prepare, except that it
+// returns an error instead of panicking, so that the misconfiguration
+// can be handled at startup, such as invalid TrustedProxies, the
+// route which regular expression is invalid and the route conflicts,
+// they are reported as *RouteError and *ConflictError respectively,
+// the latter wraps ErrConflict.
+func (r *Router) PrepareE() error {
+	r.reconcileOptions()
+
+	var err error
+	if r.trustedProxies, err = parseTrustedProxies(r.TrustedProxies); err != nil {
+		return err
+	}
+	if err = r.prepareNetworks(); err != nil {
+		return err
+	}
+
+	r.allowedMethods = nil
+	if len(r.AllowedMethods) > 0 {
+		r.allowedMethods = make(map[string]bool, len(r.AllowedMethods))
+		for _, method := range r.AllowedMethods {
+			r.allowedMethods[method] = true
+		}
+	}
+
+	if err = r.walk((*Route).compile); err != nil {
+		return err
+	}
+
+	r.prepare()
+
+	if r.FatalConflicts {
+		if conflicts := r.Conflicts(); len(conflicts) > 0 {
+			return &ConflictError{Conflicts: conflicts}
+		}
+	}
+
+	if r.LogSummary {
+		r.logSummary()
+	}
+
+	return nil
+}
+
+// RouteError is the error of invalid route.
+type RouteError struct {
+	// The request method.
+	Method string
+
+	// The pattern of route.
+	Pattern string
+
+	// The full prefix of the group which the route belongs to.
+	Prefix string
+
+	// The underlying error.
+	Err error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("invalid route %s %q in group %q: %v", e.Method, e.Pattern, e.Prefix, e.Err)
+}
+
+// Unwrap returns the underlying error, so that the kind of error can be
+// checked via errors.Is, such as ErrEmptyPattern.
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// compile compiles the regular expression of route, so that the
+// invalid regular expression is reported before combining, the one
+// restored via UnmarshalBinary is skipped.
+func (route *Route) compile() error {
+	if route.restored {
+		return nil
+	}
+	if _, err := regexp.Compile("^(?:" + route.reg + ")$"); err != nil {
+		return &RouteError{Method: route.method, Pattern: route.pattern, Prefix: route.router.fullPrefix(), Err: err}
+	}
+
+	return nil
+}
+
+func (r *Router) prepare() {
+	// retrieve middleware for chaining
+	middleware := r.middleware()
+	engine := r.root().Engine
+
+	matchers := make(map[string]matcher, len(r.routes))
+	for _, method := range r.sortedMethods() {
+		routes := r.routes[method]
+		for _, route := range routes {
+			r.prepareRoute(route)
+		}
+
+		matchers[method] = newMatcher(engine, sortRoutes(routes))
+	}
+	r.matchers.Store(matchers)
+	if r.fallback != nil {
+		r.prepareRoute(r.fallback)
+	}
+
+	if r.mounted != nil {
+		route := &Route{router: r, middleware: r.mountedMiddleware}
+		r.finalMounted = route.chain(r.mounted, middleware)
+		if route.abortable {
+			r.finalMounted = abortable(r.finalMounted)
+		}
+	}
+
+	r.finalUnmatched = nil
+	if r.root().WrapErrorHandlers && len(middleware) > 0 {
+		route := &Route{router: r}
+		r.finalUnmatched = route.chain(r.unmatchedHandler(), middleware)
+		if route.abortable {
+			r.finalUnmatched = abortable(r.finalUnmatched)
+		}
+	}
+
+	if r.root().CaseInsensitive {
+		r.prepareFold()
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		r.groups[prefix].prepare()
+	}
+}
+
+// prepareRoute prepares the route which belongs to the router, such as
+// chaining middleware, merging the parameters and context values of
+// group prefixes.
+func (r *Router) prepareRoute(route *Route) {
+	middleware := r.middleware()
+	matchedMiddleware := r.matchedMiddleware()
+	contextValues := r.collectContextValues()
+	rewriters := r.collectRewriters()
+	prefixParams := r.collectPrefixParams()
+	prefixTransformers := r.collectPrefixTransformers()
+
+	route.paramNames = route.params
+	if len(prefixParams) > 0 {
+		route.paramNames = append(prefixParams[:len(prefixParams):len(prefixParams)], route.params...)
+	}
+	route.finalTransformers = route.transformers
+	if prefixTransformers != nil {
+		route.finalTransformers = append(prefixTransformers[:len(prefixTransformers):len(prefixTransformers)], route.transformers...)
+	} else if route.transformers != nil && len(prefixParams) > 0 {
+		route.finalTransformers = append(make([]func(string) string, len(prefixParams)), route.transformers...)
+	}
+	route.fullPattern = r.fullPrefix() + route.pattern
+	route.deadline, _ = route.meta[DeadlineMeta].(time.Duration)
+	route.finalContextValues = contextValues
+	if len(route.contextValues) > 0 {
+		route.finalContextValues = append(contextValues[:len(contextValues):len(contextValues)], route.contextValues...)
+	}
+	route.finalRewriters = rewriters
+	if len(route.rewriters) > 0 {
+		route.finalRewriters = append(rewriters[:len(rewriters):len(rewriters)], route.rewriters...)
+	}
+	handler := route.handler
+	if handler == nil {
+		handler = http.HandlerFunc(r.handleNotImplemented)
+	}
+	if route.panicRate > 0 {
+		handler = syntheticPanic(handler, route.panicRate)
+	}
+	if route.timeout > 0 {
+		code := route.timeoutCode
+		if code == 0 {
+			code = http.StatusServiceUnavailable
+		}
+		handler = TimeoutHandler(handler, route.timeout, code, http.StatusText(code))
+	}
+	route.finalHandler = route.chain(handler, middleware)
+	if route.flagFallback != nil {
+		route.finalFlagFallback = route.chain(route.flagFallback, middleware)
+	}
+	if len(matchedMiddleware) > 0 {
+		info := route.info()
+		route.finalHandler = chainMatched(route.finalHandler, info, matchedMiddleware)
+		if route.finalFlagFallback != nil {
+			route.finalFlagFallback = chainMatched(route.finalFlagFallback, info, matchedMiddleware)
+		}
+	}
+	if route.buffered {
+		route.finalHandler = bufferResponse(route.finalHandler)
+		if route.finalFlagFallback != nil {
+			route.finalFlagFallback = bufferResponse(route.finalFlagFallback)
+		}
+	}
+}
+
+// loadMatchers returns the mapping from request method to matcher.
+func (r *Router) loadMatchers() map[string]matcher {
+	matchers, _ := r.matchers.Load().(map[string]matcher)
+	return matchers
+}
+
+// Group returns a new group router with then given prefix.
+//
+// The prefix can be consisted of multiple segments, such as "api/v1",
+// the leading and trailing slashes are ignored, and the intermediate
+// groups will be reused or created. The intermediate group which was
+// created implicitly is taken over by the later Group call with its
+// prefix, such as Group("api") after Group("api/v1"), so that the
+// result does not depend on the order of registration.
+//
+// The segment can be a named parameter that supported by the parser,
+// such as `users/<id>` and `posts/<id:\d+>`, the parameters of group
+// prefixes are merged into the parameters of child routes, in front
+// of the route parameters.
+//
+// Causes a panic if the prefix is empty or contains empty segment,
+// or the group already exists.
+func (r *Router) Group(prefix string) *Router {
+	if r.inline != nil {
+		group := r.inline.Group(prefix)
+		group.Middleware = append(group.Middleware, r.Middleware...)
+		return group
+	}
+
+	router, last := r.groupParent(prefix)
+	if group, ok := router.groups[last]; ok {
+		// takes over the intermediate group.
+		group.implicit = false
+		return group
+	}
+
+	return router.newGroup(last)
+}
+
+// groupParent returns the parent of group with the given prefix and
+// the last segment of prefix, the intermediate groups will be reused
+// or created implicitly.
+//
+// Causes a panic if the prefix is empty or contains empty segment,
+// or the group already exists and it was not created implicitly.
+func (r *Router) groupParent(prefix string) (router *Router, last string) {
+	if prefix == "" {
+		panic(`the group prefix MUST NOT be empty`)
+	}
+
+	segments := strings.Split(strings.Trim(prefix, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" {
+			panic(fmt.Errorf("the group prefix %q MUST NOT contains empty segment", prefix))
+		}
+	}
+
+	router = r
+	for _, segment := range segments[:len(segments)-1] {
+		group, ok := router.groups[segment]
+		if !ok {
+			group = router.newGroup(segment)
+			group.implicit = true
+		}
+		router = group
+	}
+
+	last = segments[len(segments)-1]
+	if group, ok := router.groups[last]; ok && !group.implicit {
+		panic(fmt.Errorf("the group which prefix equal to %q already exists", prefix))
+	}
+
+	return router, last
+}
+
+// Route creates a group with the given prefix and middleware via
+// Group, and calls fn with the group, so that a whole subtree can be
+// declared inline:
+//
+//	r.Route("/admin", func(admin *fastrouter.Router) {
+//	    admin.Route("users", func(users *fastrouter.Router) {
+//	        users.Get("/", listUsers)
+//	        users.Get("/<id>", showUser)
+//	    })
+//	}, authMiddleware)
+//
+// Returns the group.
+func (r *Router) Route(prefix string, fn func(group *Router), middleware ...Middleware) *Router {
+	group := r.Group(prefix)
+	group.Middleware = append(group.Middleware, middleware...)
+	fn(group)
+	return group
+}
+
+// Use appends the given middleware to the Middleware of router, so that
+// middleware can be attached fluently:
+//
+//	r.Use(logger, recoverer)
+//
+// The middleware takes effect on Prepare.
+func (r *Router) Use(middleware ...Middleware) {
+	r.Middleware = append(r.Middleware, middleware...)
+}
+
+// With returns an inline router which applies the given middleware to
+// the routes that registered via it, the routes belong to the router
+// itself, rather than a group:
+//
+//	r.With(authMiddleware).Post("/posts", createPost)
+//
+// Only Handle and its shortcuts, Group, Route, Mount and MountAdmin
+// are supported by the inline router, the options of inline router are
+// ignored.
+func (r *Router) With(middleware ...Middleware) *Router {
+	target := r
+	if r.inline != nil {
+		target = r.inline
+	}
+
+	return &Router{
+		inline:     target,
+		Middleware: append(r.Middleware[:len(r.Middleware):len(r.Middleware)], middleware...),
+	}
+}
+
+// Mount mounts the handler under the given prefix, the requests which
+// path starts with the prefix are delegated to the handler with the
+// prefix stripped, it allows to embed third-party handlers, such as
+// another Router, net/http/pprof and so on:
+//
+//	r.Mount("/debug/pprof", http.HandlerFunc(pprof.Index))
+//
+// The prefix is created via Group, the middleware of router and its
+// parents and the given middleware are applied to the handler, and
+// the parameters of the prefix are passed via context.
+//
+// Causes a panic if the group of prefix already exists.
+func (r *Router) Mount(prefix string, handler http.Handler, middleware ...Middleware) {
+	group := r.Group(prefix)
+	group.mounted = handler
+	group.mountedMiddleware = middleware
+}
+
+// AdoptGroup attaches the given router as a group with the given
+// prefix, so that a group built in one package, such as a reusable
+// auth module which exposes its own routes, can be attached to any
+// application router:
+//
+//	auth := fastrouter.New()
+//	auth.Get("/login", login).Name("login")
+//
+//	r.AdoptGroup("auth", auth)
+//
+// The group keeps its own parser, middleware and groups, the names of
+// its routes are moved into the root router, and the options which are
+// only effective in root router are ignored.
+//
+// Causes a panic if the group already belongs to a router, the group of
+// prefix already exists, or any route name already exists.
+func (r *Router) AdoptGroup(prefix string, group *Router) {
+	root := r.root()
+	if group.parent != nil || group == root {
+		panic(fmt.Errorf("the group adopted as %q already belongs to a router", prefix))
+	}
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	for name := range group.names {
+		if _, ok := root.names[name]; ok {
+			panic(fmt.Errorf("the route which name equal to %q already exists", name))
+		}
+	}
+
+	router, last := r.groupParent(prefix)
+	if _, ok := router.groups[last]; ok {
+		panic(fmt.Errorf("the group which prefix equal to %q already exists", prefix))
+	}
+	router.attachGroup(last, group)
+
+	for name, route := range group.names {
+		root.names[name] = route
+	}
+	group.names = make(map[string]*Route)
+}
+
+// serveMounted delegates the request to the mounted handler, the path
+// is relative to the mount prefix.
+func (r *Router) serveMounted(w http.ResponseWriter, req *http.Request, path string, prefixValues []string) {
+	r2 := new(http.Request)
+	*r2 = *req
+	r2.URL = new(url.URL)
+	*r2.URL = *req.URL
+	r2.URL.Path, r2.URL.RawPath = path, ""
+	if root := r.root(); root.UseRawPath && req.URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			r2.URL.Path, r2.URL.RawPath = unescaped, path
+		}
+	}
+
+	if paramNames := r.collectPrefixParams(); len(paramNames) > 0 {
+		params := make(map[string]string, len(paramNames))
+		for i, name := range paramNames {
+			params[name] = prefixValues[i]
+		}
+		r2 = r2.WithContext(context.WithValue(r2.Context(), contextParamsKey, params))
+	}
+
+	r.finalMounted.ServeHTTP(w, r2)
+}
+
+// newGroup creates a group with the given single segment prefix.
+func (r *Router) newGroup(prefix string) *Router {
+	// group will inherits parent's parser
+	group := New()
+	group.parser = r.parser
+	r.attachGroup(prefix, group)
+	return group
+}
+
+// attachGroup attaches the group to router with the given prefix which
+// is a single segment.
+func (r *Router) attachGroup(prefix string, group *Router) {
+	group.prefixParsed, group.prefixValidators, group.prefixTransformers = r.parse("/" + prefix)
+	if reg := group.prefixParsed.reg; len(group.prefixParsed.params) > 0 {
+		if !strings.HasSuffix(reg, "/?") {
+			panic(fmt.Errorf("the group prefix %q MUST NOT contains catch-all parameter", prefix))
+		}
+		group.prefixReg = regexp.MustCompile("^(?:" + reg[1:len(reg)-2] + ")$")
+		group.prefixParams = group.prefixParsed.params
+		r.paramGroups = append(r.paramGroups, group)
+	}
+
+	group.parent = r
+	group.prefix = prefix
+	r.groups[prefix] = group
+}
+
+// matchPrefix matches the path segment against the parameterized
+// prefix, and returns the parameter values, ok is false if the segment
+// does not match.
+func (r *Router) matchPrefix(segment string) (values []string, ok bool) {
+	matches := r.prefixReg.FindStringSubmatch(segment)
+	if matches == nil {
+		return nil, false
+	}
+
+	values = matches[1 : 1+len(r.prefixParams)]
+	for i, fn := range r.prefixValidators {
+		if fn != nil && !fn(values[i]) {
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+// collectPrefixParams returns the parameter names of the prefixes of router
+// and its parents, in order from root to router.
+func (r *Router) collectPrefixParams() []string {
+	if r.parent == nil {
+		return nil
+	}
+
+	params := r.parent.collectPrefixParams()
+	if len(r.prefixParams) == 0 {
+		return params
+	}
+
+	return append(params[:len(params):len(params)], r.prefixParams...)
+}
+
+// collectPrefixTransformers returns the transformers of the prefix
+// parameters of router and its parents, in order of
+// collectPrefixParams, nil if there is no transformer.
+func (r *Router) collectPrefixTransformers() []func(string) string {
+	if r.parent == nil {
+		return nil
+	}
+
+	transformers := r.parent.collectPrefixTransformers()
+	if transformers == nil && r.prefixTransformers == nil {
+		return nil
+	}
+	if transformers == nil {
+		transformers = make([]func(string) string, len(r.parent.collectPrefixParams()))
+	}
+	if r.prefixTransformers == nil {
+		return append(transformers, make([]func(string) string, len(r.prefixParams))...)
+	}
+
+	return append(transformers, r.prefixTransformers...)
+}
+
+// Handle registers handler with the given method, pattern and middleware,
+// and returns the registered route.
+//
+// The request method is case sensitive, the non-standard methods,
+// such as WebDAV's PROPFIND and MKCOL, are also supported.
+//
+// The handler is a http.HandlerFunc that handle request, the route
+// with nil handler is a reserved route, it is handled by the nearest
+// NotImplementedHandler, so that the full API surface can be published
+// before the implementations land.
+//
+// It also allows to specify middleware for the given handler, for example,
+// we usually specify a body limit middleware for the upload handler.
+//
+// Causes a panic if the method is not a valid token, or parsing
+// failed, such as invalid pattern.
+func (r *Router) Handle(method, pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	if !isToken(method) {
+		panic(fmt.Errorf("the method %q is not a valid token", method))
+	}
+
+	if r.inline != nil {
+		return r.inline.Handle(method, pattern, handler, append(r.Middleware[:len(r.Middleware):len(r.Middleware)], middleware...)...)
+	}
+
+	route, err := r.register(method, pattern, handler, middleware)
+	if err != nil {
+		panic(err)
+	}
+
+	return route
+}
+
+// register parses the pattern and registers the route, the parsing
+// error is returned as it is.
+func (r *Router) register(method, pattern string, handler http.HandlerFunc, middleware []Middleware) (*Route, error) {
+	route := &Route{router: r, method: method, pattern: pattern, middleware: middleware}
+	if handler != nil {
+		route.handler = handler
+	}
+	parsed, validators, transformers, err := r.parseE(pattern)
+	if err != nil {
+		return nil, err
+	}
+	route.validators, route.transformers = validators, transformers
+	route.reg, route.params, route.hasTrailingSlashes = parsed.reg, parsed.params, parsed.hasTrailingSlashes
+	route.restored = parsed.restored
+
+	r.routes[method] = append(r.routes[method], route)
+
+	return route, nil
+}
+
+// standardMethods is the standard request methods, in order of RFC 7231
+// and RFC 5789.
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPatch,
+}
+
+// isToken reports whether s is a valid token as defined in RFC 7230.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Methods returns all the registered methods of the router and its
+// groups, in sorted order.
+func (r *Router) Methods() []string {
+	set := make(map[string]bool)
+	r.collectMethods(set)
+
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+func (r *Router) collectMethods(set map[string]bool) {
+	for method, routes := range r.routes {
+		if len(routes) > 0 {
+			set[method] = true
+		}
+	}
+	for _, group := range r.groups {
+		group.collectMethods(set)
+	}
+}
+
+// Any registers handler for all standard request methods via Match.
+func (r *Router) Any(pattern string, handler http.HandlerFunc, middleware ...Middleware) []*Route {
+	return r.Match(standardMethods, pattern, handler, middleware...)
+}
+
+// Match registers handler with the given methods via Handle, and
+// returns the registered routes in order of methods.
+//
+// Causes a panic if the methods is empty.
+func (r *Router) Match(methods []string, pattern string, handler http.HandlerFunc, middleware ...Middleware) []*Route {
+	if len(methods) == 0 {
+		panic(`the methods MUST NOT be empty`)
+	}
+
+	routes := make([]*Route, len(methods))
+	for i, method := range methods {
+		routes[i] = r.Handle(method, pattern, handler, middleware...)
+	}
+
+	return routes
+}
+
+// parse parses the pattern with the parser and the constraints, the
+// parsing result restored via UnmarshalBinary will be used if the
+// router has no constraint.
+//
+// Causes a panic if parsing failed.
+func (r *Router) parse(pattern string) (parsed parsedPattern, validators []func(string) bool, transformers []func(string) string) {
+	parsed, validators, transformers, err := r.parseE(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+// parseE parses the pattern as same as parse, except that it returns
+// an error instead of panicking.
+func (r *Router) parseE(pattern string) (parsed parsedPattern, validators []func(string) bool, transformers []func(string) string, err error) {
+	root := r.root()
+	if p, ok := r.parser.(Parser); ok && len(root.constraints) > 0 {
+		parsed.reg, parsed.params, validators, transformers, parsed.hasTrailingSlashes, err = p.parse(pattern, root.constraints)
+	} else if restored, ok := root.parsed[parsedKey(r.parser, pattern)]; ok {
+		parsed = restored
+	} else {
+		parsed.reg, parsed.params, parsed.hasTrailingSlashes, err = r.parser.Parse(pattern)
+	}
+
+	return
+}
+
+// Delete is a shortcut of Handle for handling DELETE request.
+func (r *Router) Delete(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodDelete, pattern, handler, middleware...)
+}
+
+// Get is a shortcut of Handle for handling GET request.
+func (r *Router) Get(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodGet, pattern, handler, middleware...)
+}
+
+// Post is a shortcut of Handle for handling POST request.
+func (r *Router) Post(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodPost, pattern, handler, middleware...)
+}
+
+// Head is a shortcut of Handle for handling HEAD request.
+//
+// Note that, the HEAD request falls back to the GET handler with
+// response body suppressed if no HEAD route matched, unless the
+// DisableAutoHead is enabled.
+func (r *Router) Head(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodHead, pattern, handler, middleware...)
+}
+
+// Patch is a shortcut of Handle for handling PATCH request.
+func (r *Router) Patch(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodPatch, pattern, handler, middleware...)
+}
+
+// Options is a shortcut of Handle for handling OPTIONS request, it
+// takes precedence over the automatic OPTIONS response.
+func (r *Router) Options(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodOptions, pattern, handler, middleware...)
+}
+
+// Connect is a shortcut of Handle for handling CONNECT request.
+func (r *Router) Connect(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodConnect, pattern, handler, middleware...)
+}
+
+// Trace is a shortcut of Handle for handling TRACE request.
+func (r *Router) Trace(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodTrace, pattern, handler, middleware...)
+}
+
+// Put is a shortcut of Handle for handling PUT request.
+func (r *Router) Put(pattern string, handler http.HandlerFunc, middleware ...Middleware) *Route {
+	return r.Handle(http.MethodPut, pattern, handler, middleware...)
+}
+
+// retrieveMethods returns all allowed methods of the request
+// path, in alphabetical order.
+func (r *Router) retrieveMethods(path string) (methods []string) {
+	matchers := r.loadMatchers()
+	for method, m := range matchers {
+		if route, _ := m.match(path, nil); route != nil && !route.gone {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	if !r.root().DisableAutoHead {
+		// HEAD request falls back to GET handler.
+		i := sort.SearchStrings(methods, http.MethodGet)
+		j := sort.SearchStrings(methods, http.MethodHead)
+		if i < len(methods) && methods[i] == http.MethodGet && (j == len(methods) || methods[j] != http.MethodHead) {
+			methods = append(methods, "")
+			copy(methods[j+1:], methods[j:])
+			methods[j] = http.MethodHead
+		}
+	}
+
+	return
+}
+
+// Lookup looks up the route which matches the given method and path,
+// and returns its handler that chained with middleware, and the
+// parameters of the path, matched is false if nothing matched.
+//
+// It allows to verify routing without going through ServeHTTP, note
+// that, the router MUST makes preparations before looking up.
+func (r *Router) Lookup(method, path string) (handler http.Handler, params map[string]string, matched bool) {
+	route, values := r.lookup(method, path)
+	if route == nil {
+		return nil, nil, false
+	}
+
+	route.transform(values)
+	if len(route.paramNames) > 0 {
+		params = make(map[string]string, len(route.paramNames))
+		for i, name := range route.paramNames {
+			params[name] = values[i]
+		}
+	}
+
+	return route.finalHandler, params, true
+}
+
+// LookupRoute returns the route which matches the given method and
+// path as same as Lookup, nil if nothing matched.
+func (r *Router) LookupRoute(method, path string) *Route {
+	route, _ := r.lookup(method, path)
+	return route
+}
+
+// lookup returns the route which matches the given method and path,
+// and its parameter values.
+func (r *Router) lookup(method, path string) (*Route, []string) {
+	router, path, prefixValues := r.fetchGroup(path)
+	matchers := router.loadMatchers()
+	if m, ok := matchers[method]; ok {
+		if route, values := m.match(path, prefixValues); route != nil {
+			return route, values
+		}
+	}
+	if m, ok := matchers[http.MethodGet]; ok && method == http.MethodHead && !r.root().DisableAutoHead {
+		return m.match(path, prefixValues)
+	}
+
+	return nil, nil
+}
+
+// ServeHTTP implements http.Handler's ServeHTTP method.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// handle request normalization.
+	if r.Normalization != nil {
+		var ok bool
+		if req, ok = r.Normalization.normalize(w, req); !ok {
+			return
+		}
+	}
+
+	method := req.Method
+	if r.allowedMethods != nil && !r.allowedMethods[method] {
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		return
+	}
+
+	path := req.URL.Path
+	// handle path cleaning.
+	if r.CleanPath {
+		if cleaned := cleanPath(path); cleaned != path {
+			r.redirectPermanently(w, req, cleaned)
+			return
+		}
+	}
+
+	// fetch group.
+	var start time.Time
+	if r.Debug {
+		start = time.Now()
+	}
+	router, path, prefixValues := r.fetchGroup(r.requestPath(req))
+	if r.Debug {
+		req = withTrace(req, start)
+	}
+
+	// handle panic.
+	if !r.DisableRecovery || router.hasPanicHandler() {
+		defer router.recoverPanic(w, req)
+	}
+	atomic.AddUint64(&r.requests, 1)
+	if !r.admits(w, req, router) {
+		return
+	}
+	if atomic.LoadInt32(&r.maintenance) != 0 && !router.isAdmin() {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if r.ReadOnly && !isSafeMethod(method) {
+		r.rejectReadOnly(w, req, router, path)
+		return
+	}
+
+	if router.finalMounted != nil {
+		router.serveMounted(w, req, path, prefixValues)
+		return
+	}
+
+	if r.dispatch(w, req, router, method, path, prefixValues) {
+		return
+	}
+	if method == http.MethodHead && !r.DisableAutoHead && r.dispatch(&headResponseWriter{w}, req, router, http.MethodGet, path, prefixValues) {
+		// falls back to the GET handler.
+		return
+	}
+
+	// retrieve allowed methods
+	methods := router.retrieveMethods(path)
+
+	// handle case-insensitive matching.
+	if len(methods) == 0 && r.CaseInsensitive && r.serveFold(w, req) {
+		return
+	}
+	if len(methods) == 0 && router.fallback != nil {
+		r.serveFallback(w, req, router, path, prefixValues)
+		return
+	}
+	if r.Debug {
+		writeTrace(w, req, router, nil)
+	}
+
+	if router.finalUnmatched != nil {
+		router.finalUnmatched.ServeHTTP(w, req)
+		return
+	}
+	router.serveUnmatched(w, req, path, methods)
+}
+
+// admits reports whether the given group admits the request, that is,
+// the request is received on the Ports, comes from the Networks and
+// its header is within the HeaderLimit of group, the request is
+// rejected otherwise.
+func (r *Router) admits(w http.ResponseWriter, req *http.Request, router *Router) bool {
+	if !router.servesPort(req) || !router.servesClient(req) {
+		r.handleNotFound(w, req)
+		return false
+	}
+	if !router.allowsHeader(req) {
+		code := http.StatusRequestHeaderFieldsTooLarge
+		http.Error(w, http.StatusText(code), code)
+		return false
+	}
+
+	return true
+}
+
+// serveUnmatched handles the request which no route matched, that is,
+// the OPTIONS, Method Not Allowed and Not Found requests, the path is
+// relative to the router, and the methods is the allowed methods of
+// the path.
+func (r *Router) serveUnmatched(w http.ResponseWriter, req *http.Request, path string, methods []string) {
+	root := r.root()
+
+	// handle OPTIONS request.
+	if req.Method == http.MethodOptions {
+		if len(methods) == 0 && root.OptionsNotFound {
+			r.handleNotFound(w, req)
+			return
+		}
+		if root.OptionsHandler != nil {
+			root.OptionsHandler(w, req, methods)
+			return
+		}
+
+		r.handleOptions(w, path, methods)
+		return
+	}
+
+	// retrieve the allowed methods of the URL path.
+	if len(methods) > 0 {
+		r.handleMethodNotAllowed(w, req, path, methods)
+		return
+	}
+
+	// handle Not Found.
+	r.handleNotFound(w, req)
+}
+
+// unmatchedHandler returns the handler which handles the unmatched
+// request via serveUnmatched, it is chained with the middleware of
+// router if the WrapErrorHandlers is enabled.
+func (r *Router) unmatchedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		root := r.root()
+		_, path, _ := root.fetchGroup(root.requestPath(req))
+		r.serveUnmatched(w, req, path, r.retrieveMethods(path))
+	})
+}
+
+// dispatch dispatches the request to the route which matches the
+// given method and path, reports whether a route matched, the
+// prefixValues is the parameter values of group prefixes.
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request, router *Router, method, path string, prefixValues []string) bool {
+	m, ok := router.loadMatchers()[method]
+	if !ok {
+		return false
+	}
+
+	// fetch route
+	if r.PooledParams {
+		pc := acquireParamsContext()
+		route, values := m.match(path, append(pc.params.values, prefixValues...))
+		if route != nil && route.timeout > 0 {
+			// the handler of route may outlive the request, so that
+			// the context is not pooled, see Route.Timeout.
+			r.handle(w, req, route, append([]string(nil), values...), &paramsContext{})
+		} else if route != nil {
+			r.handle(w, req, route, values, pc)
+		}
+		releaseParamsContext(pc)
+		return route != nil
+	} else if route, values := m.match(path, prefixValues); route != nil {
+		r.handle(w, req, route, values, nil)
+		return true
+	}
+
+	return false
+}
+
+// headResponseWriter suppresses the response body for the HEAD
+// request which is handled by GET handler.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// handleMethodNotAllowed handles Method Not Allowed via the nearest
+// MethodNotAllowedHandler, or the default handler.
+func (r *Router) handleMethodNotAllowed(w http.ResponseWriter, req *http.Request, path string, methods []string) {
+	for group := r; group != nil; group = group.parent {
+		if group.MethodNotAllowedHandler != nil {
+			group.MethodNotAllowedHandler(w, req, methods)
+			return
+		}
+	}
+
+	r.writeRequirements(w.Header(), path, methods)
+	methodNotAllowed(w, req, methods)
 }
 
-// Prepare makes preparations before handling requests:
-//
-// 1. combines route's regular expressions;
-//
-// 2. chaining middleware.
-//
-// Note that, router MUST makes preparations before handling request,
-// otherwise it can not works as expected.
-func (r *Router) Prepare() {
-	r.prepare()
+// isSafeMethod reports whether the request method is one of GET, HEAD
+// and OPTIONS.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
 }
 
-func (r *Router) prepare() {
-	// retrieve middleware for chaining
-	middleware := r.middleware()
+// rejectReadOnly rejects the non-safe request in read-only mode, the
+// router is the group which the request path belongs to.
+func (r *Router) rejectReadOnly(w http.ResponseWriter, req *http.Request, router *Router, path string) {
+	if r.ReadOnlyStatusCode == http.StatusForbidden {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
 
-	for method := range r.routes {
-		routes := r.routes[method]
-		regs := []string{}
-		for i := 0; i < len(routes); i++ {
-			if routes[i] != nil {
-				regs = append(regs, "("+routes[i].reg+")")
-
-				// chaining middleware
-				handler := routes[i].handler
-				// handler middleware
-				for j := len(routes[i].middleware) - 1; j >= 0; j-- {
-					handler = routes[i].middleware[j](handler)
-				}
-				// global middleware
-				for j := len(middleware) - 1; j >= 0; j-- {
-					handler = middleware[j](handler)
-				}
-				routes[i].finalHandler = handler
-				routes[i].finalHandler = handler
+	methods := []string{}
+	for _, method := range router.retrieveMethods(path) {
+		if isSafeMethod(method) {
+			methods = append(methods, method)
+		}
+	}
+	router.handleMethodNotAllowed(w, req, path, methods)
+}
+
+// handleOptions writes the automatic OPTIONS response with the
+// allowed methods, the OptionsHeader, OptionsMaxAge and OptionsBody
+// of the nearest router take precedence.
+func (r *Router) handleOptions(w http.ResponseWriter, path string, methods []string) {
+	header := w.Header()
+	var maxAge time.Duration
+	var body []byte
+	for router := r; router != nil; router = router.parent {
+		for k, v := range router.OptionsHeader {
+			if _, ok := header[k]; !ok {
+				header[k] = v
 			}
 		}
-		reg := strings.Join(regs, "|")
-		r.combinedRegexps[method] = regexp.MustCompile("^(?:" + reg + ")$")
+		if maxAge == 0 {
+			maxAge = router.OptionsMaxAge
+		}
+		if body == nil {
+			body = router.OptionsBody
+		}
 	}
 
-	for _, group := range r.groups {
-		group.prepare()
+	if maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge/time.Second)))
+	}
+	header.Set("Allow", strings.Join(methods, ", "))
+	r.writeRequirements(header, path, methods)
+	if body == nil && r.root().OptionsDescribe {
+		header.Set("Content-Type", "application/json; charset=utf-8")
+		body, _ = json.Marshal(r.describe(path, methods))
+	}
+	if body != nil {
+		w.Write(body)
 	}
 }
 
-// Group returns a new group router with then given prefix.
-func (r *Router) Group(prefix string) *Router {
-	if prefix == "" {
-		panic(`the group prefix MUST NOT be empty`)
-	}
-	if strings.Contains(prefix, "/") {
-		panic(`the group prefix MUST NOT contains '/'`)
+// routeDescription is the description of route in the automatic
+// OPTIONS responses.
+type routeDescription struct {
+	Method      string `json:"method"`
+	Pattern     string `json:"pattern"`
+	Description string `json:"description,omitempty"`
+}
+
+// describe returns the descriptions of the routes which match the
+// given path and methods.
+func (r *Router) describe(path string, methods []string) map[string]interface{} {
+	routes := []routeDescription{}
+	r.pathRoutes(path, methods, func(method string, route *Route) {
+		routes = append(routes, routeDescription{method, route.router.fullPrefix() + route.pattern, route.description})
+	})
+
+	return map[string]interface{}{
+		"allow":  methods,
+		"routes": routes,
 	}
+}
 
-	if _, ok := r.groups[prefix]; ok {
-		panic(fmt.Errorf("the group which prefix equal to %q already exists", prefix))
+// pathRoutes calls fn with each of the given methods and the route of
+// the method which matches the given path, the HEAD method falls back
+// to the GET route.
+func (r *Router) pathRoutes(path string, methods []string, fn func(method string, route *Route)) {
+	matchers := r.loadMatchers()
+	for _, method := range methods {
+		var route *Route
+		if m, ok := matchers[method]; ok {
+			route, _ = m.match(path, nil)
+		}
+		if m, ok := matchers[http.MethodGet]; ok && route == nil && method == http.MethodHead {
+			// HEAD request falls back to GET handler.
+			route, _ = m.match(path, nil)
+		}
+		if route != nil {
+			fn(method, route)
+		}
 	}
+}
 
-	// group will inherits parent's parser
-	group := New()
-	group.parent = r
-	group.parser = r.parser
-	r.groups[prefix] = group
-	return group
+// redirect replies to the request with a redirect to the given
+// path, the X-Forwarded-Prefix and BasePath will be prepended to
+// the path.
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, path string, code int) {
+	http.Redirect(w, req, r.redirectURL(req, path), code)
 }
 
-// Handle registers handler with the given method, pattern and middleware.
-//
-// The request method is case sensitive.
-//
-// The handler is a http.HandlerFunc that handle request.
-//
-// It also allows to specify middleware for the given handler, for example,
-// we usually specify a body limit middleware for the upload handler.
-//
-// Causes a panic if parsing failed, such as invalid pattern.
-func (r *Router) Handle(method, pattern string, handler http.HandlerFunc, middleware ...Middleware) {
-	if _, ok := r.routes[method]; !ok {
-		r.routes[method] = []*route{nil}
+// redirectURL returns the URL of redirect to the given path.
+func (r *Router) redirectURL(req *http.Request, path string) string {
+	u := *req.URL
+	u.Path = r.forwardedPrefix(req) + r.basePath() + path
+	u.RawPath = ""
+	return u.String()
+}
+
+// redirectSlashes replies to the request with the redirect of trailing
+// slashes policy via the RedirectHandler of route, or the nearest
+// RedirectHandler of its routers, the path is in the form of
+// requestPath.
+func (r *Router) redirectSlashes(w http.ResponseWriter, req *http.Request, route *Route, path string, code int) {
+	handler := route.redirectHandler
+	for router := route.router; handler == nil && router != nil; router = router.parent {
+		handler = router.RedirectHandler
 	}
-	route := &route{handler: handler, middleware: middleware}
-	var err error
-	route.reg, route.params, route.hasTrailingSlashes, err = r.parser.Parse(pattern)
-	if err != nil {
-		panic(err)
+	location := r.slashesURL(req, path)
+	if handler == nil {
+		http.Redirect(w, req, location, code)
+		return
 	}
 
-	r.routes[method] = append(r.routes[method], route)
-	for i := 0; i < len(route.params); i++ {
-		r.routes[method] = append(r.routes[method], nil)
-	}
+	handler(w, req, location, code)
 }
 
-// Delete is a shortcut of Handle for handling DELETE request.
-func (r *Router) Delete(pattern string, handler http.HandlerFunc, middleware ...Middleware) {
-	r.Handle(http.MethodDelete, pattern, handler, middleware...)
+// slashesURL returns the URL of the trailing slashes redirect to the
+// given path which is in the form of requestPath, so that the escaped
+// characters of RawPath, such as "%2F", are kept as they are.
+func (r *Router) slashesURL(req *http.Request, path string) string {
+	if r.UseRawPath && req.URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			prefix := r.forwardedPrefix(req) + r.basePath()
+			u := *req.URL
+			u.Path = prefix + unescaped
+			u.RawPath = (&url.URL{Path: prefix}).EscapedPath() + path
+			return u.String()
+		}
+	}
+
+	return r.redirectURL(req, path)
 }
 
-// Get is a shortcut of Handle for handling GET request.
-func (r *Router) Get(pattern string, handler http.HandlerFunc, middleware ...Middleware) {
-	r.Handle(http.MethodGet, pattern, handler, middleware...)
+// redirectPermanently replies to the request with a permanent redirect
+// to the given path, the status code is 301 for GET request, and 308
+// for the others.
+func (r *Router) redirectPermanently(w http.ResponseWriter, req *http.Request, path string) {
+	// status code, default 301.
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet {
+		// status code should be 308 if the request is not a GET request.
+		code = http.StatusPermanentRedirect
+	}
+	r.redirect(w, req, path, code)
 }
 
-// Post is a shortcut of Handle for handling POST request.
-func (r *Router) Post(pattern string, handler http.HandlerFunc, middleware ...Middleware) {
-	r.Handle(http.MethodPost, pattern, handler, middleware...)
+// cleanPath returns the canonical path of p, it eliminates duplicate
+// slashes and dot segments, and keeps the trailing slashes.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
 }
 
-// Put is a shortcut of Handle for handling PUT request.
-func (r *Router) Put(pattern string, handler http.HandlerFunc, middleware ...Middleware) {
-	r.Handle(http.MethodPut, pattern, handler, middleware...)
+// basePath returns the BasePath of root router without trailing slashes.
+func (r *Router) basePath() string {
+	return strings.TrimSuffix(r.root().BasePath, "/")
 }
 
-// ServeFiles serve static resources.
-//
-// The pattern MUST contains parameter placeholder named "filepath",
-// it is related to pattern parser.
-//
-// The root is the absolute or relative path of the static resources.
-func (r *Router) ServeFiles(pattern, root string, middleware ...Middleware) {
-	if !strings.Contains(pattern, "filepath") {
-		panic(`the pattern MUST contains parameter placeholder named "filepath"`)
+// methodNotAllowed is the default handler for handling Method Not
+// Allowed, it renders the allowed methods as JSON, HTML or plain text
+// according to the Accept header.
+func methodNotAllowed(w http.ResponseWriter, req *http.Request, methods []string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	text := http.StatusText(http.StatusMethodNotAllowed)
+	switch negotiate(req.Header.Get("Accept"), "text/plain", "application/json", "text/html") {
+	case "application/json":
+		if methods == nil {
+			methods = []string{}
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"error":   text,
+			"allowed": methods,
+		})
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write(body)
+	case "text/html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>%s</title></head><body><h1>%s</h1><p>Allowed methods: %s</p></body></html>\n",
+			text, text, html.EscapeString(strings.Join(methods, ", ")))
+	default:
+		http.Error(w, text, http.StatusMethodNotAllowed)
 	}
+}
 
-	fs := http.FileServer(http.Dir(root))
-	handler := func(w http.ResponseWriter, req *http.Request) {
-		if params, ok := req.Context().Value(contextParamsKey).(map[string]string); ok {
-			req.URL.Path = params["filepath"]
-			fs.ServeHTTP(w, req)
+// handleNotFound handles Not Found via the nearest NotFoundHandler,
+// or http.NotFound.
+func (r *Router) handleNotFound(w http.ResponseWriter, req *http.Request) {
+	for router := r; router != nil; router = router.parent {
+		if router.NotFoundHandler != nil {
+			router.NotFoundHandler.ServeHTTP(w, req)
 			return
 		}
 	}
 
-	r.Handle(http.MethodGet, pattern, http.HandlerFunc(handler), middleware...)
+	http.NotFound(w, req)
 }
 
-// retrieveMethods returns all allowed methods of the request
-// path. And the result is random, since it uses map.
-func (r *Router) retrieveMethods(path string) (methods []string) {
-	for method, reg := range r.combinedRegexps {
-		if reg.MatchString(path) {
-			methods = append(methods, method)
-		}
+// requestPath returns the path of request for matching, it is the
+// RawPath if UseRawPath is enabled and the RawPath is not empty.
+func (r *Router) requestPath(req *http.Request) string {
+	if r.UseRawPath && req.URL.RawPath != "" {
+		return req.URL.RawPath
 	}
 
-	return
+	return req.URL.Path
 }
 
-// ServeHTTP implements http.Handler's ServeHTTP method.
-func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// handle panic if PanicHandler is set.
-	if r.PanicHandler != nil {
-		defer func() {
-			if rcv := recover(); rcv != nil {
-				r.PanicHandler(w, req, rcv)
-			}
-		}()
+// unescapeValues unescapes the parameter values in place, the invalid
+// values are kept as they are.
+func unescapeValues(values []string) {
+	for i, v := range values {
+		if strings.IndexByte(v, '%') < 0 {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(v); err == nil {
+			values[i] = unescaped
+		}
 	}
+}
 
-	method := req.Method
-	path := req.URL.Path
-	// fetch group.
-	router, path := r.fetchGroup(path)
-	if reg, ok := router.combinedRegexps[method]; ok {
-		matches := reg.FindStringSubmatch(path)
-		if matches != nil {
-			// fetch route
-			var i = 1
-			for ; i < len(matches) && matches[i] == ""; i++ {
-			}
-			route := router.routes[method][i]
-
-			// handle trailing slashes.
-			if r.TrailingSlashesPolicy != IgnoreTrailingSlashes {
-				// status code, default 301.
-				code := http.StatusMovedPermanently
-				if method != http.MethodGet {
-					// status code should be 308 if the request is not a GET request.
-					code = http.StatusPermanentRedirect
-				}
+// handleNotImplemented handles the reserved route via the nearest
+// NotImplementedHandler, or responds with 501 Not Implemented.
+func (r *Router) handleNotImplemented(w http.ResponseWriter, req *http.Request) {
+	for router := r; router != nil; router = router.parent {
+		if router.NotImplementedHandler != nil {
+			router.NotImplementedHandler.ServeHTTP(w, req)
+			return
+		}
+	}
 
-				pos := len(req.URL.Path) - 1
-				isRootPath := req.URL.Path == "/"
-				endWithSlashes := req.URL.Path[pos] == '/'
-				if r.TrailingSlashesPolicy == RemoveTrailingSlashes && endWithSlashes && !isRootPath {
-					req.URL.Path = req.URL.Path[:pos]
-					http.Redirect(w, req, req.URL.String(), code)
-					return
-				}
-				if r.TrailingSlashesPolicy == AppendTrailingSlashes && !endWithSlashes && !isRootPath {
-					req.URL.Path = req.URL.Path + "/"
-					http.Redirect(w, req, req.URL.String(), code)
-					return
-				}
-				if r.TrailingSlashesPolicy == StrictTrailingSlashes && !isRootPath {
-					if route.hasTrailingSlashes && !endWithSlashes {
-						req.URL.Path = req.URL.Path + "/"
-						http.Redirect(w, req, req.URL.String(), code)
-						return
-					}
-					if !route.hasTrailingSlashes && endWithSlashes {
-						req.URL.Path = req.URL.Path[:pos]
-						http.Redirect(w, req, req.URL.String(), code)
-					}
-				}
-			}
+	http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+}
 
-			if len(route.params) > 0 {
-				// extract parameters from the URL path.
-				params := make(map[string]string, len(route.params))
-				for _, name := range route.params {
-					i++
-					params[name] = matches[i]
-				}
+// handle handles the request with the matched route, the parameters
+// and route are passed via the given pooled context if it is not nil.
+func (r *Router) handle(w http.ResponseWriter, req *http.Request, route *Route, values []string, pc *paramsContext) {
+	handler := route.finalHandler
+	if req.Method == http.MethodHead && route.method == http.MethodGet {
+		atomic.AddUint64(&route.headHits, 1)
+	} else {
+		atomic.AddUint64(&route.hits, 1)
+	}
+	atomic.StoreInt64(&route.lastHit, time.Now().UnixNano())
 
-				// pass parameters to downstream handler via context.
-				ctx := context.WithValue(req.Context(), contextParamsKey, params)
-				req = req.WithContext(ctx)
-			}
+	// handle disabled route.
+	if !route.Enabled() {
+		route.router.handleNotFound(w, req)
+		return
+	}
 
-			// handle request
-			route.finalHandler.ServeHTTP(w, req)
+	// handle feature flag.
+	if route.flag != "" && r.FlagProvider != nil && !r.FlagProvider.Enabled(req, route.flag) {
+		if route.finalFlagFallback == nil {
+			route.router.handleNotFound(w, req)
 			return
 		}
+		handler = route.finalFlagFallback
 	}
 
-	// retrieve allowed methods
-	methods := router.retrieveMethods(path)
+	// handle trailing slashes.
+	if r.TrailingSlashesPolicy != IgnoreTrailingSlashes {
+		// status code, default 301.
+		code := http.StatusMovedPermanently
+		if req.Method != http.MethodGet {
+			// status code should be 308 if the request is not a GET request.
+			code = http.StatusPermanentRedirect
+		}
 
-	// handle OPTIONS request.
-	if method == http.MethodOptions {
-		if r.OptionsHandler != nil {
-			r.OptionsHandler(w, req, methods)
+		// the escaped slashes of RawPath are not trailing slashes.
+		path := r.requestPath(req)
+		pos := len(path) - 1
+		isRootPath := path == "/"
+		endWithSlashes := path[pos] == '/'
+		if r.TrailingSlashesPolicy == RemoveTrailingSlashes && endWithSlashes && !isRootPath {
+			r.redirectSlashes(w, req, route, path[:pos], code)
+			return
+		}
+		if r.TrailingSlashesPolicy == AppendTrailingSlashes && !endWithSlashes && !isRootPath {
+			r.redirectSlashes(w, req, route, path+"/", code)
 			return
 		}
+		if r.TrailingSlashesPolicy == StrictTrailingSlashes && !isRootPath {
+			if route.hasTrailingSlashes && !endWithSlashes {
+				r.redirectSlashes(w, req, route, path+"/", code)
+				return
+			}
+			if !route.hasTrailingSlashes && endWithSlashes {
+				r.redirectSlashes(w, req, route, path[:pos], code)
+				return
+			}
+		}
+	}
 
-		w.Header().Set("Allow", strings.Join(methods, ", "))
-		return
+	if r.UnescapePathValues && r.UseRawPath && req.URL.RawPath != "" {
+		unescapeValues(values)
 	}
+	route.transform(values)
 
-	// retrieve the allowed methods of the URL path.
-	if len(methods) > 0 {
-		// handle Method Not Allowed.
-		if r.MethodNotAllowedHandler != nil {
-			r.MethodNotAllowedHandler(w, req, methods)
-			return
+	ctx := req.Context()
+	// inject the static context values.
+	for _, v := range route.finalContextValues {
+		ctx = context.WithValue(ctx, v.key, v.value)
+	}
+	// apply the deadline of route.
+	if route.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, route.deadline)
+		defer cancel()
+	}
+	// apply the budget of caller.
+	if r.RequestTimeoutHeader != "" {
+		if budget, ok := r.requestBudget(req); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+	}
+	if pc != nil {
+		// pass parameters and route to downstream handler
+		// via the pooled context.
+		pc.Context = ctx
+		pc.route = route
+		pc.abortable = route.abortable
+		pc.params.names = route.paramNames
+		pc.params.values = values
+		ctx = pc
+	} else if len(route.paramNames) > 0 {
+		// extract parameters from the URL path.
+		params := make(map[string]string, len(route.paramNames))
+		for i, name := range route.paramNames {
+			params[name] = values[i]
 		}
 
-		w.Header().Set("Allow", strings.Join(methods, ", "))
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		return
+		// pass parameters to downstream handler via context.
+		ctx = context.WithValue(ctx, contextParamsKey, params)
 	}
+	if pc == nil && route.abortable {
+		// pass route and the aborted flag to downstream handler
+		// via a single context.
+		ctx = &paramsContext{Context: ctx, route: route, abortable: true}
+	} else if pc == nil {
+		// pass route to downstream handler via context,
+		// so that middleware can access its pattern and metadata.
+		ctx = context.WithValue(ctx, contextRouteKey, route)
+	}
+	req = route.rewrite(req.WithContext(ctx))
 
-	// handle Not Found.
-	if r.NotFoundHandler != nil {
-		r.NotFoundHandler.ServeHTTP(w, req)
+	// handle request
+	if r.Debug {
+		writeTrace(w, req, route.router, route)
+	}
+	if route.requireLength && req.ContentLength < 0 {
+		http.Error(w, http.StatusText(http.StatusLengthRequired), http.StatusLengthRequired)
+		return
+	}
+	if r.StrictContentLength && route.maxBodySize > 0 && req.ContentLength > route.maxBodySize {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if route.maxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, route.maxBodySize)
+	}
+	if route.responseLimit > 0 {
+		lw := newLimitWriter(w, req, route)
+		handler.ServeHTTP(lw, req)
+		lw.finish()
 		return
 	}
+	handler.ServeHTTP(w, req)
+}
 
-	http.NotFound(w, req)
+// root returns the root router.
+func (r *Router) root() *Router {
+	root := r
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	return root
 }
 
 func (r *Router) middleware() (middleware []Middleware) {
@@ -380,8 +1968,22 @@ func (r *Router) middleware() (middleware []Middleware) {
 	return
 }
 
-func (r *Router) fetchGroup(path string) (*Router, string) {
+// matchedMiddleware returns the matched-route middleware of router and
+// its parents, in order from root to router.
+func (r *Router) matchedMiddleware() []MatchedMiddleware {
+	if r.parent == nil {
+		return r.MatchedMiddleware
+	}
+
+	middleware := r.parent.matchedMiddleware()
+	return append(middleware[:len(middleware):len(middleware)], r.MatchedMiddleware...)
+}
+
+// fetchGroup returns the group which the path belongs to, the path
+// relative to the group, and the parameter values of group prefixes.
+func (r *Router) fetchGroup(path string) (*Router, string, []string) {
 	router := r
+	var values []string
 walk:
 	if path != "/" && len(r.groups) > 0 {
 		i := 1
@@ -389,7 +1991,19 @@ walk:
 		}
 		if i > 1 {
 			prefix := path[1:i]
-			if group, ok := router.groups[prefix]; ok {
+			group, ok := router.groups[prefix]
+			if ok && group.prefixReg != nil {
+				// the parameterized prefix MUST NOT be matched literally.
+				ok = false
+			}
+			for j := 0; !ok && j < len(router.paramGroups); j++ {
+				var vs []string
+				if vs, ok = router.paramGroups[j].matchPrefix(prefix); ok {
+					group = router.paramGroups[j]
+					values = append(values, vs...)
+				}
+			}
+			if ok {
 				router = group
 				if i < len(path) {
 					path = path[i:]
@@ -400,21 +2014,289 @@ walk:
 		}
 	}
 
-	return router, path
+	return router, path, values
 }
 
-type route struct {
+// Route is a registered route, it is returned by Handle and
+// its shortcuts, such as Get, Post and so on.
+type Route struct {
+	// the number of hits, it is the first field for 64-bit alignment
+	// of atomic operations.
+	hits uint64
+
+	// the number of HEAD requests which fall back to the GET route.
+	headHits uint64
+
+	// the time of the latest hit in Unix nanoseconds, zero if the route
+	// has never been hit.
+	lastHit int64
+
+	// indicates whether the route is disabled.
+	disabled int32
+
+	// indicates whether the route is a retired endpoint, see Gone.
+	gone bool
+
+	// the router which the route belongs to.
+	router *Router
+
+	// route name for reverse routing.
+	name string
+
+	// the localized aliases of route, keyed by locale, see Route.Alias.
+	aliases map[string]*Route
+
+	// the locale of alias, empty if the route is not an alias.
+	locale string
+
+	method string
+
+	pattern string
+
+	// the pattern which contains the prefixes of groups.
+	fullPattern string
+
 	reg string
 
 	params []string
 
+	// the parameter names of group prefixes and route, in order.
+	paramNames []string
+
 	hasTrailingSlashes bool
 
+	// the validation funcs of parameters in order, nil if the route
+	// has no validation func constraint.
+	validators []func(string) bool
+
+	// the transformers of parameters in order, nil if the route has
+	// no transformer, see Router.Transformer.
+	transformers []func(string) string
+
+	// the transformers of group prefixes and route parameters, in
+	// order of paramNames.
+	finalTransformers []func(string) string
+
 	middleware []Middleware
 
 	handler http.Handler
 
 	finalHandler http.Handler
+
+	// route metadata.
+	meta map[string]interface{}
+
+	// human-readable description.
+	description string
+
+	// the matching priority, see Route.Priority.
+	priority int
+
+	// the rate of synthetic panics, see Route.PanicRate.
+	panicRate float64
+
+	// the names of router middleware to be skipped.
+	skipMiddleware []string
+
+	// the maximum bytes of response body, zero means no limit.
+	responseLimit int64
+
+	// the maximum bytes of request body, zero means no limit.
+	maxBodySize int64
+
+	// the handler for emitting the redirects of trailing slashes policy,
+	// see Route.RedirectHandler.
+	redirectHandler func(w http.ResponseWriter, req *http.Request, url string, code int)
+
+	// the allowed origins of route, nil means the origins of CORSPolicy
+	// are used, see Route.AllowOrigins.
+	origins map[string]bool
+
+	// indicates whether the response is buffered, see Route.Buffered.
+	buffered bool
+
+	// indicates whether the request body MUST have a declared length,
+	// see Route.RequireContentLength.
+	requireLength bool
+
+	// the duration of handler timeout, zero means no timeout.
+	timeout time.Duration
+
+	// the status code of timeout response, see Route.TimeoutCode.
+	timeoutCode int
+
+	// indicates whether the middleware chain is guarded for Abort.
+	abortable bool
+
+	// indicates whether the parsing result is restored via
+	// UnmarshalBinary, see parsedPattern.
+	restored bool
+
+	// the deadline of request context, zero means no deadline, see
+	// DeadlineMeta.
+	deadline time.Duration
+
+	// feature flag name.
+	flag string
+
+	// the handler for handling request when the feature flag is disabled.
+	flagFallback http.Handler
+
+	finalFlagFallback http.Handler
+
+	// the static values that will be injected into request context.
+	contextValues []contextValue
+
+	// the context values of route and its routers, in order of
+	// precedence from low to high.
+	finalContextValues []contextValue
+
+	// the request rewriters, see Route.Rewrite.
+	rewriters []RequestRewriter
+
+	// the rewriters of route and its routers, in order of application.
+	finalRewriters []RequestRewriter
+}
+
+// chain chains the given handler with the route middleware and
+// the given global middleware, each middleware is guarded for
+// skipping the aborted request if the root router is Abortable.
+func (route *Route) chain(handler http.Handler, middleware []Middleware) http.Handler {
+	middleware = route.skip(middleware)
+	route.abortable = false
+	if len(route.middleware) == 0 && len(middleware) == 0 {
+		return handler
+	}
+	route.abortable = route.router.root().Abortable
+
+	// handler middleware
+	for j := len(route.middleware) - 1; j >= 0; j-- {
+		if route.abortable {
+			handler = abortGuard(handler)
+		}
+		handler = route.middleware[j](handler)
+	}
+	// global middleware
+	for j := len(middleware) - 1; j >= 0; j-- {
+		if route.abortable {
+			handler = abortGuard(handler)
+		}
+		handler = middleware[j](handler)
+	}
+
+	return handler
+}
+
+// autoHead reports whether the route is a GET route that also handles
+// the HEAD requests via automatic HEAD, that is, there is no HEAD route
+// with the same pattern in the same router.
+func (route *Route) autoHead() bool {
+	if route.method != http.MethodGet || route.router.root().DisableAutoHead {
+		return false
+	}
+	for _, head := range route.router.routes[http.MethodHead] {
+		if head.pattern == route.pattern {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pattern returns the pattern of route.
+func (route *Route) Pattern() string {
+	return route.pattern
+}
+
+// Meta attaches metadata to the route, the metadata will be
+// accessible to middleware while handling the matched request.
+//
+// Returns the route itself for chaining.
+func (route *Route) Meta(key string, value interface{}) *Route {
+	if route.meta == nil {
+		route.meta = make(map[string]interface{})
+	}
+	route.meta[key] = value
+	return route
+}
+
+// RedirectHandler sets the handler for emitting the redirects of
+// trailing slashes policy of the route, it takes precedence over the
+// RedirectHandler of routers.
+//
+// Returns the route itself for chaining.
+func (route *Route) RedirectHandler(handler func(w http.ResponseWriter, req *http.Request, url string, code int)) *Route {
+	route.redirectHandler = handler
+	return route
+}
+
+// Description attaches a human-readable description to the route,
+// it will be returned in the automatic OPTIONS responses if the
+// OptionsDescribe is enabled.
+//
+// Returns the route itself for chaining.
+func (route *Route) Description(description string) *Route {
+	route.description = description
+	return route
+}
+
+// RouteMeta returns the metadata of the matched route, nil if the route
+// has no metadata, see Route.Meta. It is usually used by middleware for
+// reading the declarations next to route, for example:
+//
+//	r.Get("/admin/users", handler).Meta("scope", "admin")
+//
+//	func scopeMiddleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+//			if scope, ok := fastrouter.RouteMeta(req)["scope"].(string); ok && !hasScope(req, scope) {
+//				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+//				return
+//			}
+//			next.ServeHTTP(w, req)
+//		})
+//	}
+//
+// The returned map is shared by all requests of route, it MUST NOT be
+// modified.
+func RouteMeta(req *http.Request) map[string]interface{} {
+	if route := routeFromRequest(req); route != nil {
+		return route.meta
+	}
+
+	return nil
+}
+
+// routeFromRequest returns the matched route that stored in
+// the request context, nil if the route has no metadata.
+func routeFromRequest(req *http.Request) *Route {
+	if route := matchedRoute(req); route != nil && route.meta != nil {
+		return route
+	}
+
+	return nil
+}
+
+// matchedRoute returns the matched route that stored in the request
+// context, nil if no route matched.
+func matchedRoute(req *http.Request) *Route {
+	if route, ok := req.Context().Value(contextRouteKey).(*Route); ok {
+		return route
+	}
+
+	return nil
+}
+
+// MatchedPattern returns the full pattern of the matched route which
+// contains the prefixes of its groups, such as "/v1/users/<id>", empty
+// if no route matched. It is usually used by the metrics and tracing
+// middleware for labeling series without exploding cardinality on the
+// raw paths.
+func MatchedPattern(req *http.Request) string {
+	if route := matchedRoute(req); route != nil {
+		return route.fullPattern
+	}
+
+	return ""
 }
 
 // Middleware is a chaining tool for chaining http.Handler.
@@ -429,11 +2311,52 @@ type route struct {
 //             Handler
 type Middleware func(next http.Handler) http.Handler
 
+// MatchedMiddleware is a middleware which runs once the route is
+// matched, before the middleware chain of route, it is usually used for
+// the cross-cutting concerns that need the route identity, such as
+// authorization and metrics:
+//
+//	r.MatchedMiddleware = append(r.MatchedMiddleware, func(info fastrouter.RouteInfo, next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+//			if !allowed(req, info.Name, fastrouter.Params(req)) {
+//				http.Error(w, "Forbidden", http.StatusForbidden)
+//				return
+//			}
+//			next.ServeHTTP(w, req)
+//		})
+//	})
+//
+// It is called with the information of each route at Prepare time, and
+// the parameters of the matched request are accessible via Params and
+// Param.
+type MatchedMiddleware func(info RouteInfo, next http.Handler) http.Handler
+
+// chainMatched chains the given handler with the matched-route
+// middleware, the first middleware is the outermost.
+func chainMatched(handler http.Handler, info RouteInfo, middleware []MatchedMiddleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](info, handler)
+	}
+
+	return handler
+}
+
 // Params returns the parameters of the request path.
+//
+// A new map will be allocated if the router passes parameters via
+// ParamList, Param and ParamListOf are preferred in that case.
 func Params(r *http.Request) map[string]string {
 	if params, ok := r.Context().Value(contextParamsKey).(map[string]string); ok {
 		return params
 	}
 
+	if list := ParamListOf(r); list != nil {
+		params := make(map[string]string, list.Len())
+		for i, name := range list.names {
+			params[name] = list.values[i]
+		}
+		return params
+	}
+
 	return nil
 }
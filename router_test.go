@@ -5,6 +5,7 @@
 package fastrouter
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRouter_Group(t *testing.T) {
@@ -59,7 +61,7 @@ func TestRouter_Group2(t *testing.T) {
 }
 
 func TestRouter_Group3(t *testing.T) {
-	expect := `the group prefix MUST NOT contains '/'`
+	expect := fmt.Errorf("the group prefix %q MUST NOT contains empty segment", "api//v1")
 	defer func() {
 		if rcv := recover(); rcv == nil || !reflect.DeepEqual(expect, rcv) {
 			t.Errorf("expect err to be %q, but got %q", expect, rcv)
@@ -67,7 +69,7 @@ func TestRouter_Group3(t *testing.T) {
 	}()
 
 	r := New()
-	r.Group("/v1")
+	r.Group("api//v1")
 }
 
 func TestRouter_Group4(t *testing.T) {
@@ -190,7 +192,7 @@ func TestRouter_RetrieveMethods(t *testing.T) {
 	pattern := `/users/<id>`
 	r.Get(pattern, emptyHandler)
 	r.Prepare()
-	expect := []string{http.MethodGet}
+	expect := []string{http.MethodGet, http.MethodHead}
 	if methods := r.retrieveMethods(path); !compareSlice(expect, methods) {
 		t.Errorf("expect method to be %v, but got %v", expect, methods)
 	}
@@ -198,7 +200,7 @@ func TestRouter_RetrieveMethods(t *testing.T) {
 	r.Delete(pattern, emptyHandler)
 	r.Put(pattern, emptyHandler)
 	r.Prepare()
-	expect = []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+	expect = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete}
 	if methods := r.retrieveMethods(path); !compareSlice(expect, methods) {
 		t.Errorf("expect method to be %v, but got %v", expect, methods)
 	}
@@ -533,6 +535,49 @@ func TestRouter_TrailingSlashesPolicy4(t *testing.T) {
 	}
 }
 
+func TestRouter_RedirectHandler(t *testing.T) {
+	handler := func(name string) func(w http.ResponseWriter, req *http.Request, url string, code int) {
+		return func(w http.ResponseWriter, req *http.Request, url string, code int) {
+			w.Header().Set("X-Redirect-Handler", name)
+			http.Redirect(w, req, url, code)
+		}
+	}
+	r := New()
+	r.TrailingSlashesPolicy = RemoveTrailingSlashes
+	r.RedirectHandler = handler("root")
+	r.Get("/users", emptyHandler)
+	api := r.Group("api")
+	api.RedirectHandler = handler("api")
+	api.Get("/users", emptyHandler)
+	api.Post("/posts", emptyHandler).RedirectHandler(handler("posts"))
+	r.Prepare()
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+		handler  string
+	}{
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users", "root"},
+		{http.MethodGet, "/api/users/?page=2", http.StatusMovedPermanently, "/api/users?page=2", "api"},
+		{http.MethodPost, "/api/posts/", http.StatusPermanentRedirect, "/api/posts", "posts"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %s to be %d, but got %d", test.method, test.path, test.code, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("expect Location of %s %s to be %q, but got %q", test.method, test.path, test.location, location)
+		}
+		if name := w.Header().Get("X-Redirect-Handler"); name != test.handler {
+			t.Errorf("expect redirect handler of %s %s to be %q, but got %q", test.method, test.path, test.handler, name)
+		}
+	}
+}
+
 func TestRouter_Middleware(t *testing.T) {
 	middlewareKey := "Middleware"
 	anotherMiddlewareKey := "Another-Middleware"
@@ -651,3 +696,1205 @@ func compareSlice(s1, s2 []string) bool {
 
 	return true
 }
+
+func TestRoute_Meta(t *testing.T) {
+	r := New()
+	var route *Route
+	r.Get("/users/<id>", func(w http.ResponseWriter, req *http.Request) {
+		route = routeFromRequest(req)
+	}).Meta("scope", "admin")
+	r.Get("/posts", func(w http.ResponseWriter, req *http.Request) {
+		route = routeFromRequest(req)
+	})
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if route == nil || route.Pattern() != "/users/<id>" {
+		t.Fatalf("expect matched route to be %q, but got %v", "/users/<id>", route)
+	}
+	if route.meta["scope"] != "admin" {
+		t.Errorf("expect metadata scope to be %q, but got %v", "admin", route.meta["scope"])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if route != nil {
+		t.Errorf("expect no route in context, but got %v", route)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	scopeMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if scope, ok := RouteMeta(req)["scope"].(string); ok && req.Header.Get("X-Scope") != scope {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Middleware = append(r.Middleware, scopeMiddleware)
+		r.Get("/admin/users", emptyHandler).Meta("scope", "admin")
+		r.Get("/posts", emptyHandler)
+		r.Prepare()
+
+		tests := []struct {
+			path  string
+			scope string
+			code  int
+		}{
+			{"/admin/users", "", http.StatusForbidden},
+			{"/admin/users", "admin", http.StatusOK},
+			{"/posts", "", http.StatusOK},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("X-Scope", test.scope)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Errorf("expect status code of %q with scope %q to be %d, but got %d", test.path, test.scope, test.code, w.Code)
+			}
+		}
+	}
+}
+
+func TestMatchedPattern(t *testing.T) {
+	var pattern string
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		pattern = MatchedPattern(req)
+	}
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Get("/", handler)
+		v1 := r.Group("/v1")
+		v1.Get("/users/<id>", handler)
+		v1.Get("/posts", handler).Meta("scope", "admin")
+		r.Prepare()
+
+		tests := []struct {
+			path    string
+			pattern string
+		}{
+			{"/", "/"},
+			{"/v1/users/1", "/v1/users/<id>"},
+			{"/v1/posts", "/v1/posts"},
+		}
+		for _, test := range tests {
+			pattern = ""
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, test.path, nil))
+			if pattern != test.pattern {
+				t.Errorf("expect matched pattern of %q to be %q, but got %q", test.path, test.pattern, pattern)
+			}
+		}
+	}
+
+	if pattern := MatchedPattern(httptest.NewRequest(http.MethodGet, "/", nil)); pattern != "" {
+		t.Errorf("expect no matched pattern, but got %q", pattern)
+	}
+}
+
+func TestRouter_BasePath(t *testing.T) {
+	r := New()
+	r.BasePath = "/myapp"
+	r.TrailingSlashesPolicy = AppendTrailingSlashes
+	r.Get("/users/", emptyHandler)
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/users?page=2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusMovedPermanently, w.Code)
+	}
+	location := "/myapp/users/?page=2"
+	if w.Header().Get("Location") != location {
+		t.Errorf("expect header Location to be %q, but got %q", location, w.Header().Get("Location"))
+	}
+}
+
+// Configure automatic OPTIONS responses
+func TestRouter_OptionsHandler3(t *testing.T) {
+	r := New()
+	r.OptionsMaxAge = time.Hour
+	r.OptionsHeader = http.Header{"Content-Type": {"text/plain"}, "X-Router": {"root"}}
+	r.OptionsBody = []byte("root")
+	r.Delete(`/users`, emptyHandler)
+	v1 := r.Group("v1")
+	v1.OptionsMaxAge = 10 * time.Minute
+	v1.OptionsHeader = http.Header{"X-Router": {"v1"}}
+	v1.Get(`/users`, emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path        string
+		allow       string
+		maxAge      string
+		router      string
+		contentType string
+		body        string
+	}{
+		{"/users", "DELETE", "3600", "root", "text/plain", "root"},
+		{"/v1/users", "GET, HEAD", "600", "v1", "text/plain", "root"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, test.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Header().Get("Allow") != test.allow {
+			t.Errorf("expect header Allow of %q to be %q, but got %q", test.path, test.allow, w.Header().Get("Allow"))
+		}
+		if w.Header().Get("Access-Control-Max-Age") != test.maxAge {
+			t.Errorf("expect header Access-Control-Max-Age of %q to be %q, but got %q", test.path, test.maxAge, w.Header().Get("Access-Control-Max-Age"))
+		}
+		if w.Header().Get("X-Router") != test.router {
+			t.Errorf("expect header X-Router of %q to be %q, but got %q", test.path, test.router, w.Header().Get("X-Router"))
+		}
+		if w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("expect header Content-Type of %q to be %q, but got %q", test.path, test.contentType, w.Header().Get("Content-Type"))
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect response body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+	}
+}
+
+// Default MethodNotAllowedHandler with content negotiation
+func TestRouter_MethodNotAllowedHandler3(t *testing.T) {
+	r := New()
+	r.Post(`/users`, emptyHandler)
+	r.Put(`/users`, emptyHandler)
+	r.Delete(`/users`, emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"application/json", "application/json; charset=utf-8", `{"allowed":["DELETE","POST","PUT"],"error":"Method Not Allowed"}`},
+		{"text/html", "text/html; charset=utf-8", "<!DOCTYPE html>\n<html><head><title>Method Not Allowed</title></head><body><h1>Method Not Allowed</h1><p>Allowed methods: DELETE, POST, PUT</p></body></html>\n"},
+		{"", "text/plain; charset=utf-8", "Method Not Allowed\n"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, `/users`, nil)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expect status code to be %d, but got %d", http.StatusMethodNotAllowed, w.Code)
+		}
+		if allow := "DELETE, POST, PUT"; w.Header().Get("Allow") != allow {
+			t.Errorf("expect header Allow to be %q, but got %q", allow, w.Header().Get("Allow"))
+		}
+		if w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("expect header Content-Type of %q to be %q, but got %q", test.accept, test.contentType, w.Header().Get("Content-Type"))
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect response body of %q to be %q, but got %q", test.accept, test.body, w.Body.String())
+		}
+	}
+}
+
+// Group MethodNotAllowedHandler
+func TestRouter_MethodNotAllowedHandler4(t *testing.T) {
+	r := New()
+	r.MethodNotAllowedHandler = func(w http.ResponseWriter, r *http.Request, methods []string) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("root"))
+	}
+	r.Post(`/users`, emptyHandler)
+	api := r.Group("api")
+	api.MethodNotAllowedHandler = func(w http.ResponseWriter, r *http.Request, methods []string) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("api"))
+	}
+	api.Post(`/users`, emptyHandler)
+	api.Group("v1").Post(`/users`, emptyHandler)
+	r.Prepare()
+
+	tests := map[string]string{
+		"/users":        "root",
+		"/api/users":    "api",
+		"/api/v1/users": "api",
+	}
+	for path, body := range tests {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != body {
+			t.Errorf("expect response body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_Lookup(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, newHeaderMiddleware("Middleware", "Root"))
+	r.Get("/", helloHandler("home"))
+	r.Group("v1").Get(`/users/<id:\d+>`, helloHandler("user"))
+	r.Prepare()
+
+	tests := []struct {
+		method  string
+		path    string
+		matched bool
+		body    string
+		params  map[string]string
+	}{
+		{http.MethodGet, "/", true, "home", nil},
+		{http.MethodGet, "/v1/users/1", true, "user", map[string]string{"id": "1"}},
+		{http.MethodGet, "/v1/users/foo", false, "", nil},
+		{http.MethodPost, "/", false, "", nil},
+	}
+	for _, test := range tests {
+		handler, params, matched := r.Lookup(test.method, test.path)
+		if matched != test.matched {
+			t.Errorf("expect %s %q matched to be %v, but got %v", test.method, test.path, test.matched, matched)
+			continue
+		}
+		if !reflect.DeepEqual(params, test.params) {
+			t.Errorf("expect params of %s %q to be %v, but got %v", test.method, test.path, test.params, params)
+		}
+		if !matched {
+			if handler != nil {
+				t.Errorf("expect nil handler of %s %q, but got %v", test.method, test.path, handler)
+			}
+			continue
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Body.String() != test.body {
+			t.Errorf("expect response body of %s %q to be %q, but got %q", test.method, test.path, test.body, w.Body.String())
+		}
+		if w.Header().Get("Middleware") != "Root" {
+			t.Errorf("expect handler of %s %q to be chained with middleware", test.method, test.path)
+		}
+	}
+}
+
+func BenchmarkRouter_Lookup(b *testing.B) {
+	r := New()
+	r.Get(`/users/<id:\d+>/posts/<title>`, emptyHandler)
+	r.Prepare()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Lookup(http.MethodGet, "/users/1/posts/hello")
+	}
+}
+
+// Catch-all parameter
+func TestRouter_ServeFiles3(t *testing.T) {
+	r := New()
+	r.ServeFiles("/tmp/<*filepath>", os.TempDir())
+	r.Prepare()
+
+	dir, err := ioutil.TempDir("", "fastrouter")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, "file"), []byte("TestRouter_ServeFiles3"), 0666); err != nil {
+		t.Fatalf("failed to create tmp file: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tmp/"+path.Base(dir)+"/file", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if body := "TestRouter_ServeFiles3"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+}
+
+func TestRouter_ReadOnly(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler)
+	r.Post("/users", emptyHandler)
+	r.Delete("/users/<id>", emptyHandler)
+	r.ReadOnly = true
+	r.Prepare()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{http.MethodGet, "/users", http.StatusOK, ""},
+		{http.MethodPost, "/users", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodDelete, "/users/1", http.StatusMethodNotAllowed, ""},
+		{http.MethodPut, "/not-found", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/not-found", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %s to be %d, but got %d", test.method, test.path, test.code, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("expect Allow header of %s %s to be %q, but got %q", test.method, test.path, test.allow, allow)
+		}
+	}
+
+	r.ReadOnlyStatusCode = http.StatusForbidden
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestRouter_AllowedMethods(t *testing.T) {
+	r := New()
+	r.AllowedMethods = []string{http.MethodGet, http.MethodPost}
+	r.Get("/users", emptyHandler)
+	r.Handle("PROPFIND", "/users", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/users", http.StatusOK},
+		{http.MethodPost, "/users", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/not-found", http.StatusNotFound},
+		{"PROPFIND", "/users", http.StatusNotImplemented},
+		{http.MethodDelete, "/not-found", http.StatusNotImplemented},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s %s to be %d, but got %d", test.method, test.path, test.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_Any(t *testing.T) {
+	r := New()
+	routes := r.Any("/any", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Method))
+	})
+	r.Prepare()
+
+	if len(routes) != len(standardMethods) {
+		t.Fatalf("expect %d routes, but got %d", len(standardMethods), len(routes))
+	}
+	for i, method := range standardMethods {
+		if routes[i].method != method {
+			t.Errorf("expect method of route to be %q, but got %q", method, routes[i].method)
+		}
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/any", nil)
+		r.ServeHTTP(w, req)
+		if w.Body.String() != method {
+			t.Errorf("expect body to be %q, but got %q", method, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_Match(t *testing.T) {
+	r := New()
+	routes := r.Match([]string{http.MethodGet, http.MethodPost}, "/match", emptyHandler, newHeaderMiddleware("X-Match", "yes"))
+	r.Prepare()
+
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, but got %d", len(routes))
+	}
+
+	tests := []struct {
+		method string
+		code   int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusOK},
+		{http.MethodPut, http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, "/match", nil)
+		r.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expect status code of %s to be %d, but got %d", test.method, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Header().Get("X-Match") != "yes" {
+			t.Errorf("expect header X-Match to be %q, but got %q", "yes", w.Header().Get("X-Match"))
+		}
+	}
+
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for empty methods, but got nil")
+		}
+	}()
+	r.Match(nil, "/match", emptyHandler)
+}
+
+func TestRouter_Shortcuts(t *testing.T) {
+	r := New()
+	shortcuts := map[string]func(string, http.HandlerFunc, ...Middleware) *Route{
+		http.MethodHead:    r.Head,
+		http.MethodPatch:   r.Patch,
+		http.MethodOptions: r.Options,
+		http.MethodConnect: r.Connect,
+		http.MethodTrace:   r.Trace,
+	}
+	for _, shortcut := range shortcuts {
+		shortcut("/", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Method", req.Method)
+		})
+	}
+	r.Prepare()
+
+	for method := range shortcuts {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/", nil)
+		r.ServeHTTP(w, req)
+		if w.Header().Get("X-Method") != method {
+			t.Errorf("expect header X-Method to be %q, but got %q", method, w.Header().Get("X-Method"))
+		}
+	}
+}
+
+func TestRouter_Head(t *testing.T) {
+	r := New()
+	r.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Method", req.Method)
+		w.Write([]byte("users"))
+	})
+	r.Get("/posts", emptyHandler)
+	r.Head("/posts", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Method", "explicit")
+	})
+	r.Prepare()
+
+	tests := []struct {
+		path   string
+		method string
+		body   string
+	}{
+		{"/users", http.MethodHead, ""},
+		{"/posts", "explicit", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodHead, test.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expect status code of %q to be %d, but got %d", test.path, http.StatusOK, w.Code)
+		}
+		if w.Header().Get("X-Method") != test.method {
+			t.Errorf("expect header X-Method of %q to be %q, but got %q", test.path, test.method, w.Header().Get("X-Method"))
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+	}
+
+	if _, _, matched := r.Lookup(http.MethodHead, "/users"); !matched {
+		t.Error("expect HEAD request to be matched by GET route")
+	}
+}
+
+func TestRouter_OptionsDescribe(t *testing.T) {
+	r := New()
+	r.OptionsDescribe = true
+	v1 := r.Group("v1")
+	v1.Get("/users", emptyHandler).Description("List users")
+	v1.Post("/users", emptyHandler).Description("Create an user")
+	v1.Get("/posts", emptyHandler)
+	r.Get("/posts", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/v1/users", `{"allow":["GET","HEAD","POST"],"routes":[{"method":"GET","pattern":"/v1/users","description":"List users"},{"method":"HEAD","pattern":"/v1/users","description":"List users"},{"method":"POST","pattern":"/v1/users","description":"Create an user"}]}`},
+		{"/posts", `{"allow":["GET","HEAD"],"routes":[{"method":"GET","pattern":"/posts"},{"method":"HEAD","pattern":"/posts"}]}`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, test.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %q to be %s, but got %s", test.path, test.body, w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+			t.Errorf("expect JSON content type, but got %q", w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestRouter_Methods(t *testing.T) {
+	r := New()
+	r.Handle("PROPFIND", "/dav/<*filepath>", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+	})
+	r.Handle("MKCOL", "/dav/<*filepath>", emptyHandler)
+	r.Group("v1").Get("/users", emptyHandler)
+	r.Prepare()
+
+	expect := []string{http.MethodGet, "MKCOL", "PROPFIND"}
+	if methods := r.Methods(); !compareSlice(expect, methods) {
+		t.Errorf("expect methods to be %v, but got %v", expect, methods)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PROPFIND", "/dav/docs/a.txt", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMultiStatus {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusMultiStatus, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("REPORT", "/dav/docs/a.txt", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "MKCOL, PROPFIND" {
+		t.Errorf("expect header Allow to be %q, but got %q", "MKCOL, PROPFIND", allow)
+	}
+
+	for _, method := range []string{"", "PROP FIND", "GET/"} {
+		func() {
+			defer func() {
+				if rcv := recover(); rcv == nil {
+					t.Errorf("expect a panic for method %q, but got nil", method)
+				}
+			}()
+			r.Handle(method, "/", emptyHandler)
+		}()
+	}
+}
+
+func TestRouter_Group5(t *testing.T) {
+	r := New()
+	api := r.Group("api")
+	api.Get("/", helloHandler("api"))
+	v1 := r.Group("/api/v1/")
+	v1.Get("/users", helloHandler("v1 users"))
+	if v1.parent != api || v1.prefix != "v1" {
+		t.Errorf("expect group v1 to be a child of api, but got parent %v and prefix %q", v1.parent, v1.prefix)
+	}
+	r.Prepare()
+
+	tests := map[string]string{
+		"/api":          "api",
+		"/api/v1/users": "v1 users",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for existing group, but got nil")
+		}
+	}()
+	r.Group("api/v1")
+}
+
+func TestRouter_GroupIntermediate(t *testing.T) {
+	// the intermediate group is taken over regardless of the order.
+	r := New()
+	v1 := r.Group("api/v1")
+	v1.Get("/users", helloHandler("v1 users"))
+	api, err := r.GroupE("api")
+	if err != nil {
+		t.Fatalf("expect the intermediate group to be taken over, but got %v", err)
+	}
+	api.Get("/", helloHandler("api"))
+	if v1.parent != api {
+		t.Errorf("expect group v1 to be a child of api, but got parent %v", v1.parent)
+	}
+	r.Prepare()
+
+	tests := map[string]string{
+		"/api":          "api",
+		"/api/v1/users": "v1 users",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+
+	// the group which was taken over is not taken over again.
+	if _, err := r.GroupE("api"); !errors.Is(err, ErrInvalidGroupPrefix) {
+		t.Errorf("expect err to wrap %v, but got %v", ErrInvalidGroupPrefix, err)
+	}
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Error("expect a panic for existing group, but got nil")
+		}
+	}()
+	r.Group("api")
+}
+
+func TestRouter_Group6(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		params := Params(req)
+		fmt.Fprintf(w, "%s,%s", params["id"], params["post"])
+	}
+
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Group("users").Get("/me", handler)
+		users := r.Group(`users/<id:\d+>`)
+		users.Get("/", handler)
+		users.Get("/posts/<post>", handler).Name("post")
+		users.Get("/<post>", handler)
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/users/1", http.StatusOK, "1,"},
+			{"/users/1/posts/hello", http.StatusOK, "1,hello"},
+			{"/users/2/world", http.StatusOK, "2,world"},
+			{"/users/me", http.StatusOK, ","},
+			{"/users/foo/posts/hello", http.StatusNotFound, ""},
+			{"/users/<id:\\d+>/posts/hello", http.StatusNotFound, ""},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if w.Code != test.code {
+				t.Errorf("pooled %t: expect status code of %q to be %d, but got %d", pooled, test.path, test.code, w.Code)
+			}
+			if test.code == http.StatusOK && w.Body.String() != test.body {
+				t.Errorf("pooled %t: expect body of %q to be %q, but got %q", pooled, test.path, test.body, w.Body.String())
+			}
+		}
+
+		if url, err := r.URL("post", "id", "1", "post", "hello"); err != nil || url != "/users/1/posts/hello" {
+			t.Errorf("expect url to be %q, but got %q, %v", "/users/1/posts/hello", url, err)
+		}
+		if _, err := r.URL("post", "id", "foo", "post", "hello"); err == nil {
+			t.Error("expect an error for invalid group parameter, but got nil")
+		}
+		if _, params, _ := r.Lookup(http.MethodGet, "/users/3/posts/x"); params["id"] != "3" || params["post"] != "x" {
+			t.Errorf("expect params to contain id and post, but got %v", params)
+		}
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	r := New()
+	admin := r.Route("/admin", func(admin *Router) {
+		admin.Route("users", func(users *Router) {
+			users.Get("/", helloHandler("users"))
+			users.Route("<id>", func(user *Router) {
+				user.Get("/", func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte("user " + Params(req)["id"]))
+				})
+			})
+		})
+	}, newHeaderMiddleware("X-Admin", "yes"))
+	r.Prepare()
+
+	if admin.prefix != "admin" || admin.parent != r {
+		t.Errorf("expect admin group to be a child of root, but got prefix %q", admin.prefix)
+	}
+
+	tests := map[string]string{
+		"/admin/users":   "users",
+		"/admin/users/1": "user 1",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+		if w.Header().Get("X-Admin") != "yes" {
+			t.Errorf("expect header X-Admin of %q to be %q, but got %q", path, "yes", w.Header().Get("X-Admin"))
+		}
+	}
+}
+
+func TestRouter_NotFoundHandler3(t *testing.T) {
+	r := New()
+	r.NotFoundHandler = helloHandler("root")
+	app := r.Group("app")
+	app.NotFoundHandler = helloHandler("app")
+	app.Get("/users", helloHandler("users"))
+	app.Group("v1").Get("/posts", helloHandler("posts")).Flag("beta", nil)
+	r.FlagProvider = FlagProviderFunc(func(req *http.Request, flag string) bool {
+		return false
+	})
+	r.Prepare()
+
+	tests := map[string]string{
+		"/app/users":    "users",
+		"/app/unknown":  "app",
+		"/app/v1/posts": "app",
+		"/app/v1/x":     "app",
+		"/unknown":      "root",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_Mount(t *testing.T) {
+	sub := New()
+	sub.Get("/", helloHandler("index"))
+	sub.Get("/<name>", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + Params(req)["name"]))
+	})
+	sub.Prepare()
+
+	var calls int
+	r := New()
+	r.Middleware = append(r.Middleware, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.Mount("/apps/sub", sub)
+	r.Mount("users/<id>/files", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + Params(req)["id"]))
+	}))
+	r.Get("/", helloHandler("root"))
+	r.Prepare()
+
+	tests := map[string]string{
+		"/":                  "root",
+		"/apps/sub":          "index",
+		"/apps/sub/":         "index",
+		"/apps/sub/foo":      "/foo foo",
+		"/users/1/files/a/b": "/a/b 1",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+		if req.URL.Path != path {
+			t.Errorf("expect the original request path to be %q, but got %q", path, req.URL.Path)
+		}
+	}
+	if calls != len(tests) {
+		t.Errorf("expect the middleware to be called %d times, but got %d", len(tests), calls)
+	}
+}
+
+func TestRouter_MatchedMiddleware(t *testing.T) {
+	var logs []string
+	logger := func(tag string) MatchedMiddleware {
+		return func(info RouteInfo, next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				logs = append(logs, tag+":"+info.Method+" "+info.Prefix+info.Pattern+" "+Param(req, "id"))
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	r := New()
+	r.MatchedMiddleware = append(r.MatchedMiddleware, logger("root"))
+	r.Middleware = append(r.Middleware, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			logs = append(logs, "middleware")
+			next.ServeHTTP(w, req)
+		})
+	})
+	admin := r.Group("admin")
+	admin.MatchedMiddleware = append(admin.MatchedMiddleware, logger("admin"))
+	admin.Get("/users/<id>", helloHandler("user"))
+	r.Get("/", helloHandler("index"))
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		logs []string
+	}{
+		{"/admin/users/1", []string{"root:GET /admin/users/<id> 1", "admin:GET /admin/users/<id> 1", "middleware"}},
+		{"/", []string{"root:GET / ", "middleware"}},
+		{"/unknown", nil},
+	}
+	for _, test := range tests {
+		logs = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if !reflect.DeepEqual(logs, test.logs) {
+			t.Errorf("expect logs of %q to be %v, but got %v", test.path, test.logs, logs)
+		}
+	}
+}
+
+func TestRouter_PanicHandler2(t *testing.T) {
+	r := New()
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		w.Write([]byte("root"))
+	}
+	api := r.Group("api")
+	api.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error":"internal"}`))
+	}
+	panicHandler := func(w http.ResponseWriter, req *http.Request) {
+		panic("panic message")
+	}
+	api.Group("v1").Get("/panic", panicHandler)
+	r.Get("/panic", panicHandler)
+	r.Prepare()
+
+	tests := map[string]string{
+		"/api/v1/panic": `{"error":"internal"}`,
+		"/panic":        "root",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_DisableAutoHead(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler)
+	r.Head("/posts", emptyHandler)
+	r.Prepare()
+
+	expect := []string{http.MethodGet, http.MethodHead}
+	if methods := r.retrieveMethods("/users"); !reflect.DeepEqual(methods, expect) {
+		t.Errorf("expect methods to be %v, but got %v", expect, methods)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/users", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+
+	r.DisableAutoHead = true
+	expect = []string{http.MethodGet}
+	if methods := r.retrieveMethods("/users"); !reflect.DeepEqual(methods, expect) {
+		t.Errorf("expect methods to be %v, but got %v", expect, methods)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if _, _, matched := r.Lookup(http.MethodHead, "/users"); matched {
+		t.Error("expect HEAD request not to match GET route")
+	}
+}
+
+func TestRouter_NotImplementedHandler(t *testing.T) {
+	r := New()
+	r.Get("/users", nil)
+	api := r.Group("api")
+	api.NotImplementedHandler = helloHandler("api")
+	api.Post("/users", nil, newHeaderMiddleware("Middleware", "Users"))
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusNotImplemented, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users", nil))
+	if w.Body.String() != "api" || w.Header().Get("Middleware") != "Users" {
+		t.Errorf("expect the reserved route to be handled by group handler with middleware, but got %q, %v", w.Body.String(), w.Header())
+	}
+}
+
+func TestRouter_CleanPath(t *testing.T) {
+	r := New()
+	r.CleanPath = true
+	r.Get("/users/<id>", emptyHandler)
+	r.Post("/users/", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/users/1", http.StatusOK, ""},
+		{http.MethodGet, "//users//1", http.StatusMovedPermanently, "/users/1"},
+		{http.MethodGet, "/users/./1/../2?page=1", http.StatusMovedPermanently, "/users/2?page=1"},
+		{http.MethodGet, "/../users/1", http.StatusMovedPermanently, "/users/1"},
+		{http.MethodPost, "/users//", http.StatusPermanentRedirect, "/users/"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, "/", nil)
+		req.URL.Path, req.URL.RawQuery = test.path, ""
+		if i := strings.IndexByte(test.path, '?'); i >= 0 {
+			req.URL.Path, req.URL.RawQuery = test.path[:i], test.path[i+1:]
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != test.code || w.Header().Get("Location") != test.location {
+			t.Errorf("expect response of %s %q to be %d %q, but got %d %q", test.method, test.path, test.code, test.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRouter_UseRawPath(t *testing.T) {
+	for _, unescape := range []bool{false, true} {
+		r := New()
+		r.UseRawPath = true
+		r.UnescapePathValues = unescape
+		r.Get("/files/<name>", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(Param(req, "name")))
+		})
+		r.Get("/files/<dir>/<name>", helloHandler("nested"))
+		r.Prepare()
+
+		expect := "a%2Fb%20c"
+		if unescape {
+			expect = "a/b c"
+		}
+		tests := map[string]string{
+			"/files/a%2Fb%20c": expect,
+			"/files/a/b":       "nested",
+			"/files/a%20b":     "a b",
+		}
+		for path, body := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Body.String() != body {
+				t.Errorf("unescape %t: expect body of %q to be %q, but got %q", unescape, path, body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRouter_UseRawPathRedirect(t *testing.T) {
+	tests := []struct {
+		policy   int8
+		path     string
+		location string
+	}{
+		{AppendTrailingSlashes, "/files/a%2Fb", "/base/files/a%2Fb/"},
+		{AppendTrailingSlashes, "/files/a%2Fb%20c?v=1", "/base/files/a%2Fb%20c/?v=1"},
+		{RemoveTrailingSlashes, "/files/a%2Fb/", "/base/files/a%2Fb"},
+		{RemoveTrailingSlashes, "/files/a%2F", ""},
+		{AppendTrailingSlashes, "/files/a", "/base/files/a/"},
+	}
+	for _, test := range tests {
+		r := New()
+		r.UseRawPath = true
+		r.BasePath = "/base"
+		r.TrailingSlashesPolicy = test.policy
+		r.Get("/files/<name>", emptyHandler)
+		r.Prepare()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("policy %d: expect Location of %q to be %q, but got %q", test.policy, test.path, test.location, location)
+		}
+	}
+}
+
+func TestRouter_PrepareE(t *testing.T) {
+	r := New()
+	r.Get("/users/<id:\\d+>", emptyHandler)
+	if err := r.PrepareE(); err != nil {
+		t.Errorf("expect no error, but got %v", err)
+	}
+
+	r.Group("v1").Post("/posts/<id:[0-9>", emptyHandler)
+	err := r.PrepareE()
+	routeErr, ok := err.(*RouteError)
+	if !ok || routeErr.Method != http.MethodPost || routeErr.Pattern != "/posts/<id:[0-9>" || routeErr.Prefix != "/v1" {
+		t.Errorf("expect a route error, but got %#v", err)
+	}
+
+	r = New()
+	r.TrustedProxies = []string{"invalid"}
+	if err := r.PrepareE(); err == nil {
+		t.Error("expect an error for invalid trusted proxies, but got nil")
+	}
+}
+
+func TestRouter_OptionsNotFound(t *testing.T) {
+	r := New()
+	r.Get("/users", emptyHandler)
+	var methods []string
+	r.OptionsHandler = func(w http.ResponseWriter, req *http.Request, allowed []string) {
+		methods = allowed
+	}
+	r.Prepare()
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/unknown", nil))
+	if methods != nil {
+		t.Errorf("expect nil methods for unknown path, but got %v", methods)
+	}
+
+	r.OptionsNotFound = true
+	tests := map[string]int{
+		"/users":   http.StatusOK,
+		"/unknown": http.StatusNotFound,
+	}
+	for path, code := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, path, nil))
+		if w.Code != code {
+			t.Errorf("expect status code of %q to be %d, but got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestRouter_UseWith(t *testing.T) {
+	r := New()
+	r.Use(newHeaderMiddleware("X-Global", "1"))
+	r.Get("/", helloHandler("home"))
+	auth := r.With(newHeaderMiddleware("X-Auth", "1"))
+	auth.Post("/posts", helloHandler("create"), newHeaderMiddleware("X-Route", "1"))
+	auth.With(newHeaderMiddleware("X-Admin", "1")).Route("/admin", func(admin *Router) {
+		admin.Get("/users", helloHandler("users"))
+	})
+	r.Prepare()
+
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		headers []string
+	}{
+		{http.MethodGet, "/", "home", []string{"X-Global"}},
+		{http.MethodPost, "/posts", "create", []string{"X-Global", "X-Auth", "X-Route"}},
+		{http.MethodGet, "/admin/users", "users", []string{"X-Global", "X-Auth", "X-Admin"}},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %s %q to be %q, but got %q", test.method, test.path, test.body, w.Body.String())
+		}
+		for _, header := range test.headers {
+			if w.Header().Get(header) != "1" {
+				t.Errorf("expect header %s of %s %q to be set", header, test.method, test.path)
+			}
+		}
+		if len(w.Header()) != len(test.headers)+1 {
+			t.Errorf("expect headers of %s %q to be %v, but got %v", test.method, test.path, test.headers, w.Header())
+		}
+	}
+}
+
+func TestRouter_WrapErrorHandlers(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		r := New()
+		r.WrapErrorHandlers = wrap
+		r.Use(newHeaderMiddleware("X-Root", "1"))
+		r.Get("/", emptyHandler)
+		api := r.Group("/api")
+		api.Use(newHeaderMiddleware("X-API", "1"))
+		api.Get("/users", emptyHandler)
+		r.Prepare()
+
+		tests := []struct {
+			method  string
+			path    string
+			code    int
+			headers []string
+		}{
+			{http.MethodGet, "/posts", http.StatusNotFound, []string{"X-Root"}},
+			{http.MethodPost, "/", http.StatusMethodNotAllowed, []string{"X-Root"}},
+			{http.MethodOptions, "/api/users", http.StatusOK, []string{"X-Root", "X-API"}},
+			{http.MethodGet, "/api/posts", http.StatusNotFound, []string{"X-Root", "X-API"}},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+			if w.Code != test.code {
+				t.Errorf("expect status code of %s %q to be %d, but got %d", test.method, test.path, test.code, w.Code)
+			}
+			for _, header := range test.headers {
+				if wrapped := w.Header().Get(header) == "1"; wrapped != wrap {
+					t.Errorf("expect header %s of %s %q to be wrapped: %t, but got %t", header, test.method, test.path, wrap, wrapped)
+				}
+			}
+		}
+	}
+}
+
+func TestRouter_AdoptGroup(t *testing.T) {
+	auth := New()
+	auth.Middleware = append(auth.Middleware, newHeaderMiddleware("X-Module", "auth"))
+	auth.Get("/login", helloHandler("login")).Name("login")
+	auth.Group("users/<id>").Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("user " + Param(req, "id")))
+	})
+
+	r := New()
+	r.Middleware = append(r.Middleware, newHeaderMiddleware("X-App", "1"))
+	r.AdoptGroup("api/auth", auth)
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/api/auth/login", "login"},
+		{"/api/auth/users/1", "user 1"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Body.String() != test.body {
+			t.Errorf("expect body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+		}
+		if w.Header().Get("X-App") != "1" || w.Header().Get("X-Module") != "auth" {
+			t.Errorf("expect middleware of app and module to be applied to %q, but got %v", test.path, w.Header())
+		}
+	}
+
+	if u, err := r.URL("login"); err != nil || u != "/api/auth/login" {
+		t.Errorf("expect URL of login to be %q, but got %q, %v", "/api/auth/login", u, err)
+	}
+
+	func() {
+		defer func() {
+			if rcv := recover(); rcv == nil {
+				t.Error("expect a panic for adopting a group twice")
+			}
+		}()
+		New().AdoptGroup("auth", auth)
+	}()
+}
@@ -0,0 +1,305 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// RouteInfo is the information of a registered route, it is used
+// for generating documentation and debugging route tables.
+type RouteInfo struct {
+	// The request method.
+	Method string
+
+	// The pattern of route, it is relative to the group.
+	Pattern string
+
+	// The route name, empty if the route is unnamed.
+	Name string
+
+	// The full prefix of the group which the route belongs to, such
+	// as "/v1/users", empty if the route belongs to the root router.
+	Prefix string
+
+	// The external path of route which consists of the BasePath, the
+	// prefixes of groups, including the parameterized ones, and the
+	// pattern, such as "/myapp/v1/users/<id>", it is consistent with
+	// the path that reversed via URL.
+	Path string
+
+	// The number of middleware that the route passes through,
+	// including the middleware of router and its parents.
+	MiddlewareCount int
+
+	// The middleware that the route passes through, in order of
+	// chaining, the outermost first.
+	Middleware []MiddlewareInfo
+
+	// Indicates whether the GET route also handles the HEAD requests
+	// via automatic HEAD, see Router.DisableAutoHead.
+	AutoHead bool
+}
+
+// MiddlewareInfo is the information of a middleware.
+type MiddlewareInfo struct {
+	// The name of middleware, it is the name given by NamedMiddleware,
+	// or resolved via reflection, such as
+	// "github.com/razonyang/fastrouter.ResponseTimeout".
+	Name string
+
+	// The full prefix of the router which the middleware is attached
+	// to, empty if the middleware is attached to the root router or
+	// route.
+	Prefix string
+
+	// Indicates whether the middleware is attached to the route via
+	// Handle, rather than the Middleware of router.
+	Route bool
+}
+
+// NamedMiddleware names the middleware, so that the introspection,
+// such as Routes, and Route.SkipMiddleware can refer to the middleware
+// by the given name rather than the function name or position:
+//
+//	r.Middleware = append(r.Middleware, fastrouter.NamedMiddleware("auth", auth))
+//	r.Get("/login", login).SkipMiddleware("auth")
+func NamedMiddleware(name string, m Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		if probe, ok := next.(*middlewareNameProbe); ok {
+			probe.name = name
+			return probe
+		}
+		return m(next)
+	}
+}
+
+// middlewareNameProbe is a http.Handler for resolving the name of the
+// middleware returned by NamedMiddleware.
+type middlewareNameProbe struct {
+	http.Handler
+	name string
+}
+
+// namedMiddlewarePC is the entry of the middleware closure returned by
+// NamedMiddleware.
+var namedMiddlewarePC = reflect.ValueOf(NamedMiddleware("", nil)).Pointer()
+
+// middlewareName returns the name of middleware that given by
+// NamedMiddleware, or the function name of middleware, the suffixes
+// of closures and method values are trimmed, so that the middleware
+// returned by a factory function is named after the factory function.
+func middlewareName(m Middleware) string {
+	pc := reflect.ValueOf(m).Pointer()
+	if pc == namedMiddlewarePC {
+		probe := &middlewareNameProbe{}
+		m(probe)
+		return probe.name
+	}
+
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return ""
+	}
+
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	for {
+		i := strings.LastIndex(name, ".func")
+		if i < 0 || strings.Trim(name[i+5:], "0123456789.") != "" {
+			break
+		}
+		name = name[:i]
+	}
+
+	return name
+}
+
+// Use appends the given middleware to the route, they run after the
+// middleware which was passed to Handle, it is usually used for
+// configuring route fluently:
+//
+//	r.Post("/upload", upload).Use(authMiddleware).MaxBodySize(10 << 20)
+//
+// Returns the route itself for chaining.
+func (route *Route) Use(middleware ...Middleware) *Route {
+	route.middleware = append(route.middleware, middleware...)
+	return route
+}
+
+// SkipMiddleware skips the middleware of router and its parents which
+// name is one of the given names, it is usually used with
+// NamedMiddleware, for example, skipping the auth middleware for the
+// login route.
+//
+// Returns the route itself for chaining.
+func (route *Route) SkipMiddleware(names ...string) *Route {
+	route.skipMiddleware = append(route.skipMiddleware, names...)
+	return route
+}
+
+// skip returns the middleware except the ones to be skipped.
+func (route *Route) skip(middleware []Middleware) []Middleware {
+	if len(route.skipMiddleware) == 0 {
+		return middleware
+	}
+
+	filtered := make([]Middleware, 0, len(middleware))
+	for _, m := range middleware {
+		if !route.skips(middlewareName(m)) {
+			filtered = append(filtered, m)
+		}
+	}
+
+	return filtered
+}
+
+// skips reports whether the middleware with the given name is skipped.
+func (route *Route) skips(name string) bool {
+	for _, v := range route.skipMiddleware {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// middlewareInfo returns the information of middleware of router
+// and its parents, in order of chaining.
+func (r *Router) middlewareInfo() []MiddlewareInfo {
+	var infos []MiddlewareInfo
+	if r.parent != nil {
+		infos = r.parent.middlewareInfo()
+	}
+
+	prefix := r.fullPrefix()
+	for _, m := range r.Middleware {
+		infos = append(infos, MiddlewareInfo{Name: middlewareName(m), Prefix: prefix})
+	}
+
+	return infos
+}
+
+// info returns the information of route.
+func (route *Route) info() RouteInfo {
+	var middleware []MiddlewareInfo
+	for _, m := range route.router.middlewareInfo() {
+		if !route.skips(m.Name) {
+			middleware = append(middleware, m)
+		}
+	}
+	for _, m := range route.middleware {
+		middleware = append(middleware, MiddlewareInfo{Name: middlewareName(m), Route: true})
+	}
+
+	return RouteInfo{
+		Method:          route.method,
+		Pattern:         route.pattern,
+		Name:            route.name,
+		Prefix:          route.router.fullPrefix(),
+		Path:            route.path(),
+		MiddlewareCount: len(middleware),
+		Middleware:      middleware,
+		AutoHead:        route.autoHead(),
+	}
+}
+
+// path returns the external path of route, see RouteInfo.Path.
+func (route *Route) path() string {
+	path := route.router.fullPrefix()
+	if route.pattern != "/" || path == "" {
+		path += route.pattern
+	}
+
+	return route.router.basePath() + path
+}
+
+// fullPrefix returns the prefix of group which contains the prefixes
+// of its parents, such as "/v1/users".
+func (r *Router) fullPrefix() string {
+	if r.parent == nil {
+		return ""
+	}
+
+	return r.parent.fullPrefix() + "/" + r.prefix
+}
+
+// Walk walks the routes of router and its groups recursively, and
+// calls fn for each route, the walking stops if fn returns non-nil
+// error, and the error will be returned.
+//
+// The routes of router are walked in order of request method, and
+// the routes with the same method are walked in order of registration,
+// then the groups are walked in order of prefix.
+//
+// The fn MUST NOT call AddRoute and RemoveRoute.
+func (r *Router) Walk(fn func(info RouteInfo) error) error {
+	root := r.root()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	return r.walk(func(route *Route) error {
+		return fn(route.info())
+	})
+}
+
+// sortedMethods returns the request methods of routes in alphabetical
+// order, so that the iteration order does not depend on the map.
+func (r *Router) sortedMethods() []string {
+	methods := make([]string, 0, len(r.routes))
+	for method := range r.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// sortedPrefixes returns the prefixes of groups in alphabetical order,
+// so that the iteration order does not depend on the map.
+func (r *Router) sortedPrefixes() []string {
+	prefixes := make([]string, 0, len(r.groups))
+	for prefix := range r.groups {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+// walk walks the routes in order of Walk.
+func (r *Router) walk(fn func(route *Route) error) error {
+	for _, method := range r.sortedMethods() {
+		for _, route := range r.routes[method] {
+			if err := fn(route); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		if err := r.groups[prefix].walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Routes returns the information of all routes of router and its
+// groups, in order of Walk.
+func (r *Router) Routes() []RouteInfo {
+	routes := []RouteInfo{}
+	r.Walk(func(info RouteInfo) error {
+		routes = append(routes, info)
+		return nil
+	})
+
+	return routes
+}
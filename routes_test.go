@@ -0,0 +1,158 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouter_Routes(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, newHeaderMiddleware("Middleware", "Root"))
+	r.Post("/users", emptyHandler, newHeaderMiddleware("Body-Limit", "1024"))
+	r.Get("/users", emptyHandler).Name("users")
+	r.Get("/", emptyHandler)
+	v2 := r.Group("v2")
+	v2.Get("/", emptyHandler)
+	v1 := r.Group("v1")
+	v1.Middleware = append(v1.Middleware, newHeaderMiddleware("Middleware", "V1"))
+	v1.Group("users").Get("/<name>", emptyHandler).Name("user")
+
+	expect := []RouteInfo{
+		{Method: "GET", Pattern: "/users", Name: "users", Path: "/users", MiddlewareCount: 1, AutoHead: true},
+		{Method: "GET", Pattern: "/", Path: "/", MiddlewareCount: 1, AutoHead: true},
+		{Method: "POST", Pattern: "/users", Path: "/users", MiddlewareCount: 2},
+		{Method: "GET", Pattern: "/<name>", Name: "user", Prefix: "/v1/users", Path: "/v1/users/<name>", MiddlewareCount: 2, AutoHead: true},
+		{Method: "GET", Pattern: "/", Prefix: "/v2", Path: "/v2", MiddlewareCount: 1, AutoHead: true},
+	}
+	routes := r.Routes()
+	for i := range routes {
+		routes[i].Middleware = nil
+	}
+	if !reflect.DeepEqual(routes, expect) {
+		t.Errorf("expect routes to be %v, but got %v", expect, routes)
+	}
+}
+
+func TestRouter_RoutesPath(t *testing.T) {
+	r := New()
+	r.BasePath = "/myapp"
+	r.Get("/", emptyHandler).Name("home")
+	r.Group("orgs/<org>").Group("repos").Get("/<repo>", emptyHandler).Name("repo")
+
+	tests := []struct {
+		name  string
+		pairs []string
+		path  string
+		url   string
+	}{
+		{"home", nil, "/myapp/", "/myapp/"},
+		{"repo", []string{"org", "golang", "repo", "go"}, "/myapp/orgs/<org>/repos/<repo>", "/myapp/orgs/golang/repos/go"},
+	}
+	for i, route := range r.Routes() {
+		test := tests[i]
+		if route.Name != test.name || route.Path != test.path {
+			t.Errorf("expect path of route %q to be %q, but got %q of %q", test.name, test.path, route.Path, route.Name)
+		}
+		if u, err := r.URL(test.name, test.pairs...); err != nil || u != test.url {
+			t.Errorf("expect URL of route %q to be %q, but got %q, %v", test.name, test.url, u, err)
+		}
+	}
+}
+
+func authMiddleware(next http.Handler) http.Handler {
+	return next
+}
+
+func TestRouter_Routes2(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, ResponseTimeout(time.Second, "timeout"))
+	admin := r.Group("admin")
+	admin.Middleware = append(admin.Middleware, authMiddleware)
+	admin.Get("/users", emptyHandler, newHeaderMiddleware("Middleware", "Users"))
+
+	expect := []MiddlewareInfo{
+		{Name: "github.com/razonyang/fastrouter.ResponseTimeout"},
+		{Name: "github.com/razonyang/fastrouter.authMiddleware", Prefix: "/admin"},
+		{Name: "github.com/razonyang/fastrouter.newHeaderMiddleware", Route: true},
+	}
+	routes := r.Routes()
+	if len(routes) != 1 || !reflect.DeepEqual(routes[0].Middleware, expect) {
+		t.Errorf("expect middleware to be %v, but got %v", expect, routes)
+	}
+}
+
+func TestRouter_Walk(t *testing.T) {
+	r := New()
+	r.Get("/", emptyHandler)
+	r.Get("/users", emptyHandler)
+
+	expect := errors.New("stop")
+	count := 0
+	err := r.Walk(func(info RouteInfo) error {
+		count++
+		return expect
+	})
+	if err != expect {
+		t.Errorf("expect err to be %v, but got %v", expect, err)
+	}
+	if count != 1 {
+		t.Errorf("expect walking to stop after the first route, but walked %d routes", count)
+	}
+}
+
+func TestNamedMiddleware(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, NamedMiddleware("auth", newHeaderMiddleware("Auth", "true")), authMiddleware)
+	r.Get("/users", emptyHandler, NamedMiddleware("limit", newHeaderMiddleware("Limit", "10")))
+	r.Get("/login", emptyHandler).SkipMiddleware("auth")
+	r.Prepare()
+
+	expect := [][]MiddlewareInfo{
+		{
+			{Name: "auth"},
+			{Name: "github.com/razonyang/fastrouter.authMiddleware"},
+			{Name: "limit", Route: true},
+		},
+		{
+			{Name: "github.com/razonyang/fastrouter.authMiddleware"},
+		},
+	}
+	for i, info := range r.Routes() {
+		if !reflect.DeepEqual(info.Middleware, expect[i]) {
+			t.Errorf("expect middleware of %q to be %v, but got %v", info.Pattern, expect[i], info.Middleware)
+		}
+	}
+
+	tests := map[string]http.Header{
+		"/users": {"Auth": {"true"}, "Limit": {"10"}},
+		"/login": {},
+	}
+	for path, header := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if !reflect.DeepEqual(w.Header(), header) {
+			t.Errorf("expect header of %q to be %v, but got %v", path, header, w.Header())
+		}
+	}
+}
+
+func TestRouteUse(t *testing.T) {
+	r := New()
+	r.Get("/", helloHandler("hello"), newHeaderMiddleware("X-First", "1")).
+		Use(newHeaderMiddleware("X-Second", "2"))
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Header().Get("X-First") != "1" || w.Header().Get("X-Second") != "2" {
+		t.Errorf("expect both middleware to be applied, but got %v", w.Header())
+	}
+}
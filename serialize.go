@@ -0,0 +1,210 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// serializedMagic is the header of serialized prepared state, the
+// last byte is the format version.
+var serializedMagic = []byte("FRPS\x02")
+
+// parsedPattern is the parsing result of pattern.
+type parsedPattern struct {
+	reg                string
+	params             []string
+	hasTrailingSlashes bool
+
+	// indicates whether it is restored via UnmarshalBinary, that is,
+	// its regular expression was compiled by the serializing router.
+	restored bool
+}
+
+// parsedKey returns the key of the parsing result of the pattern which
+// is parsed by the given parser, since the groups may have their own
+// parsers, see AdoptGroup.
+func parsedKey(parser ParserInterface, pattern string) string {
+	return fmt.Sprintf("%T", parser) + "\x00" + pattern
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, it serializes
+// the parsing results of the patterns and group prefixes of router
+// and its groups into a compact binary blob, the handlers are not
+// included.
+//
+// The blob can be restored by UnmarshalBinary at startup, so that
+// parsing the patterns and compiling their regular expressions of
+// large route sets can be skipped.
+//
+// The blob is byte-for-byte deterministic for the same route set,
+// regardless of the order of registration, so that it can be diffed
+// and cached by content.
+func (r *Router) MarshalBinary() ([]byte, error) {
+	patterns := make(map[string]parsedPattern)
+	r.collectParsed(patterns)
+
+	keys := make([]string, 0, len(patterns))
+	for key := range patterns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(serializedMagic)
+	writeString(&buf, fmt.Sprintf("%T", r.parser))
+	writeUvarint(&buf, uint64(len(keys)))
+	for _, key := range keys {
+		parsed := patterns[key]
+		parser, pattern, _ := strings.Cut(key, "\x00")
+		writeString(&buf, parser)
+		writeString(&buf, pattern)
+		writeString(&buf, parsed.reg)
+		if parsed.hasTrailingSlashes {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		writeUvarint(&buf, uint64(len(parsed.params)))
+		for _, param := range parsed.params {
+			writeString(&buf, param)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *Router) collectParsed(patterns map[string]parsedPattern) {
+	for _, method := range r.sortedMethods() {
+		for _, route := range r.routes[method] {
+			patterns[parsedKey(r.parser, route.pattern)] = parsedPattern{
+				reg:                route.reg,
+				params:             route.params,
+				hasTrailingSlashes: route.hasTrailingSlashes,
+			}
+		}
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		group := r.groups[prefix]
+		patterns[parsedKey(r.parser, "/"+prefix)] = group.prefixParsed
+		group.collectParsed(patterns)
+	}
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, it restores
+// the parsing results serialized by MarshalBinary, the restored
+// patterns will not be parsed again while registering routes, and
+// their regular expressions will not be compiled for validation by
+// Prepare, the parsing results are looked up by the type of parser
+// and the pattern, so that the groups with their own parsers are
+// restored correctly.
+//
+// It MUST be called on the root router before registering routes, and
+// the blob MUST be serialized by a router with the same type of parser,
+// it is trusted as it is, so that it MUST NOT come from untrusted
+// sources.
+//
+// Note that, the combined regular expressions still need to be compiled
+// by Prepare, the TreeEngine is recommended since the routes represented
+// by tree require no regular expressions at all.
+func (r *Router) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, serializedMagic) {
+		return errors.New("invalid serialized prepared state")
+	}
+
+	d := &decoder{data: data[len(serializedMagic):]}
+	if parser := d.string(); parser != fmt.Sprintf("%T", r.parser) {
+		return fmt.Errorf("the prepared state is serialized with parser %q, but got %T", parser, r.parser)
+	}
+
+	count := d.uvarint()
+	parsed := make(map[string]parsedPattern)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		key := d.string() + "\x00" + d.string()
+		p := parsedPattern{reg: d.string(), hasTrailingSlashes: d.byte() == 1, restored: true}
+		n := d.uvarint()
+		for j := uint64(0); j < n && d.err == nil; j++ {
+			p.params = append(p.params, d.string())
+		}
+		parsed[key] = p
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) > 0 {
+		return errors.New("invalid serialized prepared state: trailing data")
+	}
+
+	r.parsed = parsed
+	return nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// decoder decodes the serialized prepared state, the first error
+// is recorded and the subsequent reads are no-op.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+var errTruncated = errors.New("invalid serialized prepared state: truncated data")
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.err = errTruncated
+		return 0
+	}
+
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.data)) < n {
+		d.err = errTruncated
+		return ""
+	}
+
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// countingParser counts the invocations of Parse.
+type countingParser struct {
+	Parser
+	count *int
+}
+
+func (p countingParser) Parse(pattern string) (string, []string, bool, error) {
+	*p.count++
+	return p.Parser.Parse(pattern)
+}
+
+func registerSerializeRoutes(r *Router, params *map[string]string) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		*params = Params(req)
+	}
+	r.Get("/", handler)
+	r.Get("/users/", handler)
+	r.Get(`/users/<id:\d+>`, handler)
+	r.Group("v1").Get("/posts/<year>/<title>", handler)
+	r.Get("/files/<*filepath>", handler)
+}
+
+func TestRouter_MarshalBinary(t *testing.T) {
+	count := 0
+	parser := countingParser{Parser: NewParser(), count: &count}
+	var params map[string]string
+
+	r := NewWithParser(parser)
+	registerSerializeRoutes(r, &params)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if count != 6 {
+		t.Fatalf("expect parser to be invoked %d times, but got %d", 6, count)
+	}
+
+	count = 0
+	restored := NewWithParser(parser)
+	restored.Engine = TreeEngine
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	registerSerializeRoutes(restored, &params)
+	restored.Get("/new", emptyHandler)
+	restored.Prepare()
+	if count != 1 {
+		t.Errorf("expect only the new pattern to be parsed, but parser was invoked %d times", count)
+	}
+
+	tests := map[string]map[string]string{
+		"/users/1":             {"id": "1"},
+		"/v1/posts/2017/hello": {"year": "2017", "title": "hello"},
+		"/files/css/app.css":   {"filepath": "css/app.css"},
+	}
+	for path, expect := range tests {
+		params = nil
+		w := httptest.NewRecorder()
+		restored.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expect status code of %q to be %d, but got %d", path, http.StatusOK, w.Code)
+		}
+		if !reflect.DeepEqual(params, expect) {
+			t.Errorf("expect params of %q to be %v, but got %v", path, expect, params)
+		}
+	}
+
+	// the restored regular expressions are not compiled again.
+	restored.walk(func(route *Route) error {
+		if route.restored != (route.pattern != "/new") {
+			t.Errorf("expect restored of %q to be %t, but got %t", route.pattern, route.pattern != "/new", route.restored)
+		}
+		return nil
+	})
+
+	again, _ := restored.MarshalBinary()
+	r.Get("/new", emptyHandler)
+	expect, _ := r.MarshalBinary()
+	if !reflect.DeepEqual(again, expect) {
+		t.Error("expect the serialization to be deterministic")
+	}
+}
+
+func TestRouter_UnmarshalBinary(t *testing.T) {
+	r := New()
+	r.Get("/users/<id>", emptyHandler)
+	data, _ := r.MarshalBinary()
+
+	if err := New().UnmarshalBinary([]byte("invalid")); err == nil {
+		t.Error("expect an error for invalid data, but got nil")
+	}
+	if err := New().UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expect an error for truncated data, but got nil")
+	}
+	if err := New().UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("expect an error for trailing data, but got nil")
+	}
+	if err := NewWithParser(NewColonParser()).UnmarshalBinary(data); err == nil {
+		t.Error("expect an error for different parser, but got nil")
+	}
+}
+
+func TestRouter_MarshalBinaryGroupParser(t *testing.T) {
+	build := func(data []byte) *Router {
+		r := New()
+		if data != nil {
+			if err := r.UnmarshalBinary(data); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+		}
+		r.Get("/users/:id", helloHandler("literal"))
+		r.AdoptGroup("colon", NewWithParser(NewColonParser()))
+		r.groups["colon"].Get("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(Param(req, "id")))
+		})
+		r.Prepare()
+		return r
+	}
+	data, err := build(nil).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	r := build(data)
+	tests := map[string]string{
+		"/users/:id":       "literal",
+		"/colon/users/foo": "foo",
+	}
+	for path, body := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != body {
+			t.Errorf("expect body of %q to be %q, but got %q", path, body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_MarshalBinaryDeterministic(t *testing.T) {
+	build := func(reversed bool) []byte {
+		r := New()
+		register := []func(){
+			func() { r.Get("/users/<id>", emptyHandler) },
+			func() { r.Post("/users", emptyHandler) },
+			func() { r.Group("v2").Get("/posts", emptyHandler) },
+			func() { r.Group("v1").Delete("/posts/<id>", emptyHandler) },
+		}
+		for i := range register {
+			if reversed {
+				i = len(register) - 1 - i
+			}
+			register[i]()
+		}
+		data, err := r.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		return data
+	}
+
+	expect := build(false)
+	for i := 0; i < 10; i++ {
+		if data := build(i%2 == 1); !reflect.DeepEqual(data, expect) {
+			t.Fatalf("expect serialized data to be deterministic, but got %q and %q", expect, data)
+		}
+	}
+}
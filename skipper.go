@@ -0,0 +1,60 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+)
+
+// Skipper reports whether to bypass the middleware for the request.
+type Skipper func(req *http.Request) bool
+
+// Unless returns a middleware that bypasses the given middleware if the
+// skipper reports true, so that a global middleware, such as auth and
+// logging, can be bypassed for specific routes without restructuring
+// groups:
+//
+//	r.Use(fastrouter.Unless(authMiddleware, fastrouter.SkipPaths("/health", "/metrics")))
+//
+// The skipper is called after matching, so that the matched route is
+// accessible via MatchedPattern and RouteMeta.
+func Unless(middleware Middleware, skipper Skipper) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if skipper(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			wrapped.ServeHTTP(w, req)
+		})
+	}
+}
+
+// SkipPaths returns a Skipper that reports true if the request path is
+// one of the given paths.
+func SkipPaths(paths ...string) Skipper {
+	set := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		set[path] = true
+	}
+
+	return func(req *http.Request) bool {
+		return set[req.URL.Path]
+	}
+}
+
+// SkipPatterns returns a Skipper that reports true if the full pattern
+// of matched route is one of the given patterns, see MatchedPattern.
+func SkipPatterns(patterns ...string) Skipper {
+	set := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		set[pattern] = true
+	}
+
+	return func(req *http.Request) bool {
+		return set[MatchedPattern(req)]
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnless(t *testing.T) {
+	r := New()
+	r.Use(
+		Unless(newHeaderMiddleware("X-Auth", "1"), SkipPaths("/health", "/metrics")),
+		Unless(newHeaderMiddleware("X-Log", "1"), SkipPatterns("/users/<id>")),
+	)
+	r.Get("/health", emptyHandler)
+	r.Get("/metrics", emptyHandler)
+	r.Get("/users/<id>", emptyHandler)
+	r.Get("/posts", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path string
+		auth string
+		log  string
+	}{
+		{"/health", "", "1"},
+		{"/metrics", "", "1"},
+		{"/users/1", "1", ""},
+		{"/posts", "1", "1"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if auth, log := w.Header().Get("X-Auth"), w.Header().Get("X-Log"); auth != test.auth || log != test.log {
+			t.Errorf("expect headers of %q to be %q and %q, but got %q and %q", test.path, test.auth, test.log, auth, log)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CheckStreaming verifies that the given middleware is safe for the
+// streaming routes, such as SSE and WebSocket, it is usually called in
+// tests for certifying the custom middleware:
+//
+//	if err := fastrouter.CheckStreaming(gzipMiddleware, time.Second); err != nil {
+//		t.Error(err)
+//	}
+//
+// The middleware is safe if the http.ResponseWriter passed to the
+// downstream handler preserves http.Flusher and http.Hijacker, and the
+// flushed output reaches the client within the given timeout while the
+// handler is still running, that is, the output is not buffered until
+// the handler returns.
+//
+// The request is marked with StreamingMeta, so that the middleware
+// which exempts the streaming routes can be certified.
+func CheckStreaming(middleware Middleware, timeout time.Duration) error {
+	route := &Route{meta: map[string]interface{}{StreamingMeta: true}}
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextRouteKey, route))
+
+	w := &streamingRecorder{header: make(http.Header), flushed: make(chan struct{})}
+	release := make(chan struct{})
+	result := make(chan error, 1)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			result <- errors.New("the middleware does not preserve http.Flusher")
+			return
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			result <- errors.New("the middleware does not preserve http.Hijacker")
+			return
+		}
+
+		result <- nil
+		w.Write([]byte("data: ping\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		middleware(http.HandlerFunc(handler)).ServeHTTP(w, req)
+	}()
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return err
+		}
+	case <-done:
+		return errors.New("the middleware does not call the downstream handler")
+	case <-timer.C:
+		return fmt.Errorf("the middleware does not call the downstream handler within %s", timeout)
+	}
+
+	select {
+	case <-w.flushed:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("the flushed output does not reach the client within %s", timeout)
+	}
+}
+
+// streamingRecorder is a http.ResponseWriter that implements
+// http.Flusher and http.Hijacker, it records whether any output was
+// flushed.
+type streamingRecorder struct {
+	mu      sync.Mutex
+	header  http.Header
+	written bool
+	flushed chan struct{}
+	closed  bool
+}
+
+func (w *streamingRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *streamingRecorder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(p) > 0 {
+		w.written = true
+	}
+	return len(p), nil
+}
+
+func (w *streamingRecorder) WriteHeader(code int) {}
+
+// Flush implements http.Flusher.
+func (w *streamingRecorder) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.written && !w.closed {
+		w.closed = true
+		close(w.flushed)
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *streamingRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijacking is not supported by the streaming check")
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// bufferingWriter buffers the whole response until the handler returns.
+type bufferingWriter struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferingWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *bufferingWriter) Flush() {}
+
+func bufferingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bw := &bufferingWriter{ResponseWriter: w}
+		next.ServeHTTP(bw, req)
+		w.Write(bw.buf.Bytes())
+	})
+}
+
+func TestCheckStreaming(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware Middleware
+		ok         bool
+	}{
+		{"passthrough", newHeaderMiddleware("Middleware", "Streaming"), true},
+		{"timeout", ResponseTimeout(time.Second, "timeout"), true},
+		{"buffering", bufferingMiddleware, false},
+		{"wrapping", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				next.ServeHTTP(struct{ http.ResponseWriter }{w}, req)
+			})
+		}, false},
+		{"blocking", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+		}, false},
+	}
+	for _, test := range tests {
+		err := CheckStreaming(test.middleware, 100*time.Millisecond)
+		if test.ok && err != nil {
+			t.Errorf("expect middleware %q to be streaming-safe, but got %v", test.name, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("expect middleware %q not to be streaming-safe, but got nil", test.name)
+		}
+	}
+}
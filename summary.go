@@ -0,0 +1,106 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Logger is the interface for logging, *log.Logger is an implementation.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// logger returns the Logger of root router, or the standard logger
+// if it is not set.
+func (r *Router) logger() Logger {
+	if logger := r.root().Logger; logger != nil {
+		return logger
+	}
+
+	return stdLogger{}
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// summary is a concise summary of the route table.
+type summary struct {
+	routes           map[string]int
+	groups           int
+	routerMiddleware int
+	routeMiddleware  int
+	warnings         []string
+}
+
+func (r *Router) summarize(s *summary) {
+	s.routerMiddleware += len(r.Middleware)
+
+	prefix := r.fullPrefix()
+	if r.parent != nil {
+		s.groups++
+		if len(r.routes) == 0 && len(r.groups) == 0 {
+			s.warnings = append(s.warnings, fmt.Sprintf("group %q has no routes", prefix))
+		}
+	}
+
+	root := r.root()
+	for _, method := range r.sortedMethods() {
+		patterns := make(map[string]bool)
+		for _, route := range r.routes[method] {
+			s.routes[method]++
+			s.routeMiddleware += len(route.middleware)
+			if patterns[route.pattern] {
+				s.warnings = append(s.warnings, fmt.Sprintf("duplicate route %s %q in group %q", method, route.pattern, prefix))
+			}
+			patterns[route.pattern] = true
+			if route.flag != "" && root.FlagProvider == nil {
+				s.warnings = append(s.warnings, fmt.Sprintf("route %s %q in group %q is marked with feature flag %q, but FlagProvider is not set", method, route.pattern, prefix, route.flag))
+			}
+		}
+	}
+
+	for _, prefix := range r.sortedPrefixes() {
+		r.groups[prefix].summarize(s)
+	}
+}
+
+// logSummary logs a concise summary of the route table, including the
+// number of routes per method, groups and middleware, and the
+// validation warnings.
+func (r *Router) logSummary() {
+	s := &summary{routes: make(map[string]int)}
+	r.summarize(s)
+	for _, c := range r.Conflicts() {
+		if !c.Duplicate {
+			s.warnings = append(s.warnings, c.String())
+		}
+	}
+
+	total := 0
+	methods := make([]string, 0, len(s.routes))
+	for method, count := range s.routes {
+		total += count
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	counts := make([]string, len(methods))
+	for i, method := range methods {
+		counts[i] = fmt.Sprintf("%s: %d", method, s.routes[method])
+	}
+
+	logger := r.logger()
+	logger.Printf("fastrouter: %d routes (%s), %d groups, %d router middleware, %d route middleware",
+		total, strings.Join(counts, ", "), s.groups, s.routerMiddleware, s.routeMiddleware)
+	for _, warning := range s.warnings {
+		logger.Printf("fastrouter: warning: %s", warning)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestRouter_LogSummary(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Logger = log.New(&buf, "", 0)
+	r.LogSummary = true
+	r.Middleware = append(r.Middleware, authMiddleware)
+	r.Get("/", emptyHandler)
+	r.Get("/users", emptyHandler)
+	r.Post("/users", emptyHandler, authMiddleware)
+	v1 := r.Group("v1")
+	v1.Get("/users", emptyHandler).Flag("v1", nil)
+	v1.Get("/users", emptyHandler)
+	r.Group("v2")
+	r.Prepare()
+
+	expect := `fastrouter: 5 routes (GET: 4, POST: 1), 2 groups, 1 router middleware, 1 route middleware
+fastrouter: warning: route GET "/users" in group "/v1" is marked with feature flag "v1", but FlagProvider is not set
+fastrouter: warning: duplicate route GET "/users" in group "/v1"
+fastrouter: warning: group "/v2" has no routes
+`
+	if buf.String() != expect {
+		t.Errorf("expect summary to be %q, but got %q", expect, buf.String())
+	}
+}
+
+// Summary is disabled by default
+func TestRouter_LogSummary2(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Logger = log.New(&buf, "", 0)
+	r.Get("/", emptyHandler)
+	r.Prepare()
+
+	if buf.Len() > 0 {
+		t.Errorf("expect no summary, but got %q", buf.String())
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StreamingMeta is the metadata key for marking long-lived streaming
+// routes, such as SSE and WebSocket, the routes marked as streaming
+// are exempt from ResponseTimeout.
+//
+//	r.Get("/events", handler).Meta(fastrouter.StreamingMeta, true)
+const StreamingMeta = "streaming"
+
+// DeadlineMeta is the metadata key for declaring the deadline of route,
+// the value MUST be a time.Duration, the request context is derived via
+// context.WithTimeout before invoking the middleware chain, so that the
+// per-endpoint budgets can be tuned in a single place.
+//
+//	r.Get("/reports", handler).Meta(fastrouter.DeadlineMeta, 5*time.Second)
+//
+// Unlike ResponseTimeout, the response is not written once the deadline
+// is exceeded, the handler is expected to respect the context.
+const DeadlineMeta = "deadline"
+
+// ResponseTimeout returns a middleware that aborts the handler which
+// does not produce any output within the given duration d, with a 503
+// Service Unavailable response with the given message, see
+// TimeoutHandler.
+//
+// The routes marked with StreamingMeta are exempt.
+//
+// The handler runs in another goroutine which may outlive the request,
+// so that it MUST NOT be used with the pooled context, see
+// PooledContext, use Route.Timeout if the PooledParams is enabled.
+func ResponseTimeout(d time.Duration, message string) Middleware {
+	return func(next http.Handler) http.Handler {
+		timeout := TimeoutHandler(next, d, http.StatusServiceUnavailable, message)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if route := routeFromRequest(req); route != nil && route.meta[StreamingMeta] == true {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			timeout.ServeHTTP(w, req)
+		})
+	}
+}
+
+// TimeoutHandler returns a handler which runs the given handler with
+// the request context which is derived via context.WithTimeout with
+// the given duration d, and responds with the given status code and
+// message, such as 503 Service Unavailable and 504 Gateway Timeout, if
+// the handler does not produce any output before the deadline, the
+// subsequent writes of handler fail with http.ErrHandlerTimeout. The
+// handler which has already produced output is allowed to run to
+// completion, but its context is canceled anyway.
+//
+// The panic of handler is propagated to the caller, the panic which
+// happens after the timeout response is handled via the PanicHandler,
+// RecoveryHandler or default recovery of the router which the request
+// is routed by, or logged if the recovery is disabled, since there is
+// no one to recover it.
+func TimeoutHandler(handler http.Handler, d time.Duration, code int, message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		defer cancel()
+		req = req.WithContext(ctx)
+
+		tw := &timeoutWriter{w: w, header: make(http.Header), ctx: ctx, code: code, message: message}
+		done := make(chan struct{})
+		returned := make(chan struct{})
+		defer close(returned)
+		panicChan := make(chan PanicInfo)
+		go func() {
+			defer func() {
+				rcv := recover()
+				if rcv == nil {
+					return
+				}
+				info := PanicInfo{Value: rcv, Stack: debug.Stack()}
+				select {
+				case panicChan <- info:
+				case <-returned:
+					handleLatePanic(tw, req, info)
+				}
+			}()
+			handler.ServeHTTP(tw, req)
+			close(done)
+		}()
+
+		select {
+		case info := <-panicChan:
+			panic(info.Value)
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		if tw.timeout() {
+			return
+		}
+
+		// the handler has produced output, waits for it.
+		select {
+		case info := <-panicChan:
+			panic(info.Value)
+		case <-done:
+		}
+	})
+}
+
+// handleLatePanic handles the panic of handler which happens after the
+// timeout response, see TimeoutHandler.
+func handleLatePanic(w http.ResponseWriter, req *http.Request, info PanicInfo) {
+	if info.Value == http.ErrAbortHandler {
+		return
+	}
+
+	route := matchedRoute(req)
+	if route == nil {
+		log.Printf("fastrouter: panic serving %s %q after timeout: %v\n%s", req.Method, req.URL.Path, info.Value, info.Stack)
+		return
+	}
+	if !route.router.handlePanic(w, req, info) {
+		route.router.logger().Printf("fastrouter: panic serving %s %q after timeout: %v\n%s", req.Method, req.URL.Path, info.Value, info.Stack)
+	}
+}
+
+// Timeout aborts the handler of route which does not produce any output
+// within the given duration d, with a 503 Service Unavailable response
+// by default, see TimeoutHandler and TimeoutCode. Unlike the
+// ResponseTimeout middleware, it only covers the handler, the
+// middleware of route and router are not covered, and it applies to
+// the routes marked with StreamingMeta as well. The context of route is
+// never pooled, since the handler may outlive the request.
+//
+// Returns the route itself for chaining.
+func (route *Route) Timeout(d time.Duration) *Route {
+	route.timeout = d
+	return route
+}
+
+// TimeoutCode sets the status code of the timeout response of Timeout,
+// such as 504 Gateway Timeout, defaults to 503 Service Unavailable.
+//
+// Returns the route itself for chaining.
+func (route *Route) TimeoutCode(code int) *Route {
+	route.timeoutCode = code
+	return route
+}
+
+// requestBudget returns the time budget which declared in the
+// RequestTimeoutHeader of request, capped by MaxRequestTimeout, ok is
+// false if the header is absent or invalid.
+func (r *Router) requestBudget(req *http.Request) (budget time.Duration, ok bool) {
+	value := req.Header.Get(r.RequestTimeoutHeader)
+	if value == "" {
+		return 0, false
+	}
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		budget = time.Duration(ms) * time.Millisecond
+	} else if budget, err = time.ParseDuration(value); err != nil {
+		return 0, false
+	}
+	if budget <= 0 {
+		return 0, false
+	}
+	if r.MaxRequestTimeout > 0 && budget > r.MaxRequestTimeout {
+		budget = r.MaxRequestTimeout
+	}
+
+	return budget, true
+}
+
+// timeoutWriter is a locked http.ResponseWriter which buffers the
+// header until the handler produces output, and discards the writes
+// after the timeout response was written.
+type timeoutWriter struct {
+	mu sync.Mutex
+
+	w http.ResponseWriter
+
+	// the request context with deadline.
+	ctx context.Context
+
+	// the status code and message of timeout response.
+	code    int
+	message string
+
+	// header is used before the handler produces output.
+	header http.Header
+
+	// indicates whether the handler has produced output.
+	started bool
+
+	// indicates whether the timeout response was written.
+	timedOut bool
+}
+
+// start flushes the buffered header into the underlying writer, it
+// MUST be called with the lock held.
+func (tw *timeoutWriter) start() {
+	if tw.started {
+		return
+	}
+	tw.started = true
+
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+// timeout writes the timeout response if the handler has not produced
+// any output, reports whether the response was written.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	return tw.expire()
+}
+
+// expire writes the timeout response once if the handler has not
+// produced any output, reports whether the response was written, it
+// MUST be called with the lock held.
+func (tw *timeoutWriter) expire() bool {
+	if tw.started {
+		return false
+	}
+
+	if !tw.timedOut {
+		tw.timedOut = true
+		http.Error(tw.w, tw.message, tw.code)
+	}
+	return true
+}
+
+// exceeded reports whether the writes are rejected, that is, the
+// deadline was exceeded before the handler produced output, it MUST be
+// called with the lock held.
+func (tw *timeoutWriter) exceeded() bool {
+	if tw.timedOut {
+		return true
+	}
+
+	return tw.ctx.Err() == context.DeadlineExceeded && tw.expire()
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.started {
+		return tw.w.Header()
+	}
+
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.exceeded() {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.start()
+	return tw.w.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.exceeded() {
+		return
+	}
+
+	tw.start()
+	tw.w.WriteHeader(code)
+}
+
+// Flush implements http.Flusher.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.exceeded() {
+		return
+	}
+
+	tw.start()
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseTimeout(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, ResponseTimeout(10*time.Millisecond, "timeout"))
+	canceled := make(chan bool, 1)
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+			canceled <- true
+		case <-time.After(time.Second):
+			canceled <- false
+		}
+		w.Write([]byte("slow"))
+	})
+	r.Get("/fast", helloHandler("fast"))
+	r.Prepare()
+
+	var req *http.Request
+	var w *httptest.ResponseRecorder
+
+	req = httptest.NewRequest(http.MethodGet, "/slow", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if body := "timeout\n"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+	if !<-canceled {
+		t.Error("expect request context to be canceled")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/fast", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if body := "fast"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+}
+
+// Handler that has produced output
+func TestResponseTimeout2(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, ResponseTimeout(10*time.Millisecond, "timeout"))
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte(" world"))
+	})
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if body := "hello world"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+	if contentType := "text/plain"; w.Header().Get("Content-Type") != contentType {
+		t.Errorf("expect header Content-Type to be %q, but got %q", contentType, w.Header().Get("Content-Type"))
+	}
+}
+
+// Streaming route
+func TestResponseTimeout3(t *testing.T) {
+	r := New()
+	r.Middleware = append(r.Middleware, ResponseTimeout(10*time.Millisecond, "timeout"))
+	r.Get("/events", func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("event"))
+	}).Meta(StreamingMeta, true)
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusOK, w.Code)
+	}
+	if body := "event"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+}
+
+// Panic in handler
+func TestResponseTimeout4(t *testing.T) {
+	r := New()
+	var recovered interface{}
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		recovered = rcv
+	}
+	r.Middleware = append(r.Middleware, ResponseTimeout(time.Second, "timeout"))
+	r.Get("/panic", func(w http.ResponseWriter, req *http.Request) {
+		panic("panic message")
+	})
+	r.Prepare()
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if recovered != "panic message" {
+		t.Errorf("expect panic to be %q, but got %v", "panic message", recovered)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	r := New()
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}).Timeout(10 * time.Millisecond)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestRouteTimeoutCode(t *testing.T) {
+	r := New()
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}).Timeout(10 * time.Millisecond).TimeoutCode(http.StatusGatewayTimeout)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusGatewayTimeout, w.Code)
+	}
+	if body := http.StatusText(http.StatusGatewayTimeout) + "\n"; w.Body.String() != body {
+		t.Errorf("expect response body to be %q, but got %q", body, w.Body.String())
+	}
+}
+
+func TestRouteTimeout_LatePanic(t *testing.T) {
+	release := make(chan struct{})
+	recovered := make(chan interface{}, 1)
+	r := New()
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+		recovered <- rcv
+	}
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		panic("late")
+	}).Timeout(10 * time.Millisecond)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	close(release)
+	select {
+	case rcv := <-recovered:
+		if rcv != "late" {
+			t.Errorf("expect panic to be %q, but got %v", "late", rcv)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect the panic after timeout to be handled")
+	}
+}
+
+func TestRouteTimeout_PooledParams(t *testing.T) {
+	release := make(chan struct{})
+	result := make(chan string, 1)
+	r := New()
+	r.PooledParams = true
+	r.Get("/slow/<id>", func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		<-req.Context().Done()
+		result <- Param(req, "id") + " " + MatchedPattern(req)
+	}).Timeout(10 * time.Millisecond)
+	r.Get("/users/<name>", emptyHandler)
+	r.Prepare()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/1", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect status code to be %d, but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// reuses the pooled contexts while the handler is still running.
+	for i := 0; i < 10; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/foo", nil))
+	}
+	close(release)
+	select {
+	case actual := <-result:
+		if actual != "1 /slow/<id>" {
+			t.Errorf("expect the timed out handler to read %q, but got %q", "1 /slow/<id>", actual)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect the timed out handler to run to completion")
+	}
+}
+
+func TestDeadlineMeta(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		deadline, ok := req.Context().Deadline()
+		if !ok {
+			w.Write([]byte("none"))
+			return
+		}
+		if remaining := time.Until(deadline); remaining <= 0 || remaining > time.Second {
+			t.Errorf("expect remaining time to be within %s, but got %s", time.Second, remaining)
+		}
+		w.Write([]byte("deadline"))
+	}
+	for _, pooled := range []bool{false, true} {
+		r := New()
+		r.PooledParams = pooled
+		r.Get("/reports/<id>", handler).Meta(DeadlineMeta, time.Second)
+		r.Get("/users", handler)
+		r.Prepare()
+
+		tests := []struct {
+			path string
+			body string
+		}{
+			{"/reports/1", "deadline"},
+			{"/users", "none"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if w.Body.String() != test.body {
+				t.Errorf("expect body of %q to be %q, but got %q", test.path, test.body, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRouter_RequestTimeoutHeader(t *testing.T) {
+	r := New()
+	r.RequestTimeoutHeader = "X-Request-Timeout"
+	r.MaxRequestTimeout = 2 * time.Second
+	var remaining time.Duration
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		remaining = 0
+		if deadline, ok := req.Context().Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+	})
+	r.Get("/reports", func(w http.ResponseWriter, req *http.Request) {
+		remaining = 0
+		if deadline, ok := req.Context().Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+	}).Meta(DeadlineMeta, 100*time.Millisecond)
+	r.Prepare()
+
+	tests := []struct {
+		path   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"/", "", 0, 0},
+		{"/", "invalid", 0, 0},
+		{"/", "-1", 0, 0},
+		{"/", "500", 400 * time.Millisecond, 500 * time.Millisecond},
+		{"/", "1.5s", 1400 * time.Millisecond, 1500 * time.Millisecond},
+		{"/", "1h", 1900 * time.Millisecond, 2 * time.Second},
+		{"/reports", "1s", 1, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("X-Request-Timeout", test.header)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if remaining < test.min || remaining > test.max {
+			t.Errorf("expect remaining time of %q with budget %q to be within [%s, %s], but got %s", test.path, test.header, test.min, test.max, remaining)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Disable switches off the route without removing it, the requests of
+// disabled route are handled as Not Found. It is safe to be called
+// while serving, so that a misbehaving endpoint can be switched off
+// from an admin hook.
+//
+// Returns the route itself for chaining.
+func (route *Route) Disable() *Route {
+	atomic.StoreInt32(&route.disabled, 1)
+	return route
+}
+
+// Enable switches on the route which was disabled, it is safe to be
+// called while serving.
+//
+// Returns the route itself for chaining.
+func (route *Route) Enable() *Route {
+	atomic.StoreInt32(&route.disabled, 0)
+	return route
+}
+
+// Enabled reports whether the route is enabled.
+func (route *Route) Enabled() bool {
+	return atomic.LoadInt32(&route.disabled) == 0
+}
+
+// SetEnabled enables or disables the route of the given name, see
+// Route.Disable.
+//
+// Returns non-nil error if the route does not exist.
+func (r *Router) SetEnabled(name string, enabled bool) error {
+	route, ok := r.namedRoute(name)
+	if !ok {
+		return fmt.Errorf("the route which name equal to %q does not exist", name)
+	}
+
+	if enabled {
+		route.Enable()
+	} else {
+		route.Disable()
+	}
+	return nil
+}
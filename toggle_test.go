@@ -0,0 +1,47 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteDisable(t *testing.T) {
+	r := New()
+	route := r.Get("/users", helloHandler("users")).Name("users")
+	r.Get("/posts", helloHandler("posts")).Disable()
+	r.Prepare()
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	if code := serve("/posts"); code != http.StatusNotFound {
+		t.Errorf("expect disabled route to be %d, but got %d", http.StatusNotFound, code)
+	}
+
+	if err := r.SetEnabled("users", false); err != nil {
+		t.Fatal(err)
+	}
+	if route.Enabled() {
+		t.Error("expect route to be disabled")
+	}
+	if code := serve("/users"); code != http.StatusNotFound {
+		t.Errorf("expect disabled route to be %d, but got %d", http.StatusNotFound, code)
+	}
+
+	route.Enable()
+	if code := serve("/users"); code != http.StatusOK {
+		t.Errorf("expect enabled route to be %d, but got %d", http.StatusOK, code)
+	}
+
+	if err := r.SetEnabled("nonexistent", true); err == nil {
+		t.Error("expect an error for nonexistent route")
+	}
+}
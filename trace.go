@@ -0,0 +1,50 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TraceHeader is the response header which summarizes the routing
+// decision in debug mode, see Router.Debug, for example:
+//
+//	X-Router-Trace: group="/api"; route="GET /users/<id>"; match=12.5µs
+//
+// The route is none if no route matched, the match is the time elapsed
+// from receiving the request to the routing decision.
+const TraceHeader = "X-Router-Trace"
+
+type traceKey struct{}
+
+var contextTraceKey traceKey
+
+// withTrace returns a shallow copy of request with the start time of
+// routing, it is only called in debug mode.
+func withTrace(req *http.Request, start time.Time) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), contextTraceKey, start))
+}
+
+// writeTrace sets the TraceHeader with the given group and route, the
+// route is nil if no route matched.
+func writeTrace(w http.ResponseWriter, req *http.Request, group *Router, route *Route) {
+	start, ok := req.Context().Value(contextTraceKey).(time.Time)
+	if !ok {
+		return
+	}
+
+	prefix := group.fullPrefix()
+	if prefix == "" {
+		prefix = "/"
+	}
+	matched := "none"
+	if route != nil {
+		matched = fmt.Sprintf("%q", route.method+" "+route.pattern)
+	}
+	w.Header().Set(TraceHeader, fmt.Sprintf("group=%q; route=%s; match=%s", prefix, matched, time.Since(start)))
+}
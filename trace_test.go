@@ -0,0 +1,44 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRouterDebug(t *testing.T) {
+	r := New()
+	r.Debug = true
+	r.Get("/", emptyHandler)
+	api := r.Group("/api")
+	api.Get("/users/<id:\\d+>", emptyHandler)
+	r.Prepare()
+
+	tests := []struct {
+		path  string
+		trace string
+	}{
+		{"/", `^group="/"; route="GET /"; match=\S+$`},
+		{"/api/users/1", `^group="/api"; route="GET /users/<id:\\\\d\+>"; match=\S+$`},
+		{"/api/posts", `^group="/api"; route=none; match=\S+$`},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if trace := w.Header().Get(TraceHeader); !regexp.MustCompile(test.trace).MatchString(trace) {
+			t.Errorf("expect trace of %q to match %q, but got %q", test.path, test.trace, trace)
+		}
+	}
+
+	r.Debug = false
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if trace := w.Header().Get(TraceHeader); trace != "" {
+		t.Errorf("expect no trace if debug mode is disabled, but got %q", trace)
+	}
+}
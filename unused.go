@@ -0,0 +1,35 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// UnusedRoutes returns the routes of router and its groups which have
+// not been hit within the given window, in order of Walk, it helps to
+// find the dead endpoints to retire. The routes which have never been
+// hit since the process started are also reported, so the window
+// SHOULD NOT be longer than the uptime.
+//
+// The GET route is considered as hit by the HEAD requests which fall
+// back to it via automatic HEAD.
+func (r *Router) UnusedRoutes(since time.Duration) []RouteInfo {
+	root := r.root()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	threshold := time.Now().Add(-since).UnixNano()
+	routes := []RouteInfo{}
+	r.walk(func(route *Route) error {
+		if atomic.LoadInt64(&route.lastHit) < threshold {
+			routes = append(routes, route.info())
+		}
+		return nil
+	})
+
+	return routes
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRouter_UnusedRoutes(t *testing.T) {
+	r := New()
+	r.Get("/", emptyHandler)
+	users := r.Get("/users", emptyHandler)
+	v1 := r.Group("/v1")
+	v1.Get("/posts", emptyHandler)
+	v1.Get("/legacy", emptyHandler)
+	r.Prepare()
+
+	serve := func(method, path string) {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+	serve(http.MethodGet, "/")
+	serve(http.MethodHead, "/v1/posts")
+	serve(http.MethodGet, "/users")
+	// pretends that the route was hit two hours ago.
+	atomic.StoreInt64(&users.lastHit, time.Now().Add(-2*time.Hour).UnixNano())
+
+	routes := r.UnusedRoutes(time.Hour)
+	expected := []string{"/users", "/v1/legacy"}
+	if len(routes) != len(expected) {
+		t.Fatalf("expect %d unused routes, but got %v", len(expected), routes)
+	}
+	for i, route := range routes {
+		if pattern := route.Prefix + route.Pattern; pattern != expected[i] {
+			t.Errorf("expect unused route %d to be %q, but got %q", i, expected[i], pattern)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Name names the route for reverse routing, the name MUST be
+// unique in the whole router tree.
+//
+// Returns the route itself for chaining.
+func (route *Route) Name(name string) *Route {
+	if name == "" {
+		panic(`the route name MUST NOT be empty`)
+	}
+
+	root := route.router.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if _, ok := root.names[name]; ok {
+		panic(fmt.Errorf("the route which name equal to %q already exists", name))
+	}
+
+	if route.name != "" {
+		delete(root.names, route.name)
+	}
+	route.name = name
+	root.names[name] = route
+	return route
+}
+
+// URL reverses the route into an URL path with the given parameters,
+// the pairs is a list of parameter name and value, for example:
+//
+//	route.URL("year", "2017", "month", "09")
+//
+// The BasePath of root router will be prepended to the path, and
+// the trailing slashes of path respects the TrailingSlashesPolicy,
+// so that the URL will not be redirected.
+//
+// Returns non-nil error, if the parser of route does not implements
+// BuilderInterface, or any parameter is missing or invalid.
+func (route *Route) URL(pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("the parameters of route %q MUST be pairs of name and value", route.pattern)
+	}
+
+	builder, ok := route.router.parser.(BuilderInterface)
+	if !ok {
+		return "", fmt.Errorf("the parser of route %q does not implements BuilderInterface", route.pattern)
+	}
+
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+	constraints := route.router.root().constraints
+	build := func(pattern string) (string, error) {
+		if p, ok := builder.(Parser); ok {
+			return p.build(pattern, params, constraints)
+		}
+		return builder.Build(pattern, params)
+	}
+
+	path, err := build(route.pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// prepend group prefixes.
+	for router := route.router; router.parent != nil; router = router.parent {
+		prefix := "/" + router.prefix
+		if router.prefixReg != nil {
+			if prefix, err = build(prefix); err != nil {
+				return "", err
+			}
+		}
+		if path == "/" {
+			path = prefix
+		} else {
+			path = prefix + path
+		}
+	}
+
+	return (&url.URL{Path: route.router.basePath() + route.trailingSlashes(path)}).EscapedPath(), nil
+}
+
+// trailingSlashes appends or removes the trailing slashes of path
+// according to the TrailingSlashesPolicy, so that the path will not
+// be redirected.
+func (route *Route) trailingSlashes(path string) string {
+	if path == "/" {
+		return path
+	}
+
+	endWithSlashes := strings.HasSuffix(path, "/")
+	switch route.router.root().TrailingSlashesPolicy {
+	case AppendTrailingSlashes:
+		if !endWithSlashes {
+			path += "/"
+		}
+	case RemoveTrailingSlashes:
+		if endWithSlashes {
+			path = path[:len(path)-1]
+		}
+	case StrictTrailingSlashes:
+		if route.hasTrailingSlashes && !endWithSlashes {
+			path += "/"
+		} else if !route.hasTrailingSlashes && endWithSlashes {
+			path = path[:len(path)-1]
+		}
+	}
+
+	return path
+}
+
+// URL reverses the route which named as the given name into an URL
+// path, see Route.URL for details.
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	route, ok := r.namedRoute(name)
+	if !ok {
+		return "", fmt.Errorf("the route which name equal to %q does not exist", name)
+	}
+
+	return route.URL(pairs...)
+}
+
+// namedRoute returns the route which named as the given name, it is
+// safe to call concurrently with RemoveRoute.
+func (r *Router) namedRoute(name string) (*Route, bool) {
+	root := r.root()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	route, ok := root.names[name]
+	return route, ok
+}
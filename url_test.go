@@ -0,0 +1,124 @@
+// Copyright 2017 Razon Yang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fastrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRoute_Name(t *testing.T) {
+	r := New()
+	r.Get("/", emptyHandler).Name("home")
+	v1 := r.Group("v1")
+	route := v1.Get("/users", emptyHandler).Name("users")
+	if r.names["users"] != route {
+		t.Errorf("expect route users to be registered in root router, but got %v", r.names["users"])
+	}
+
+	expect := fmt.Errorf("the route which name equal to %q already exists", "home")
+	defer func() {
+		if rcv := recover(); rcv == nil || !reflect.DeepEqual(expect, rcv) {
+			t.Errorf("expect err to be %q, but got %q", expect, rcv)
+		}
+	}()
+	v1.Get("/home", emptyHandler).Name("home")
+}
+
+func TestRouter_URL(t *testing.T) {
+	r := New()
+	r.Get("/", emptyHandler).Name("home")
+	r.Get(`/posts/<year:\d{4}>/<month:\d{2}>/<title>`, emptyHandler).Name("post")
+	r.Get("/files/<filepath:.+>", emptyHandler).Name("file")
+	v1 := r.Group("v1")
+	v1.Get("/", emptyHandler).Name("v1")
+	v1.Group("users").Get("/<name>/", emptyHandler).Name("user")
+
+	tests := []struct {
+		name  string
+		pairs []string
+		url   string
+		err   error
+	}{
+		{"home", nil, "/", nil},
+		{"post", []string{"year", "2017", "month", "09", "title", "hello world"}, "/posts/2017/09/hello%20world", nil},
+		{"file", []string{"filepath", "css/app.css"}, "/files/css/app.css", nil},
+		{"v1", nil, "/v1", nil},
+		{"user", []string{"name", "foo"}, "/v1/users/foo/", nil},
+		{"nonexistent", nil, "", fmt.Errorf("the route which name equal to %q does not exist", "nonexistent")},
+		{"user", []string{"name"}, "", fmt.Errorf("the parameters of route %q MUST be pairs of name and value", "/<name>/")},
+		{"user", nil, "", fmt.Errorf("the parameter %q is missing in pattern %q", "name", "/<name>/")},
+		{"user", []string{"name", "foo/bar"}, "", fmt.Errorf("the parameter %q MUST NOT be empty or contains '/' in pattern %q", "name", "/<name>/")},
+	}
+	for _, test := range tests {
+		url, err := r.URL(test.name, test.pairs...)
+		if url != test.url {
+			t.Errorf("expect the URL of %q to be %q, but got %q", test.name, test.url, url)
+		}
+		if !reflect.DeepEqual(test.err, err) {
+			t.Errorf("expect the err of %q to be %v, but got %v", test.name, test.err, err)
+		}
+	}
+
+	if _, err := r.URL("post", "year", "17", "month", "09", "title", "hello"); err == nil {
+		t.Error("expect an error for invalid parameter, but got nil")
+	}
+}
+
+func TestRouter_URL2(t *testing.T) {
+	r := New()
+	r.BasePath = "/myapp/"
+	r.Get("/", emptyHandler).Name("home")
+	r.Group("v1").Get("/users/<name>", emptyHandler).Name("user")
+
+	if url, _ := r.URL("home"); url != "/myapp/" {
+		t.Errorf("expect the URL of home to be %q, but got %q", "/myapp/", url)
+	}
+	if url, _ := r.URL("user", "name", "foo"); url != "/myapp/v1/users/foo" {
+		t.Errorf("expect the URL of user to be %q, but got %q", "/myapp/v1/users/foo", url)
+	}
+}
+
+func TestRouter_URL3(t *testing.T) {
+	tests := []struct {
+		policy int8
+		urls   []string
+	}{
+		{IgnoreTrailingSlashes, []string{"/", "/users", "/posts/", "/v1"}},
+		{AppendTrailingSlashes, []string{"/", "/users/", "/posts/", "/v1/"}},
+		{RemoveTrailingSlashes, []string{"/", "/users", "/posts", "/v1"}},
+		{StrictTrailingSlashes, []string{"/", "/users", "/posts/", "/v1"}},
+	}
+	for _, test := range tests {
+		r := New()
+		r.TrailingSlashesPolicy = test.policy
+		r.Get("/", emptyHandler).Name("home")
+		r.Get("/users", emptyHandler).Name("users")
+		r.Get("/posts/", emptyHandler).Name("posts")
+		r.Group("v1").Get("/", emptyHandler).Name("v1")
+		r.Prepare()
+
+		for i, name := range []string{"home", "users", "posts", "v1"} {
+			url, err := r.URL(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if url != test.urls[i] {
+				t.Errorf("policy %d: expect url of %q to be %q, but got %q", test.policy, name, test.urls[i], url)
+			}
+
+			// the generated URL MUST NOT be redirected.
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("policy %d: expect status code of %q to be %d, but got %d", test.policy, url, http.StatusOK, w.Code)
+			}
+		}
+	}
+}